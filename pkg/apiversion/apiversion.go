@@ -0,0 +1,123 @@
+// Package apiversion serves the versions of the api side by side, each under
+// its /vN prefix and on the unversioned paths by the API-Version header, and
+// tells the clients of a deprecated version when it ends.
+package apiversion
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/labstack/echo/v4"
+)
+
+// Header names the version of the api a request to an unversioned path is
+// served by.
+const Header = "API-Version"
+
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+	HeaderLink        = "Link"
+)
+
+// prefix is the /vN at the start of a versioned path.
+var prefix = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+// Prefix is the path the routes of version are grouped under.
+func Prefix(version int) string {
+	return "/v" + strconv.Itoa(version)
+}
+
+// Select serves a request by the handler of the version of its API-Version
+// header, the handler of def without the header. A version without handler is
+// answered 400.
+func Select(def int, handlers map[int]echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Add(echo.HeaderVary, Header)
+		version := def
+		if raw := strings.TrimSpace(c.Request().Header.Get(Header)); raw != "" {
+			var err error
+			if version, err = strconv.Atoi(raw); err != nil {
+				return c.NoContent(http.StatusBadRequest)
+			}
+		}
+		handler, ok := handlers[version]
+		if !ok {
+			return c.NoContent(http.StatusBadRequest)
+		}
+		return handler(c)
+	}
+}
+
+// Deprecate tells in every response of a deprecated version the time it was
+// deprecated at (RFC 9745), the time it is no longer served after (RFC 8594)
+// and the same path in the successor version.
+func Deprecate(deprecated time.Time, sunset time.Time, successor int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set(HeaderDeprecation, "@"+strconv.FormatInt(deprecated.Unix(), 10))
+			header.Set(HeaderSunset, sunset.UTC().Format(http.TimeFormat))
+			header.Add(HeaderLink, "<"+Successor(c.Request().URL.Path, successor)+`>; rel="successor-version"`)
+			return next(c)
+		}
+	}
+}
+
+// Successor is path, versioned or not, in version.
+func Successor(path string, version int) string {
+	if loc := prefix.FindStringIndex(path); loc != nil {
+		path = "/" + path[loc[1]:]
+	}
+	return strings.TrimSuffix(Prefix(version)+path, "/")
+}
+
+// DeprecateV1 tells the clients of v1 to move to v2 by the dates of cf.
+func DeprecateV1(cf config.Config) echo.MiddlewareFunc {
+	return Deprecate(cf.V1Deprecated(), cf.V1Sunset(), 2)
+}
+
+// Versions are the middleware of every served version, the one deprecating
+// it or nil.
+type Versions map[int]echo.MiddlewareFunc
+
+// Served are the versions served by cf, v1 deprecated by v2.
+func Served(cf config.Config) Versions {
+	return Versions{1: DeprecateV1(cf), 2: nil}
+}
+
+// Deprecated tells whether version is deprecated, the clients are told so by
+// its middleware.
+func (v Versions) Deprecated(version int) bool {
+	return v[version] != nil
+}
+
+// sorted are the versions of v, the oldest first.
+func (v Versions) sorted() []int {
+	versions := make([]int, 0, len(v))
+	for version := range v {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Add serves handler the same in every version, on path under the prefix of
+// each and on path by the API-Version header, def without it.
+func (v Versions) Add(e *echo.Echo, def int, method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	handlers := map[int]echo.HandlerFunc{}
+	for version, deprecate := range v {
+		h := handler
+		if deprecate != nil {
+			h = deprecate(handler)
+		}
+		handlers[version] = h
+		e.Add(method, Prefix(version)+path, h, middleware...)
+	}
+	e.Add(method, path, Select(def, handlers), middleware...)
+}
@@ -0,0 +1,178 @@
+//go:build unit
+
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	handler := Select(1, map[int]echo.HandlerFunc{
+		1: func(c echo.Context) error { return c.String(http.StatusOK, "v1") },
+		2: func(c echo.Context) error { return c.String(http.StatusOK, "v2") },
+	})
+
+	for header, want := range map[string]struct {
+		status int
+		body   string
+	}{
+		"":    {http.StatusOK, "v1"},
+		"1":   {http.StatusOK, "v1"},
+		" 2 ": {http.StatusOK, "v2"},
+		"3":   {http.StatusBadRequest, ""},
+		"two": {http.StatusBadRequest, ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+		req.Header.Set(Header, header)
+		rec := httptest.NewRecorder()
+
+		err := handler(echo.New().NewContext(req, rec))
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, want.status, rec.Code, header)
+			assert.Equal(t, want.body, rec.Body.String(), header)
+			assert.Equal(t, Header, rec.Header().Get(echo.HeaderVary), header)
+		}
+	}
+}
+
+func TestDeprecate(t *testing.T) {
+	deprecated := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+	handler := Deprecate(deprecated, sunset, 2)(func(c echo.Context) error {
+		return c.NoContent(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/expenses/1?as_of=now", nil)
+	rec := httptest.NewRecorder()
+
+	err := handler(echo.New().NewContext(req, rec))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "@1792368000", rec.Header().Get(HeaderDeprecation))
+		assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", rec.Header().Get(HeaderSunset))
+		assert.Equal(t, `</v2/expenses/1>; rel="successor-version"`, rec.Header().Get(HeaderLink))
+	}
+}
+
+func TestSuccessor(t *testing.T) {
+	for path, want := range map[string]string{
+		"/v1/expenses/1": "/v2/expenses/1",
+		"/expenses":      "/v2/expenses",
+		"/v1":            "/v2",
+		"/v1/":           "/v2",
+		"/v10/expenses":  "/v2/expenses",
+		"/vat/expenses":  "/v2/vat/expenses",
+	} {
+		assert.Equal(t, want, Successor(path, 2), path)
+	}
+}
+
+type itemV1 struct {
+	Id int64 `json:"id"`
+}
+
+type itemV2 struct {
+	Id string `json:"id"`
+}
+
+func TestOperations(t *testing.T) {
+	v1 := []openapi.Operation{
+		{Method: http.MethodGet, Path: "/items/:id", Id: "GetItem", Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the item", Body: itemV1{}},
+			openapi.NotFound,
+		}},
+		{Method: http.MethodDelete, Path: "/items/:id", Id: "DeleteItem", Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "deleted"},
+		}},
+	}
+	v2 := []openapi.Operation{
+		{Method: http.MethodGet, Path: "/items/:id", Id: "GetItem", Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the item", Body: itemV2{}},
+			openapi.NotFound,
+		}},
+		{Method: http.MethodDelete, Path: "/items/:id", Id: "DeleteItem", Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "deleted"},
+		}},
+	}
+
+	t.Run("should prefix the paths and suffix the ids with the version", func(t *testing.T) {
+		ops := Operations(1, true, v1)
+
+		assert.Equal(t, "/v1/items/:id", ops[0].Path)
+		assert.Equal(t, "GetItemV1", ops[0].Id)
+		assert.True(t, ops[0].Deprecated)
+		assert.Equal(t, "/items/:id", v1[0].Path)
+	})
+
+	t.Run("should document the bodies of every version on the unversioned paths", func(t *testing.T) {
+		ops := Unversioned(1, map[int][]openapi.Operation{1: v1, 2: v2})
+
+		assert.Len(t, ops, 2)
+		assert.Equal(t, "/items/:id", ops[0].Path)
+		assert.Equal(t, "GetItem", ops[0].Id)
+		assert.Equal(t, Header, ops[0].Params[0].Name)
+		assert.Equal(t, openapi.AnyOf(itemV1{}, itemV2{}), ops[0].Responses[0].Body)
+		assert.Nil(t, ops[0].Responses[1].Body)
+		assert.Nil(t, ops[1].Responses[0].Body)
+		assert.Equal(t, itemV1{}, v1[0].Responses[0].Body)
+	})
+
+	t.Run("should document the operations served the same in every version", func(t *testing.T) {
+		versions := Versions{1: Deprecate(time.Unix(0, 0), time.Unix(0, 0), 2), 2: nil}
+
+		ops := versions.Same(1, v2[:1])
+
+		paths := []string{}
+		for _, op := range ops {
+			paths = append(paths, op.Path)
+		}
+		assert.Equal(t, []string{"/v1/items/:id", "/v2/items/:id", "/items/:id"}, paths)
+		assert.Equal(t, []bool{true, false, false}, []bool{ops[0].Deprecated, ops[1].Deprecated, ops[2].Deprecated})
+		assert.Equal(t, itemV2{}, ops[2].Responses[0].Body)
+	})
+
+	t.Run("should document the versions as they are served", func(t *testing.T) {
+		ops := Versions{1: nil, 2: nil}.Same(1, v2[:1])
+
+		assert.Equal(t, []bool{false, false, false}, []bool{ops[0].Deprecated, ops[1].Deprecated, ops[2].Deprecated})
+	})
+}
+
+func TestVersionsAdd(t *testing.T) {
+	e := echo.New()
+	versions := Versions{1: Deprecate(time.Unix(0, 0), time.Unix(0, 0), 2), 2: nil}
+	versions.Add(e, 1, http.MethodGet, "/items/search", func(c echo.Context) error {
+		return c.String(http.StatusOK, "found")
+	})
+
+	for path, want := range map[string]struct {
+		version    string
+		status     int
+		deprecated bool
+	}{
+		"/v1/items/search": {"", http.StatusOK, true},
+		"/v2/items/search": {"", http.StatusOK, false},
+		"/items/search":    {"", http.StatusOK, true},
+		"/items/search ":   {"2", http.StatusOK, false},
+		"/items/search  ":  {"3", http.StatusBadRequest, false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, strings.TrimSpace(path), nil)
+		req.Header.Set(Header, want.version)
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, want.status, rec.Code, path)
+		assert.Equal(t, want.deprecated, rec.Header().Get(HeaderDeprecation) != "", path)
+	}
+}
@@ -0,0 +1,120 @@
+package apiversion
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+)
+
+// Operations documents the operations of version under its prefix, their ids
+// suffixed with it.
+func Operations(version int, deprecated bool, operations []openapi.Operation) []openapi.Operation {
+	result := make([]openapi.Operation, 0, len(operations))
+	for _, op := range operations {
+		op.Path = Prefix(version) + op.Path
+		op.Id += "V" + strconv.Itoa(version)
+		op.Deprecated = deprecated
+		result = append(result, op)
+	}
+	return result
+}
+
+// Unversioned documents the operations of the unversioned paths, served by
+// the versions of operations by the API-Version header and by def without it.
+// An operation of def reads and writes the bodies, and answers the statuses,
+// of the operations of the same id in every version.
+func Unversioned(def int, operations map[int][]openapi.Operation) []openapi.Operation {
+	versions := []int{}
+	for version := range operations {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	enum := []string{}
+	for _, version := range versions {
+		enum = append(enum, strconv.Itoa(version))
+	}
+	header := openapi.Param{
+		Name:        Header,
+		In:          "header",
+		Description: "version of the api, " + strconv.Itoa(def) + " when missing",
+		Schema:      &openapi.Schema{Type: "string", Enum: enum},
+	}
+
+	result := make([]openapi.Operation, 0, len(operations[def]))
+	for _, op := range operations[def] {
+		var bodies []any
+		responses := map[int][]any{}
+		op.Responses = append([]openapi.Response{}, op.Responses...)
+		for _, version := range versions {
+			for _, other := range operations[version] {
+				if other.Id != op.Id {
+					continue
+				}
+				if other.Body != nil {
+					bodies = appendType(bodies, other.Body)
+				}
+				for _, r := range other.Responses {
+					if r.Body != nil {
+						responses[r.Status] = appendType(responses[r.Status], r.Body)
+					}
+					if !hasStatus(op.Responses, r.Status) {
+						op.Responses = append(op.Responses, r)
+					}
+				}
+			}
+		}
+
+		op.Params = append(append([]openapi.Param{}, op.Params...), header)
+		if op.Body != nil {
+			op.Body = anyOf(bodies)
+		}
+		for i, r := range op.Responses {
+			if r.Body != nil {
+				op.Responses[i].Body = anyOf(responses[r.Status])
+			}
+		}
+		result = append(result, op)
+	}
+	return result
+}
+
+// Same documents the operations served the same in every version of v, under
+// the prefix of each, deprecated when it is, and on the unversioned paths.
+func (v Versions) Same(def int, operations []openapi.Operation) []openapi.Operation {
+	result := []openapi.Operation{}
+	unversioned := map[int][]openapi.Operation{}
+	for _, version := range v.sorted() {
+		result = append(result, Operations(version, v.Deprecated(version), operations)...)
+		unversioned[version] = operations
+	}
+	return append(result, Unversioned(def, unversioned)...)
+}
+
+func hasStatus(responses []openapi.Response, status int) bool {
+	for _, r := range responses {
+		if r.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// appendType appends v to values unless a value of its type is there.
+func appendType(values []any, v any) []any {
+	for _, value := range values {
+		if reflect.TypeOf(value) == reflect.TypeOf(v) {
+			return values
+		}
+	}
+	return append(values, v)
+}
+
+// anyOf is the body of any of values, the single one as is.
+func anyOf(values []any) any {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return openapi.AnyOf(values...)
+}
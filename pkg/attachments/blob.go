@@ -0,0 +1,15 @@
+package attachments
+
+import (
+	"context"
+	"io"
+)
+
+// BlobStore keeps the content of attachments. Keys are content hashes, so Put
+// does nothing when the key already exists. Get and Delete return
+// common.ErrNotFound for an unknown key.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
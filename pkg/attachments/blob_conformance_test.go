@@ -0,0 +1,68 @@
+//go:build unit || integration
+
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// runBlobStoreConformance is the behavior every BlobStore must have.
+// newStore must return an empty store.
+func runBlobStoreConformance(t *testing.T, newStore func(t *testing.T) BlobStore) {
+	ctx := context.Background()
+	key := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	t.Run("Get should return the content of Put", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.Put(ctx, key, strings.NewReader("test"), 4, "text/plain")
+		assert.NoError(t, err)
+		content, err := store.Get(ctx, key)
+		assert.NoError(t, err)
+		defer content.Close()
+		got, _ := io.ReadAll(content)
+
+		assert.Equal(t, "test", string(got))
+	})
+
+	t.Run("Put should keep the existing content of a key", func(t *testing.T) {
+		store := newStore(t)
+		store.Put(ctx, key, strings.NewReader("test"), 4, "text/plain")
+
+		err := store.Put(ctx, key, strings.NewReader("other"), 5, "text/plain")
+		assert.NoError(t, err)
+		content, _ := store.Get(ctx, key)
+		defer content.Close()
+		got, _ := io.ReadAll(content)
+
+		assert.Equal(t, "test", string(got))
+	})
+
+	t.Run("Get and Delete should return common.ErrNotFound for an unknown key", func(t *testing.T) {
+		store := newStore(t)
+
+		_, getErr := store.Get(ctx, key)
+		deleteErr := store.Delete(ctx, key)
+
+		assert.ErrorIs(t, getErr, common.ErrNotFound)
+		assert.ErrorIs(t, deleteErr, common.ErrNotFound)
+	})
+
+	t.Run("Delete should remove the content", func(t *testing.T) {
+		store := newStore(t)
+		store.Put(ctx, key, bytes.NewReader([]byte("test")), 4, "text/plain")
+
+		err := store.Delete(ctx, key)
+		assert.NoError(t, err)
+		_, err = store.Get(ctx, key)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+	})
+}
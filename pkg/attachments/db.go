@@ -0,0 +1,232 @@
+package attachments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/lib/pq"
+)
+
+// The blob of a hash is written and deleted under a transaction lock of the
+// hash, so a delete cannot remove a blob that an upload is attaching.
+const (
+	lockQuery       = "select pg_advisory_xact_lock(hashtext($1))"
+	insertQuery     = "INSERT INTO attachments (expense_id, filename, content_type, size, sha256) values ($1, $2, $3, $4, $5) ON CONFLICT (expense_id, sha256) DO NOTHING RETURNING id, expense_id, filename, content_type, size, sha256, created_at"
+	searchHashQuery = "select id, expense_id, filename, content_type, size, sha256, created_at from attachments where expense_id = $1 and sha256 = $2"
+	searchByIdQuery = "select id, expense_id, filename, content_type, size, sha256, created_at from attachments where expense_id = $1 and id = $2"
+	listQuery       = "select id, expense_id, filename, content_type, size, sha256, created_at from attachments where expense_id = $1 order by id"
+	deleteQuery     = "DELETE FROM attachments WHERE expense_id = $1 AND id = $2"
+	referencedQuery = "select exists (select 1 from attachments where sha256 = $1)"
+)
+
+// foreignKeyViolation is the postgres error code of a reference to a missing row.
+const foreignKeyViolation = "23503"
+
+type DataMgmt struct {
+	dataMgmt *sql.DB
+	timeout  time.Duration
+}
+
+func New(d *sql.DB, timeout time.Duration) *DataMgmt {
+	return &DataMgmt{dataMgmt: d, timeout: timeout}
+}
+
+func (mgmt DataMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+// queryError prefers the context error and maps a missing row or expense to
+// common.ErrNotFound.
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation) {
+		err = common.ErrNotFound
+	}
+	tracing.RecordError(ctx, err)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAttachment(row scanner) (*AttachmentResponse, error) {
+	result := &AttachmentResponse{}
+	err := row.Scan(&result.Id, &result.ExpenseId, &result.Filename, &result.ContentType, &result.Size, &result.Sha256, &result.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Insert records the upload and calls put to store its blob, unless the
+// expense already has the same content in which case the existing attachment
+// is returned with created false. The blob is put before the commit, a failed
+// commit calls remove with its hash when no attachment refers to it.
+func (mgmt DataMgmt) Insert(ctx context.Context, up Upload, put func(ctx context.Context) error, remove func(ctx context.Context, sha256 string) error) (*AttachmentResponse, bool, error) {
+	defer metrics.ObserveQuery("InsertAttachment", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "AttachmentMgmt.Insert", insertQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := mgmt.dataMgmt.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, lockQuery, up.Sha256); err != nil {
+		return nil, false, queryError(ctx, err)
+	}
+
+	result, err := scanAttachment(tx.QueryRowContext(ctx, insertQuery, up.ExpenseId, up.Filename, up.ContentType, up.Size, up.Sha256))
+	if errors.Is(err, sql.ErrNoRows) {
+		result, err = scanAttachment(tx.QueryRowContext(ctx, searchHashQuery, up.ExpenseId, up.Sha256))
+		if err != nil {
+			return nil, false, queryError(ctx, err)
+		}
+		return result, false, nil
+	}
+	if err != nil {
+		return nil, false, queryError(ctx, err)
+	}
+
+	// the row is inserted first so an unknown expense fails before the blob
+	// is written
+	if err := put(ctx); err != nil {
+		return nil, false, queryError(ctx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		err = queryError(ctx, err)
+		// ctx may be what failed the commit
+		removeCtx, cancel := mgmt.withTimeout(context.Background())
+		defer cancel()
+		if err := mgmt.removeBlob(removeCtx, up.Sha256, remove); err != nil {
+			tracing.RecordError(ctx, err)
+		}
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+func (mgmt DataMgmt) SearchById(ctx context.Context, expenseId int64, id int64) (*AttachmentResponse, error) {
+	defer metrics.ObserveQuery("SearchAttachmentById", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "AttachmentMgmt.SearchById", searchByIdQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := scanAttachment(mgmt.dataMgmt.QueryRowContext(ctx, searchByIdQuery, expenseId, id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) List(ctx context.Context, expenseId int64) ([]AttachmentResponse, error) {
+	defer metrics.ObserveQuery("ListAttachments", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "AttachmentMgmt.List", listQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, listQuery, expenseId)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []AttachmentResponse{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		result = append(result, *attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Delete removes the attachment and calls remove with its hash when no other
+// attachment shares the blob. The blob is removed once the delete is
+// committed, a blob the remove fails to delete is left as an orphan.
+func (mgmt DataMgmt) Delete(ctx context.Context, expenseId int64, id int64, remove func(ctx context.Context, sha256 string) error) error {
+	defer metrics.ObserveQuery("DeleteAttachment", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "AttachmentMgmt.Delete", deleteQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := mgmt.dataMgmt.BeginTx(ctx, nil)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	attachment, err := scanAttachment(tx.QueryRowContext(ctx, searchByIdQuery, expenseId, id))
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	result, err := tx.ExecContext(ctx, deleteQuery, expenseId, id)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	// a concurrent delete may have won since the search
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		if err == nil {
+			err = common.ErrNotFound
+		}
+		return queryError(ctx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return queryError(ctx, err)
+	}
+
+	if err := mgmt.removeBlob(ctx, attachment.Sha256, remove); err != nil {
+		tracing.RecordError(ctx, err)
+	}
+	return nil
+}
+
+// removeBlob calls remove with sha256 unless an attachment refers to it,
+// under the lock of the hash so that an upload does not attach it meanwhile.
+func (mgmt DataMgmt) removeBlob(ctx context.Context, sha256 string, remove func(ctx context.Context, sha256 string) error) error {
+	tx, err := mgmt.dataMgmt.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, lockQuery, sha256); err != nil {
+		return err
+	}
+	var referenced bool
+	if err := tx.QueryRowContext(ctx, referencedQuery, sha256).Scan(&referenced); err != nil {
+		return err
+	}
+	if !referenced {
+		if err := remove(ctx, sha256); err != nil && !errors.Is(err, common.ErrNotFound) {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,209 @@
+//go:build unit
+
+package attachments
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var columns = []string{"id", "expense_id", "filename", "content_type", "size", "sha256", "created_at"}
+
+func TestInsert(t *testing.T) {
+	up := Upload{ExpenseId: 1, Filename: "receipt.png", ContentType: "image/png", Size: 4, Sha256: "abcd"}
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("should insert the attachment and put its blob before commit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("abcd").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs(1, "receipt.png", "image/png", 4, "abcd").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(7, 1, "receipt.png", "image/png", 4, "abcd", now))
+		mock.ExpectCommit()
+		putWasCalled := false
+
+		result, created, err := New(db, time.Second).Insert(context.Background(), up, func(ctx context.Context) error {
+			putWasCalled = true
+			return nil
+		}, noRemove(t))
+
+		assert.NoError(t, err)
+		assert.True(t, created)
+		assert.True(t, putWasCalled)
+		assert.Equal(t, &AttachmentResponse{Id: 7, ExpenseId: 1, Filename: "receipt.png", ContentType: "image/png", Size: 4, Sha256: "abcd", CreatedAt: now}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return the existing attachment without put when the content is already attached", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("abcd").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs(1, "receipt.png", "image/png", 4, "abcd").WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(regexp.QuoteMeta(searchHashQuery)).WithArgs(1, "abcd").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 1, "first.png", "image/png", 4, "abcd", now))
+		mock.ExpectRollback()
+		putWasCalled := false
+
+		result, created, err := New(db, time.Second).Insert(context.Background(), up, func(ctx context.Context) error {
+			putWasCalled = true
+			return nil
+		}, noRemove(t))
+
+		assert.NoError(t, err)
+		assert.False(t, created)
+		assert.False(t, putWasCalled)
+		assert.Equal(t, int64(3), result.Id)
+	})
+
+	t.Run("should return common.ErrNotFound without put for an unknown expense", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("abcd").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WillReturnError(&pq.Error{Code: "23503"})
+		mock.ExpectRollback()
+		putWasCalled := false
+
+		_, _, err = New(db, time.Second).Insert(context.Background(), up, func(ctx context.Context) error {
+			putWasCalled = true
+			return nil
+		}, noRemove(t))
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.False(t, putWasCalled)
+	})
+
+	t.Run("should roll back when put fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("abcd").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(7, 1, "receipt.png", "image/png", 4, "abcd", now))
+		mock.ExpectRollback()
+
+		_, _, err = New(db, time.Second).Insert(context.Background(), up, func(ctx context.Context) error {
+			return errors.New("store down")
+		}, noRemove(t))
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	for _, tc := range []struct {
+		name       string
+		referenced bool
+		removed    bool
+	}{
+		{"should remove the blob put when the commit fails", false, true},
+		{"should keep the blob put when the commit fails while an attachment refers to it", true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("abcd").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(7, 1, "receipt.png", "image/png", 4, "abcd", now))
+			mock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("abcd").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(referencedQuery)).WithArgs("abcd").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.referenced))
+			mock.ExpectCommit()
+			removed := false
+
+			_, _, err = New(db, time.Second).Insert(context.Background(), up, func(ctx context.Context) error {
+				return nil
+			}, func(ctx context.Context, sha256 string) error {
+				removed = true
+				return nil
+			})
+
+			assert.EqualError(t, err, "connection reset")
+			assert.Equal(t, tc.removed, removed)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// noRemove fails the test when a blob is removed.
+func noRemove(t *testing.T) func(ctx context.Context, sha256 string) error {
+	return func(ctx context.Context, sha256 string) error {
+		t.Fatal("unexpected remove")
+		return nil
+	}
+}
+
+func TestDelete(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name       string
+		referenced bool
+		removed    bool
+	}{
+		{name: "should remove the blob when no other attachment shares it", referenced: false, removed: true},
+		{name: "should keep the blob shared with another attachment", referenced: true, removed: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(searchByIdQuery)).WithArgs(1, 7).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(7, 1, "receipt.png", "image/png", 4, "abcd", now))
+			mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(1, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("abcd").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(referencedQuery)).WithArgs("abcd").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.referenced))
+			mock.ExpectCommit()
+			removed := ""
+
+			err = New(db, time.Second).Delete(context.Background(), 1, 7, func(ctx context.Context, sha256 string) error {
+				removed = sha256
+				return nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.removed, removed == "abcd")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+	t.Run("should keep the delete committed when the blob cannot be removed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(searchByIdQuery)).WithArgs(1, 7).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(7, 1, "receipt.png", "image/png", 4, "abcd", now))
+		mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(1, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("abcd").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(referencedQuery)).WithArgs("abcd").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectRollback()
+
+		err = New(db, time.Second).Delete(context.Background(), 1, 7, func(ctx context.Context, sha256 string) error {
+			return errors.New("blob store down")
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,143 @@
+package attachments
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/labstack/echo/v4"
+)
+
+// formFile is the multipart field of the uploaded file.
+const formFile = "file"
+
+type Services interface {
+	AddAttachment(ctx context.Context, expenseId int64, filename string, file io.ReadSeeker, size int64) (*AttachmentResponse, bool, error)
+	ListAttachments(ctx context.Context, expenseId int64) ([]AttachmentResponse, error)
+	DownloadAttachment(ctx context.Context, expenseId int64, id int64) (*AttachmentResponse, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, expenseId int64, id int64) error
+}
+
+type Handler struct {
+	log     common.Log
+	service Services
+}
+
+func NewHandler(s Services, l common.Log) *Handler {
+	return &Handler{service: s, log: l}
+}
+
+func paramIds(c echo.Context) (int64, int64, error) {
+	expenseId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	id, err := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+	return expenseId, id, err
+}
+
+// AddAttachment answers 201 for a new attachment and 200 when the expense
+// already had the same content.
+func (h Handler) AddAttachment(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.AddAttachment")
+	defer span.End()
+
+	expenseId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	header, err := c.FormFile(formFile)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	defer file.Close()
+
+	resp, created, err := h.service.AddAttachment(ctx, expenseId, header.Filename, file, header.Size)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler AddAttachment Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if !created {
+		return c.JSON(http.StatusOK, resp)
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (h Handler) ListAttachments(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.ListAttachments")
+	defer span.End()
+
+	expenseId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.ListAttachments(ctx, expenseId)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler ListAttachments Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) DownloadAttachment(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.DownloadAttachment")
+	defer span.End()
+
+	expenseId, id, err := paramIds(c)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, content, err := h.service.DownloadAttachment(ctx, expenseId, id)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler DownloadAttachment Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	defer content.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": resp.Filename}))
+	c.Response().Header().Set(echo.HeaderContentLength, strconv.FormatInt(resp.Size, 10))
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	return c.Stream(http.StatusOK, resp.ContentType, content)
+}
+
+func (h Handler) DeleteAttachment(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.DeleteAttachment")
+	defer span.End()
+
+	expenseId, id, err := paramIds(c)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if err := h.service.DeleteAttachment(ctx, expenseId, id); err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler DeleteAttachment Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
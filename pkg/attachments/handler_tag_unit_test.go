@@ -0,0 +1,154 @@
+//go:build unit
+
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ServiceStub struct {
+	filename string
+	content  []byte
+	created  bool
+	code     int
+}
+
+func (s *ServiceStub) error() error {
+	if s.code != 0 {
+		return &common.Error{Code: s.code}
+	}
+	return nil
+}
+
+func (s *ServiceStub) AddAttachment(ctx context.Context, expenseId int64, filename string, file io.ReadSeeker, size int64) (*AttachmentResponse, bool, error) {
+	s.filename = filename
+	s.content, _ = io.ReadAll(file)
+	if err := s.error(); err != nil {
+		return nil, false, err
+	}
+	return &AttachmentResponse{Id: 1, ExpenseId: expenseId, Filename: filename, Size: size}, s.created, nil
+}
+
+func (s *ServiceStub) ListAttachments(ctx context.Context, expenseId int64) ([]AttachmentResponse, error) {
+	return nil, s.error()
+}
+
+func (s *ServiceStub) DownloadAttachment(ctx context.Context, expenseId int64, id int64) (*AttachmentResponse, io.ReadCloser, error) {
+	if err := s.error(); err != nil {
+		return nil, nil, err
+	}
+	resp := &AttachmentResponse{Id: id, ExpenseId: expenseId, Filename: "ใบเสร็จ.png", ContentType: "image/png", Size: int64(len(png))}
+	return resp, io.NopCloser(bytes.NewReader(png)), nil
+}
+
+func (s *ServiceStub) DeleteAttachment(ctx context.Context, expenseId int64, id int64) error {
+	return s.error()
+}
+
+func multipartBody(t *testing.T, filename string, content []byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	part, err := w.CreateFormFile(formFile, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	w.Close()
+	return body, w.FormDataContentType()
+}
+
+func TestAddAttachmentHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		created bool
+		want    int
+	}{
+		{name: "should return http status code = 201 for a new attachment", created: true, want: http.StatusCreated},
+		{name: "should return http status code = 200 when the content is already attached", created: false, want: http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, "receipt.png", png)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/expenses/1/attachments", body)
+			req.Header.Set(echo.HeaderContentType, contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+			service := &ServiceStub{created: tc.created}
+
+			err := NewHandler(service, logrus.New()).AddAttachment(c)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, rec.Code)
+				assert.Equal(t, "receipt.png", service.filename)
+				assert.Equal(t, png, service.content)
+				resp := &AttachmentResponse{}
+				json.Unmarshal(rec.Body.Bytes(), resp)
+				assert.Equal(t, int64(len(png)), resp.Size)
+			}
+		})
+	}
+
+	t.Run("should return http status code = 400 without a file", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/attachments", strings.NewReader("{}"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		err := NewHandler(&ServiceStub{}, logrus.New()).AddAttachment(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestDownloadAttachmentHandler(t *testing.T) {
+	t.Run("should stream the content as a download", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/expenses/1/attachments/7", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "attachmentId")
+		c.SetParamValues("1", "7")
+
+		err := NewHandler(&ServiceStub{}, logrus.New()).DownloadAttachment(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "image/png", rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, "attachment; filename*=utf-8''%E0%B9%83%E0%B8%9A%E0%B9%80%E0%B8%AA%E0%B8%A3%E0%B9%87%E0%B8%88.png", rec.Header().Get(echo.HeaderContentDisposition))
+			assert.Equal(t, png, rec.Body.Bytes())
+		}
+	})
+
+	t.Run("should return http status code = 404 when the attachment does not exist", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/expenses/1/attachments/404", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "attachmentId")
+		c.SetParamValues("1", "404")
+
+		err := NewHandler(&ServiceStub{code: http.StatusNotFound}, logrus.New()).DownloadAttachment(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
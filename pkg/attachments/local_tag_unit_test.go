@@ -0,0 +1,17 @@
+//go:build unit
+
+package attachments
+
+import (
+	"testing"
+)
+
+func TestLocalStoreConformance(t *testing.T) {
+	runBlobStoreConformance(t, func(t *testing.T) BlobStore {
+		store, err := NewLocalStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
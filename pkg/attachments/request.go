@@ -0,0 +1,11 @@
+package attachments
+
+// Upload is the metadata of an uploaded file, Sha256 is the hex digest of its
+// content and its key in the BlobStore.
+type Upload struct {
+	ExpenseId   int64
+	Filename    string
+	ContentType string
+	Size        int64
+	Sha256      string
+}
@@ -0,0 +1,13 @@
+package attachments
+
+import "time"
+
+type AttachmentResponse struct {
+	Id          int64     `json:"id"`
+	ExpenseId   int64     `json:"expense_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Sha256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
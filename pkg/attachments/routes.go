@@ -0,0 +1,63 @@
+package attachments
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/apiversion"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// multipartOverhead is allowed on top of the file for the multipart headers.
+const multipartOverhead = 1 << 20
+
+// operations are the routes of Routes, the same in every version of the api.
+var operations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/expenses/:id/attachments", Id: "AddAttachment", Summary: "Attach a file to an expense", Tag: "attachments",
+		Body: &openapi.Schema{Type: "object", Required: []string{formFile}, Properties: map[string]*openapi.Schema{
+			formFile: {Type: "string", ContentMediaType: "application/octet-stream"},
+		}}, BodyType: "multipart/form-data", Responses: []openapi.Response{
+			{Status: http.StatusCreated, Description: "the attachment", Body: AttachmentResponse{}},
+			{Status: http.StatusOK, Description: "the expense already has the file", Body: AttachmentResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+			{Status: http.StatusRequestEntityTooLarge, Description: "the file is too large"},
+			{Status: http.StatusUnsupportedMediaType, Description: "the type of the file is not allowed"},
+		}},
+	{Method: http.MethodGet, Path: "/expenses/:id/attachments", Id: "ListAttachments", Summary: "List the attachments of an expense", Tag: "attachments",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the attachments", Body: []AttachmentResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodGet, Path: "/expenses/:id/attachments/:attachmentId", Id: "DownloadAttachment", Summary: "Download an attachment", Tag: "attachments",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the file, with its content type", ContentType: "application/octet-stream"},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodDelete, Path: "/expenses/:id/attachments/:attachmentId", Id: "DeleteAttachment", Summary: "Delete an attachment", Tag: "attachments",
+		Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "deleted"},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+}
+
+// Operations describe the routes of Routes in the OpenAPI document, in every
+// version of versions.
+func Operations(versions apiversion.Versions) []openapi.Operation {
+	return versions.Same(1, operations)
+}
+
+func Routes(echo *echo.Echo, ins *config.Instance, storage Storage, blobs BlobStore) {
+	cf := ins.Config.Get()
+	attachmentService := NewService(storage, blobs, cf.AttachmentMaxSize(), cf.AttachmentAllowedTypes(), ins.Log)
+	attachmentHandler := NewHandler(attachmentService, ins.Log)
+	bodyLimit := middleware.BodyLimit(strconv.FormatInt(cf.AttachmentMaxSize()+multipartOverhead, 10))
+
+	versions := apiversion.Served(cf)
+	versions.Add(echo, 1, http.MethodPost, "/expenses/:id/attachments", attachmentHandler.AddAttachment, bodyLimit)
+	versions.Add(echo, 1, http.MethodGet, "/expenses/:id/attachments", attachmentHandler.ListAttachments)
+	versions.Add(echo, 1, http.MethodGet, "/expenses/:id/attachments/:attachmentId", attachmentHandler.DownloadAttachment)
+	versions.Add(echo, 1, http.MethodDelete, "/expenses/:id/attachments/:attachmentId", attachmentHandler.DeleteAttachment)
+}
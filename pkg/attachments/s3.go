@@ -0,0 +1,80 @@
+package attachments
+
+import (
+	"context"
+	"io"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type S3Config struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3Store keeps blobs as objects of a bucket of an S3-compatible store such as
+// AWS S3 or MinIO. The bucket must exist.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3Store(cf S3Config) (*S3Store, error) {
+	client, err := minio.New(cf.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cf.AccessKey, cf.SecretKey, ""),
+		Secure: cf.UseSSL,
+		Region: cf.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{client: client, bucket: cf.Bucket}, nil
+}
+
+func s3Error(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return common.ErrNotFound
+	}
+	return err
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		return nil
+	}
+	if s3Error(err) != common.ErrNotFound {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	// GetObject is lazy, Stat surfaces a missing key before the caller starts
+	// writing a response.
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, s3Error(err)
+	}
+	return obj, nil
+}
+
+// Delete removes the object. S3 does not report missing keys on delete, so it
+// is checked first.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if _, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{}); err != nil {
+		return s3Error(err)
+	}
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
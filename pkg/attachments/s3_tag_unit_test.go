@@ -0,0 +1,36 @@
+//go:build unit
+
+package attachments
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+// TestS3StoreConformance runs S3Store against an in-process S3 stand-in.
+func TestS3StoreConformance(t *testing.T) {
+	runBlobStoreConformance(t, func(t *testing.T) BlobStore {
+		backend := s3mem.New()
+		if err := backend.CreateBucket("receipts"); err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(gofakes3.New(backend).Server())
+		t.Cleanup(server.Close)
+
+		store, err := NewS3Store(S3Config{
+			Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+			Bucket:    "receipts",
+			Region:    "us-east-1",
+			AccessKey: "access",
+			SecretKey: "secret",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
@@ -0,0 +1,169 @@
+package attachments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+)
+
+type Storage interface {
+	Insert(ctx context.Context, up Upload, put func(ctx context.Context) error, remove func(ctx context.Context, sha256 string) error) (*AttachmentResponse, bool, error)
+	SearchById(ctx context.Context, expenseId int64, id int64) (*AttachmentResponse, error)
+	List(ctx context.Context, expenseId int64) ([]AttachmentResponse, error)
+	Delete(ctx context.Context, expenseId int64, id int64, remove func(ctx context.Context, sha256 string) error) error
+}
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+const maxFilenameLen = 255
+
+type Service struct {
+	log          common.Log
+	storage      Storage
+	blobs        BlobStore
+	maxSize      int64
+	allowedTypes map[string]bool
+}
+
+func NewService(s Storage, b BlobStore, maxSize int64, allowedTypes []string, l common.Log) *Service {
+	allowed := map[string]bool{}
+	for _, t := range allowedTypes {
+		allowed[t] = true
+	}
+	return &Service{storage: s, blobs: b, maxSize: maxSize, allowedTypes: allowed, log: l}
+}
+
+// detectType sniffs the content type of file, the type sent by the client is
+// not trusted.
+func detectType(file io.ReadSeeker) (string, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	return mediaType, err
+}
+
+func hash(file io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cleanFilename keeps the base name of filename without control characters.
+func cleanFilename(filename string) string {
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filepath.Base(strings.ReplaceAll(filename, "\\", "/")))
+	if filename == "." || filename == "/" || filename == "" {
+		filename = "attachment"
+	}
+	if len(filename) > maxFilenameLen {
+		filename = filename[:maxFilenameLen]
+	}
+	return filename
+}
+
+// AddAttachment stores file for the expense. The returned bool is false when
+// the expense already had the same content and the existing attachment is
+// returned.
+func (s Service) AddAttachment(ctx context.Context, expenseId int64, filename string, file io.ReadSeeker, size int64) (*AttachmentResponse, bool, error) {
+	ctx, span := tracing.Start(ctx, "Service.AddAttachment")
+	defer span.End()
+
+	if size > s.maxSize {
+		return nil, false, &common.Error{Code: http.StatusRequestEntityTooLarge, Desc: "Insert Attachment Error : file too large"}
+	}
+	contentType, err := detectType(file)
+	if err != nil {
+		return nil, false, &common.Error{Code: http.StatusBadRequest, Desc: "Insert Attachment Error", OriginalError: err}
+	}
+	if !s.allowedTypes[contentType] {
+		return nil, false, &common.Error{Code: http.StatusUnsupportedMediaType, Desc: "Insert Attachment Error : unsupported type " + contentType}
+	}
+	sum, err := hash(file)
+	if err != nil {
+		return nil, false, &common.Error{Code: http.StatusBadRequest, Desc: "Insert Attachment Error", OriginalError: err}
+	}
+
+	up := Upload{ExpenseId: expenseId, Filename: cleanFilename(filename), ContentType: contentType, Size: size, Sha256: sum}
+	resp, created, err := s.storage.Insert(ctx, up, func(ctx context.Context) error {
+		return s.blobs.Put(ctx, sum, file, size, contentType)
+	}, s.blobs.Delete)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Insert Attachment Error : %s", err)
+		return nil, false, &common.Error{Code: common.StatusFromError(err), Desc: "Insert Attachment Error", OriginalError: err}
+	}
+	return resp, created, nil
+}
+
+func (s Service) ListAttachments(ctx context.Context, expenseId int64) ([]AttachmentResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.ListAttachments")
+	defer span.End()
+
+	resp, err := s.storage.List(ctx, expenseId)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("List Attachments Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "List Attachments Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+// DownloadAttachment returns the attachment with its content, the caller
+// closes the content.
+func (s Service) DownloadAttachment(ctx context.Context, expenseId int64, id int64) (*AttachmentResponse, io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "Service.DownloadAttachment")
+	defer span.End()
+
+	resp, err := s.storage.SearchById(ctx, expenseId, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Search Attachment By Id Error : %s", err)
+		return nil, nil, &common.Error{Code: common.StatusFromError(err), Desc: "Search Attachment By Id Error", OriginalError: err}
+	}
+
+	content, err := s.blobs.Get(ctx, resp.Sha256)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Download Attachment Error : %s", err)
+		return nil, nil, &common.Error{Code: common.StatusFromError(err), Desc: "Download Attachment Error", OriginalError: err}
+	}
+	return resp, content, nil
+}
+
+func (s Service) DeleteAttachment(ctx context.Context, expenseId int64, id int64) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteAttachment")
+	defer span.End()
+
+	err := s.storage.Delete(ctx, expenseId, id, s.blobs.Delete)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Delete Attachment Error : %s", err)
+		return &common.Error{Code: common.StatusFromError(err), Desc: "Delete Attachment Error", OriginalError: err}
+	}
+	return nil
+}
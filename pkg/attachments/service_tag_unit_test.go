@@ -0,0 +1,127 @@
+//go:build unit
+
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// png is the smallest content sniffed as image/png.
+var png = []byte("\x89PNG\x0D\x0A\x1A\x0Areceipt")
+
+type StorageStub struct {
+	up      Upload
+	created bool
+	err     error
+}
+
+func (s *StorageStub) Insert(ctx context.Context, up Upload, put func(ctx context.Context) error, remove func(ctx context.Context, sha256 string) error) (*AttachmentResponse, bool, error) {
+	s.up = up
+	if s.err != nil {
+		return nil, false, s.err
+	}
+	if s.created {
+		if err := put(ctx); err != nil {
+			return nil, false, err
+		}
+	}
+	return &AttachmentResponse{Id: 1, ExpenseId: up.ExpenseId, Filename: up.Filename, ContentType: up.ContentType, Size: up.Size, Sha256: up.Sha256}, s.created, nil
+}
+
+func (s *StorageStub) SearchById(ctx context.Context, expenseId int64, id int64) (*AttachmentResponse, error) {
+	return nil, s.err
+}
+
+func (s *StorageStub) List(ctx context.Context, expenseId int64) ([]AttachmentResponse, error) {
+	return nil, s.err
+}
+
+func (s *StorageStub) Delete(ctx context.Context, expenseId int64, id int64, remove func(ctx context.Context, sha256 string) error) error {
+	return s.err
+}
+
+type BlobStub struct {
+	blobs map[string][]byte
+}
+
+func (b *BlobStub) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	content, err := io.ReadAll(r)
+	b.blobs[key] = content
+	return err
+}
+
+func (b *BlobStub) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(b.blobs[key])), nil
+}
+
+func (b *BlobStub) Delete(ctx context.Context, key string) error {
+	delete(b.blobs, key)
+	return nil
+}
+
+func newService(storage Storage, blobs BlobStore) *Service {
+	return NewService(storage, blobs, 64, []string{"image/png"}, logrus.New())
+}
+
+func TestAddAttachment(t *testing.T) {
+	sum := sha256.Sum256(png)
+	wantHash := hex.EncodeToString(sum[:])
+
+	t.Run("should store the content under its hash with the sniffed type", func(t *testing.T) {
+		storage := &StorageStub{created: true}
+		blobs := &BlobStub{blobs: map[string][]byte{}}
+
+		resp, created, err := newService(storage, blobs).AddAttachment(context.Background(), 1, "../../receipt.png", bytes.NewReader(png), int64(len(png)))
+
+		assert.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, Upload{ExpenseId: 1, Filename: "receipt.png", ContentType: "image/png", Size: int64(len(png)), Sha256: wantHash}, storage.up)
+		assert.Equal(t, wantHash, resp.Sha256)
+		assert.Equal(t, png, blobs.blobs[wantHash])
+	})
+
+	t.Run("should return error code 413 for a file over the size limit", func(t *testing.T) {
+		storage := &StorageStub{}
+
+		_, _, err := newService(storage, &BlobStub{}).AddAttachment(context.Background(), 1, "big.png", bytes.NewReader(png), 65)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*common.Error).Code)
+		assert.Equal(t, Upload{}, storage.up)
+	})
+
+	t.Run("should return error code 415 for a type that is not allowed whatever its name", func(t *testing.T) {
+		storage := &StorageStub{}
+		content := []byte("%PDF-1.4 receipt")
+
+		_, _, err := newService(storage, &BlobStub{}).AddAttachment(context.Background(), 1, "receipt.png", bytes.NewReader(content), int64(len(content)))
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, err.(*common.Error).Code)
+		assert.Equal(t, Upload{}, storage.up)
+	})
+
+	t.Run("should return error code 404 when the expense does not exist", func(t *testing.T) {
+		storage := &StorageStub{err: common.ErrNotFound}
+
+		_, _, err := newService(storage, &BlobStub{}).AddAttachment(context.Background(), 404, "receipt.png", bytes.NewReader(png), int64(len(png)))
+
+		assert.Equal(t, http.StatusNotFound, err.(*common.Error).Code)
+	})
+}
+
+func TestCleanFilename(t *testing.T) {
+	t.Run("should keep only the base name without control characters", func(t *testing.T) {
+		assert.Equal(t, "receipt.png", cleanFilename(`C:\Users\me\receipt.png`))
+		assert.Equal(t, "receipt.png", cleanFilename("../receipt\r\n.png"))
+		assert.Equal(t, "attachment", cleanFilename(""))
+	})
+}
@@ -0,0 +1,31 @@
+package attachments
+
+import (
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+)
+
+// NewStorage creates the metadata Storage of the configured backend. Only
+// postgres keeps attachments, the other backends return common.ErrUnsupported.
+func NewStorage(ins *config.Instance) (Storage, error) {
+	cf := ins.Config.Get()
+	if cf.StorageBackend() != config.BackendPostgres {
+		return nil, common.ErrUnsupported
+	}
+	return New(ins.DB, cf.DbTimeout()), nil
+}
+
+// NewBlobStore creates the configured BlobStore.
+func NewBlobStore(cf config.Config) (BlobStore, error) {
+	if cf.AttachmentStore() == config.AttachmentStoreS3 {
+		return NewS3Store(S3Config{
+			Endpoint:  cf.S3Endpoint(),
+			Bucket:    cf.S3Bucket(),
+			Region:    cf.S3Region(),
+			AccessKey: cf.S3AccessKey(),
+			SecretKey: cf.S3SecretKey(),
+			UseSSL:    cf.S3UseSSL(),
+		})
+	}
+	return NewLocalStore(cf.AttachmentDir())
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/lib/pq"
+)
+
+const (
+	minReconnect = time.Second
+	maxReconnect = time.Minute
+	// pingInterval detects a lost connection when no notification comes.
+	pingInterval = 90 * time.Second
+)
+
+// Invalidator is the cache of the writes notified on a channel.
+type Invalidator interface {
+	// Invalidate drops the value named by the payload of a notification.
+	Invalidate(payload string)
+	// SetEnabled disables the cache while the notifications may be lost and
+	// enables it again, empty, once they are received.
+	SetEnabled(enabled bool)
+}
+
+// Listener tells an Invalidator about the notifications of a postgres
+// channel. It holds a connection of its own, out of the pool.
+type Listener struct {
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+// Listen listens to channel on the database of url until Close is called. The
+// target is disabled until the Listener listens.
+func Listen(url string, channel string, target Invalidator, log common.Log) *Listener {
+	target.SetEnabled(false)
+	listener := pq.NewListener(url, minReconnect, maxReconnect, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			target.SetEnabled(false)
+			log.Warnf("Cache listener disconnected, cache disabled : %s", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Warnf("Cache listener connect fail : %s", err)
+		}
+	})
+	l := &Listener{listener: listener, done: make(chan struct{})}
+
+	go func() {
+		defer close(l.done)
+		if err := listener.Listen(channel); err != nil {
+			log.Errorf("Cache listen fail, cache disabled : %s", err)
+			return
+		}
+		target.SetEnabled(true)
+
+		ping := time.NewTicker(pingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case n, ok := <-listener.Notify:
+				if !ok {
+					return
+				}
+				if n == nil {
+					// reconnected, the notifications meanwhile are lost
+					target.SetEnabled(true)
+					continue
+				}
+				target.Invalidate(n.Extra)
+			case <-ping.C:
+				go listener.Ping()
+			}
+		}
+	}()
+	return l
+}
+
+// Close stops listening.
+func (l *Listener) Close() error {
+	err := l.listener.Close()
+	<-l.done
+	return err
+}
@@ -0,0 +1,128 @@
+// Package cache keeps recently read values in process memory, dropped when the
+// database tells they were written.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a cache of at most size values, evicting the least recently used one
+// when full. A value expires ttl after it was added.
+//
+// A reader adding the value it read from the database races with a write
+// removing the value meanwhile: Add takes the Generation read before the read
+// and drops the value when a Remove or Purge happened since.
+type LRU[K comparable, V any] struct {
+	mu         sync.Mutex
+	size       int
+	ttl        time.Duration
+	items      map[K]*list.Element
+	order      *list.List
+	generation uint64
+	disabled   bool
+	now        func() time.Time
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+func NewLRU[K comparable, V any](size int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{size: size, ttl: ttl, items: map[K]*list.Element{}, order: list.New(), now: time.Now}
+}
+
+// Get returns the value of key unless it is missing or expired.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if !c.now().Before(e.expiresAt) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Generation changes with every Remove and Purge.
+func (c *LRU[K, V]) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Add adds the value of key read at generation and reports whether it was
+// added. A disabled cache adds nothing.
+func (c *LRU[K, V]) Add(key K, value V, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.disabled || generation != c.generation {
+		return false
+	}
+	e := &entry[K, V]{key: key, value: value, expiresAt: c.now().Add(c.ttl)}
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return true
+	}
+	c.items[key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[K, V]).key)
+	}
+	return true
+}
+
+// Remove drops the value of key.
+func (c *LRU[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Purge drops every value.
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.items = map[K]*list.Element{}
+	c.order.Init()
+}
+
+// SetEnabled purges the cache and lets it add values again or not, while the
+// writes cannot be told to it.
+func (c *LRU[K, V]) SetEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.disabled = !enabled
+	c.items = map[K]*list.Element{}
+	c.order.Init()
+}
+
+// Len is the number of values in the cache, the expired ones included.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
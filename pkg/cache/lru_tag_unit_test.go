@@ -0,0 +1,74 @@
+//go:build unit
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("should evict the least recently used value", func(t *testing.T) {
+		c := NewLRU[int, string](2, time.Minute)
+		c.Add(1, "a", c.Generation())
+		c.Add(2, "b", c.Generation())
+		c.Get(1)
+
+		c.Add(3, "c", c.Generation())
+
+		_, ok := c.Get(2)
+		assert.False(t, ok)
+		a, _ := c.Get(1)
+		assert.Equal(t, "a", a)
+		assert.Equal(t, 2, c.Len())
+	})
+
+	t.Run("should expire a value after the ttl", func(t *testing.T) {
+		now := time.Now()
+		c := NewLRU[int, string](2, time.Minute)
+		c.now = func() time.Time { return now }
+		c.Add(1, "a", c.Generation())
+
+		now = now.Add(time.Minute)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("should not add a value read before a removal", func(t *testing.T) {
+		c := NewLRU[int, string](2, time.Minute)
+		generation := c.Generation()
+
+		c.Remove(1)
+
+		assert.False(t, c.Add(1, "stale", generation))
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+	})
+
+	t.Run("should remove and purge the values", func(t *testing.T) {
+		c := NewLRU[int, string](3, time.Minute)
+		c.Add(1, "a", c.Generation())
+		c.Add(2, "b", c.Generation())
+
+		c.Remove(1)
+		assert.Equal(t, 1, c.Len())
+		c.Purge()
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("should add nothing while disabled", func(t *testing.T) {
+		c := NewLRU[int, string](3, time.Minute)
+		c.Add(1, "a", c.Generation())
+
+		c.SetEnabled(false)
+		assert.Equal(t, 0, c.Len())
+		assert.False(t, c.Add(1, "a", c.Generation()))
+
+		c.SetEnabled(true)
+		assert.True(t, c.Add(1, "a", c.Generation()))
+	})
+}
@@ -0,0 +1,298 @@
+package categories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/lib/pq"
+)
+
+const (
+	insertQuery      = "INSERT INTO categories (name, parent_id) values ($1, $2) RETURNING id, name, parent_id"
+	searchByIdQuery  = "select id, name, parent_id from categories where id = $1"
+	listQuery        = "select id, name, parent_id from categories order by id"
+	searchByIdsQuery = "select id, name, parent_id from categories where id = any($1) order by id"
+	updateQuery      = "UPDATE categories SET name = $1, parent_id = $2 WHERE id = $3 RETURNING id, name, parent_id"
+	deleteQuery      = "DELETE FROM categories WHERE id = $1"
+	// lockQuery serializes the moves of categories, two concurrent moves could
+	// otherwise each pass the cycle check and create a cycle together.
+	lockQuery = "LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE"
+	// cycleQuery reports whether $2 is $1 or one of its ancestors.
+	cycleQuery = `WITH RECURSIVE ancestors AS (
+select id, parent_id from categories where id = $1
+UNION ALL
+select c.id, c.parent_id from categories c join ancestors a on c.id = a.parent_id)
+select exists (select 1 from ancestors where id = $2)`
+	// summaryQuery pairs every category with itself and its descendants and
+	// sums the expenses of the pairs.
+	summaryQuery = `WITH RECURSIVE tree AS (
+select id AS root, id from categories
+UNION ALL
+select t.root, c.id from tree t join categories c on c.parent_id = t.id),
+spent AS (select category_id, sum(amount) AS amount, count(*) AS count from expenses where category_id is not null group by category_id)
+select c.id, c.name, c.parent_id, coalesce(own.amount, 0), coalesce(own.count, 0), coalesce(sum(s.amount), 0), coalesce(sum(s.count), 0)
+from categories c
+join tree t on t.root = c.id
+left join spent s on s.category_id = t.id
+left join spent own on own.category_id = c.id
+group by c.id, c.name, c.parent_id, own.amount, own.count
+order by c.id`
+)
+
+// postgres error codes
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// ErrCycle is returned when a category would become its own ancestor.
+var ErrCycle = fmt.Errorf("%w: a category cannot be its own ancestor", common.ErrInvalid)
+
+type DataMgmt struct {
+	dataMgmt *sql.DB
+	timeout  time.Duration
+}
+
+func New(d *sql.DB, timeout time.Duration) *DataMgmt {
+	return &DataMgmt{dataMgmt: d, timeout: timeout}
+}
+
+func (mgmt DataMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+// queryError prefers the context error, maps a missing row to
+// common.ErrNotFound, a duplicate sibling name to common.ErrConflict and an
+// unknown parent to common.ErrInvalid.
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case uniqueViolation:
+			err = common.ErrConflict
+		case foreignKeyViolation:
+			err = fmt.Errorf("%w: %s", common.ErrInvalid, pqErr.Message)
+		}
+	}
+	tracing.RecordError(ctx, err)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCategory(row scanner) (*CategoryResponse, error) {
+	result := &CategoryResponse{}
+	var parent sql.NullInt64
+	if err := row.Scan(&result.Id, &result.Name, &parent); err != nil {
+		return nil, err
+	}
+	if parent.Valid {
+		result.ParentId = &parent.Int64
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) Insert(ctx context.Context, req CategoryRequest) (*CategoryResponse, error) {
+	defer metrics.ObserveQuery("InsertCategory", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "CategoryMgmt.Insert", insertQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := scanCategory(mgmt.dataMgmt.QueryRowContext(ctx, insertQuery, req.Name, req.ParentId))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) SearchById(ctx context.Context, id int64) (*CategoryResponse, error) {
+	defer metrics.ObserveQuery("SearchCategoryById", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "CategoryMgmt.SearchById", searchByIdQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := scanCategory(mgmt.dataMgmt.QueryRowContext(ctx, searchByIdQuery, id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) List(ctx context.Context) ([]CategoryResponse, error) {
+	defer metrics.ObserveQuery("ListCategories", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "CategoryMgmt.List", listQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, listQuery)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []CategoryResponse{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		result = append(result, *category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// SearchByIds returns the categories of ids in one query, the ids without
+// category are left out.
+func (mgmt DataMgmt) SearchByIds(ctx context.Context, ids []int64) ([]CategoryResponse, error) {
+	defer metrics.ObserveQuery("SearchCategoriesByIds", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "CategoryMgmt.SearchByIds", searchByIdsQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, searchByIdsQuery, pq.Array(ids))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []CategoryResponse{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		result = append(result, *category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Update renames and moves the category, ErrCycle is returned when the new
+// parent is the category itself or one of its descendants.
+func (mgmt DataMgmt) Update(ctx context.Context, id int64, req CategoryRequest) (*CategoryResponse, error) {
+	defer metrics.ObserveQuery("UpdateCategory", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "CategoryMgmt.Update", updateQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := mgmt.dataMgmt.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	if req.ParentId != nil {
+		if _, err := tx.ExecContext(ctx, lockQuery); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		var cycle bool
+		if err := tx.QueryRowContext(ctx, cycleQuery, *req.ParentId, id).Scan(&cycle); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		if cycle {
+			return nil, queryError(ctx, ErrCycle)
+		}
+	}
+
+	result, err := scanCategory(tx.QueryRowContext(ctx, updateQuery, req.Name, req.ParentId, id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Delete removes a category without children, its expenses become
+// uncategorized. common.ErrConflict is returned while it has children.
+func (mgmt DataMgmt) Delete(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("DeleteCategory", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "CategoryMgmt.Delete", deleteQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := mgmt.dataMgmt.ExecContext(ctx, deleteQuery, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		err = common.ErrConflict
+	}
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		if err == nil {
+			err = common.ErrNotFound
+		}
+		return queryError(ctx, err)
+	}
+	return nil
+}
+
+// Summary returns the spending of every category rolled up to its ancestors.
+func (mgmt DataMgmt) Summary(ctx context.Context) ([]SummaryResponse, error) {
+	defer metrics.ObserveQuery("SummaryCategories", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "CategoryMgmt.Summary", summaryQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, summaryQuery)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []SummaryResponse{}
+	for rows.Next() {
+		summary := SummaryResponse{}
+		var parent sql.NullInt64
+		err := rows.Scan(&summary.Id, &summary.Name, &parent, &summary.Amount, &summary.Count, &summary.TotalAmount, &summary.TotalCount)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		if parent.Valid {
+			summary.ParentId = &parent.Int64
+		}
+		result = append(result, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
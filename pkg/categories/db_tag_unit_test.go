@@ -0,0 +1,156 @@
+//go:build unit
+
+package categories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsert(t *testing.T) {
+	t.Run("should insert a child category", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		parent := int64(1)
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs("Flights", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(2, "Flights", 1))
+
+		result, err := New(db, time.Second).Insert(context.Background(), CategoryRequest{Name: "Flights", ParentId: &parent})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &CategoryResponse{Id: 2, Name: "Flights", ParentId: &parent}, result)
+	})
+
+	t.Run("should return common.ErrInvalid for an unknown parent", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		parent := int64(404)
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs("Flights", 404).WillReturnError(&pq.Error{Code: "23503"})
+
+		result, err := New(db, time.Second).Insert(context.Background(), CategoryRequest{Name: "Flights", ParentId: &parent})
+
+		assert.ErrorIs(t, err, common.ErrInvalid)
+		assert.Nil(t, result)
+	})
+
+	t.Run("should return common.ErrConflict for a duplicate sibling name", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs("Travel", nil).WillReturnError(&pq.Error{Code: "23505"})
+
+		_, err = New(db, time.Second).Insert(context.Background(), CategoryRequest{Name: "Travel"})
+
+		assert.ErrorIs(t, err, common.ErrConflict)
+	})
+}
+
+func TestSearchByIds(t *testing.T) {
+	t.Run("should read every category in one query", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		parent := int64(1)
+		mock.ExpectQuery(regexp.QuoteMeta(searchByIdsQuery)).WithArgs(pq.Array([]int64{1, 2, 404})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(1, "Travel", nil).AddRow(2, "Flights", 1))
+
+		result, err := New(db, time.Second).SearchByIds(context.Background(), []int64{1, 2, 404})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []CategoryResponse{{Id: 1, Name: "Travel"}, {Id: 2, Name: "Flights", ParentId: &parent}}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("should move the category when the parent is not a descendant", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		parent := int64(1)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(cycleQuery)).WithArgs(1, 3).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(regexp.QuoteMeta(updateQuery)).WithArgs("Domestic", 1, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(3, "Domestic", 1))
+		mock.ExpectCommit()
+
+		result, err := New(db, time.Second).Update(context.Background(), 3, CategoryRequest{Name: "Domestic", ParentId: &parent})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &CategoryResponse{Id: 3, Name: "Domestic", ParentId: &parent}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ErrCycle and roll back when the parent is a descendant", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		parent := int64(3)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(cycleQuery)).WithArgs(3, 1).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		result, err := New(db, time.Second).Update(context.Background(), 1, CategoryRequest{Name: "Travel", ParentId: &parent})
+
+		assert.ErrorIs(t, err, ErrCycle)
+		assert.ErrorIs(t, err, common.ErrInvalid)
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("should return common.ErrConflict while the category has children", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(1).WillReturnError(&pq.Error{Code: "23503"})
+
+		err = New(db, time.Second).Delete(context.Background(), 1)
+
+		assert.ErrorIs(t, err, common.ErrConflict)
+	})
+
+	t.Run("should return common.ErrNotFound for an unknown category", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(404).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = New(db, time.Second).Delete(context.Background(), 404)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+	})
+}
+
+func TestSummary(t *testing.T) {
+	t.Run("should return the own and rolled up spending of every category", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		rows := sqlmock.NewRows([]string{"id", "name", "parent_id", "amount", "count", "total_amount", "total_count"}).
+			AddRow(1, "Travel", nil, 100, 1, 3600, 3).
+			AddRow(2, "Flights", 1, 3500, 2, 3500, 2)
+		mock.ExpectQuery(regexp.QuoteMeta(summaryQuery)).WillReturnRows(rows)
+
+		result, err := New(db, time.Second).Summary(context.Background())
+
+		parent := int64(1)
+		assert.NoError(t, err)
+		assert.Equal(t, []SummaryResponse{
+			{Id: 1, Name: "Travel", Amount: 100, Count: 1, TotalAmount: 3600, TotalCount: 3},
+			{Id: 2, Name: "Flights", ParentId: &parent, Amount: 3500, Count: 2, TotalAmount: 3500, TotalCount: 2},
+		}, result)
+	})
+}
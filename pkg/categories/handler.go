@@ -0,0 +1,150 @@
+package categories
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/labstack/echo/v4"
+)
+
+type Services interface {
+	AddCategory(ctx context.Context, req CategoryRequest) (*CategoryResponse, error)
+	SearchCategoryById(ctx context.Context, id int64) (*CategoryResponse, error)
+	ListCategories(ctx context.Context) ([]CategoryResponse, error)
+	UpdateCategory(ctx context.Context, id int64, req CategoryRequest) (*CategoryResponse, error)
+	DeleteCategory(ctx context.Context, id int64) error
+	SummaryCategories(ctx context.Context) ([]SummaryResponse, error)
+}
+
+type Handler struct {
+	log     common.Log
+	service Services
+}
+
+func NewHandler(s Services, l common.Log) *Handler {
+	return &Handler{service: s, log: l}
+}
+
+func (h Handler) AddCategory(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.AddCategory")
+	defer span.End()
+
+	req := CategoryRequest{}
+	if err := c.Bind(&req); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.AddCategory(ctx, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler AddCategory Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (h Handler) SearchCategoryById(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.SearchCategoryById")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.SearchCategoryById(ctx, id)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler SearchCategoryById Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) ListCategories(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.ListCategories")
+	defer span.End()
+
+	resp, err := h.service.ListCategories(ctx)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler ListCategories Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) UpdateCategory(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.UpdateCategory")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	req := CategoryRequest{}
+	if err := c.Bind(&req); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.UpdateCategory(ctx, id, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler UpdateCategory Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) DeleteCategory(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.DeleteCategory")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	err = h.service.DeleteCategory(ctx, id)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler DeleteCategory Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h Handler) SummaryCategories(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.SummaryCategories")
+	defer span.End()
+
+	resp, err := h.service.SummaryCategories(ctx)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler SummaryCategories Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
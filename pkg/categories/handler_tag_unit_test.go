@@ -0,0 +1,123 @@
+//go:build unit
+
+package categories
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ServiceStub struct {
+	req  CategoryRequest
+	code int
+}
+
+func (s *ServiceStub) error() error {
+	if s.code != 0 {
+		return &common.Error{Code: s.code}
+	}
+	return nil
+}
+
+func (s *ServiceStub) AddCategory(ctx context.Context, req CategoryRequest) (*CategoryResponse, error) {
+	s.req = req
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &CategoryResponse{Id: 2, Name: req.Name, ParentId: req.ParentId}, nil
+}
+
+func (s *ServiceStub) SearchCategoryById(ctx context.Context, id int64) (*CategoryResponse, error) {
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &CategoryResponse{Id: id, Name: "Travel"}, nil
+}
+
+func (s *ServiceStub) ListCategories(ctx context.Context) ([]CategoryResponse, error) {
+	return nil, s.error()
+}
+
+func (s *ServiceStub) UpdateCategory(ctx context.Context, id int64, req CategoryRequest) (*CategoryResponse, error) {
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &CategoryResponse{Id: id, Name: req.Name, ParentId: req.ParentId}, nil
+}
+
+func (s *ServiceStub) DeleteCategory(ctx context.Context, id int64) error {
+	return s.error()
+}
+
+func (s *ServiceStub) SummaryCategories(ctx context.Context) ([]SummaryResponse, error) {
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return []SummaryResponse{{Id: 1, Name: "Travel", Amount: 100, Count: 1, TotalAmount: 3600, TotalCount: 3}}, nil
+}
+
+func newContext(method string, target string, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestAddCategoryHandler(t *testing.T) {
+	t.Run("should return http status code = 201 and the category", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, "/categories", `{"name":"Flights","parent_id":1}`)
+		service := &ServiceStub{}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.AddCategory(c)
+
+		parent := int64(1)
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.Equal(t, CategoryRequest{Name: "Flights", ParentId: &parent}, service.req)
+			resp := &CategoryResponse{}
+			json.Unmarshal(rec.Body.Bytes(), resp)
+			assert.Equal(t, &CategoryResponse{Id: 2, Name: "Flights", ParentId: &parent}, resp)
+		}
+	})
+}
+
+func TestUpdateCategoryHandler(t *testing.T) {
+	t.Run("should return http status code = 400 when the service rejects a cycle", func(t *testing.T) {
+		c, rec := newContext(http.MethodPut, "/categories/1", `{"name":"Travel","parent_id":3}`)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		handler := NewHandler(&ServiceStub{code: http.StatusBadRequest}, logrus.New())
+
+		err := handler.UpdateCategory(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestSummaryCategoriesHandler(t *testing.T) {
+	t.Run("should return http status code = 200 and the summaries", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "/categories/summary", "")
+		handler := NewHandler(&ServiceStub{}, logrus.New())
+
+		err := handler.SummaryCategories(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			resp := []SummaryResponse{}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.Equal(t, []SummaryResponse{{Id: 1, Name: "Travel", Amount: 100, Count: 1, TotalAmount: 3600, TotalCount: 3}}, resp)
+		}
+	})
+}
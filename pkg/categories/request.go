@@ -0,0 +1,8 @@
+package categories
+
+// CategoryRequest creates or replaces a category, a nil ParentId makes it a
+// root category.
+type CategoryRequest struct {
+	Name     string `json:"name"`
+	ParentId *int64 `json:"parent_id"`
+}
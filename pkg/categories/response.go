@@ -0,0 +1,20 @@
+package categories
+
+type CategoryResponse struct {
+	Id       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentId *int64 `json:"parent_id"`
+}
+
+// SummaryResponse is the spending of a category. Amount and Count are the
+// expenses of the category itself, TotalAmount and TotalCount also include
+// every descendant.
+type SummaryResponse struct {
+	Id          int64   `json:"id"`
+	Name        string  `json:"name"`
+	ParentId    *int64  `json:"parent_id"`
+	Amount      float64 `json:"amount"`
+	Count       int64   `json:"count"`
+	TotalAmount float64 `json:"total_amount"`
+	TotalCount  int64   `json:"total_count"`
+}
@@ -0,0 +1,53 @@
+package categories
+
+import (
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/labstack/echo/v4"
+)
+
+// Operations describe the routes of Routes in the OpenAPI document.
+var Operations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/categories", Id: "AddCategory", Summary: "Create a category", Tag: "categories",
+		Body: CategoryRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusCreated, Description: "the created category", Body: CategoryResponse{}},
+			openapi.BadRequest, openapi.Conflict,
+		}},
+	{Method: http.MethodGet, Path: "/categories", Id: "ListCategories", Summary: "List the categories", Tag: "categories",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the categories", Body: []CategoryResponse{}},
+		}},
+	{Method: http.MethodGet, Path: "/categories/summary", Id: "SummaryCategories", Summary: "Spending of every category", Tag: "categories",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the spending by category", Body: []SummaryResponse{}},
+		}},
+	{Method: http.MethodGet, Path: "/categories/:id", Id: "SearchCategoryById", Summary: "Read a category", Tag: "categories",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the category", Body: CategoryResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodPut, Path: "/categories/:id", Id: "UpdateCategory", Summary: "Replace a category", Tag: "categories",
+		Body: CategoryRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the updated category", Body: CategoryResponse{}},
+			openapi.BadRequest, openapi.NotFound, openapi.Conflict,
+		}},
+	{Method: http.MethodDelete, Path: "/categories/:id", Id: "DeleteCategory", Summary: "Delete a category without children", Tag: "categories",
+		Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "deleted"},
+			openapi.BadRequest, openapi.NotFound, openapi.Conflict,
+		}},
+}
+
+func Routes(echo *echo.Echo, ins *config.Instance, storage Storage) {
+	categoryService := NewService(storage, ins.Log)
+	categoryHandler := NewHandler(categoryService, ins.Log)
+
+	echo.POST("/categories", categoryHandler.AddCategory)
+	echo.GET("/categories", categoryHandler.ListCategories)
+	echo.GET("/categories/summary", categoryHandler.SummaryCategories)
+	echo.GET("/categories/:id", categoryHandler.SearchCategoryById)
+	echo.PUT("/categories/:id", categoryHandler.UpdateCategory)
+	echo.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+}
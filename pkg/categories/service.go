@@ -0,0 +1,131 @@
+package categories
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+)
+
+type Storage interface {
+	Insert(ctx context.Context, req CategoryRequest) (*CategoryResponse, error)
+	SearchById(ctx context.Context, id int64) (*CategoryResponse, error)
+	SearchByIds(ctx context.Context, ids []int64) ([]CategoryResponse, error)
+	List(ctx context.Context) ([]CategoryResponse, error)
+	Update(ctx context.Context, id int64, req CategoryRequest) (*CategoryResponse, error)
+	Delete(ctx context.Context, id int64) error
+	Summary(ctx context.Context) ([]SummaryResponse, error)
+}
+
+type Service struct {
+	log     common.Log
+	storage Storage
+}
+
+func NewService(s Storage, l common.Log) *Service {
+	return &Service{storage: s, log: l}
+}
+
+func (s Service) AddCategory(ctx context.Context, req CategoryRequest) (*CategoryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.AddCategory")
+	defer span.End()
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "Insert Category Error : empty name"}
+	}
+
+	resp, err := s.storage.Insert(ctx, req)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Insert Category Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Insert Category Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) SearchCategoryById(ctx context.Context, id int64) (*CategoryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.SearchCategoryById")
+	defer span.End()
+
+	resp, err := s.storage.SearchById(ctx, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Search Category By Id Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Search Category By Id Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+// SearchCategoriesByIds reads the categories of ids at once, the unknown ids
+// are left out.
+func (s Service) SearchCategoriesByIds(ctx context.Context, ids []int64) ([]CategoryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.SearchCategoriesByIds")
+	defer span.End()
+
+	resp, err := s.storage.SearchByIds(ctx, ids)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Search Categories By Ids Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Search Categories By Ids Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) ListCategories(ctx context.Context) ([]CategoryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.ListCategories")
+	defer span.End()
+
+	resp, err := s.storage.List(ctx)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("List Categories Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "List Categories Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) UpdateCategory(ctx context.Context, id int64, req CategoryRequest) (*CategoryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateCategory")
+	defer span.End()
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "Update Category Error : empty name"}
+	}
+
+	resp, err := s.storage.Update(ctx, id, req)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Update Category Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Update Category Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) DeleteCategory(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteCategory")
+	defer span.End()
+
+	if err := s.storage.Delete(ctx, id); err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Delete Category Error : %s", err)
+		return &common.Error{Code: common.StatusFromError(err), Desc: "Delete Category Error", OriginalError: err}
+	}
+	return nil
+}
+
+func (s Service) SummaryCategories(ctx context.Context) ([]SummaryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.SummaryCategories")
+	defer span.End()
+
+	resp, err := s.storage.Summary(ctx)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Summary Categories Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Summary Categories Error", OriginalError: err}
+	}
+	return resp, nil
+}
@@ -0,0 +1,98 @@
+//go:build unit
+
+package categories
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type StorageStub struct {
+	insertWasCalled bool
+	req             CategoryRequest
+	err             error
+}
+
+func (s *StorageStub) Insert(ctx context.Context, req CategoryRequest) (*CategoryResponse, error) {
+	s.insertWasCalled = true
+	s.req = req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &CategoryResponse{Id: 1, Name: req.Name, ParentId: req.ParentId}, nil
+}
+
+func (s *StorageStub) SearchById(ctx context.Context, id int64) (*CategoryResponse, error) {
+	return nil, s.err
+}
+
+func (s *StorageStub) SearchByIds(ctx context.Context, ids []int64) ([]CategoryResponse, error) {
+	return nil, s.err
+}
+
+func (s *StorageStub) List(ctx context.Context) ([]CategoryResponse, error) {
+	return nil, s.err
+}
+
+func (s *StorageStub) Update(ctx context.Context, id int64, req CategoryRequest) (*CategoryResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &CategoryResponse{Id: id, Name: req.Name, ParentId: req.ParentId}, nil
+}
+
+func (s *StorageStub) Delete(ctx context.Context, id int64) error {
+	return s.err
+}
+
+func (s *StorageStub) Summary(ctx context.Context) ([]SummaryResponse, error) {
+	return nil, s.err
+}
+
+func TestAddCategory(t *testing.T) {
+	t.Run("should pass the trimmed name to storage.Insert()", func(t *testing.T) {
+		storage := &StorageStub{}
+		service := NewService(storage, logrus.New())
+
+		resp, err := service.AddCategory(context.Background(), CategoryRequest{Name: " Travel "})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Travel", storage.req.Name)
+		assert.Equal(t, "Travel", resp.Name)
+	})
+
+	t.Run("should return error code 400 without calling storage.Insert() for an empty name", func(t *testing.T) {
+		storage := &StorageStub{}
+		service := NewService(storage, logrus.New())
+
+		_, err := service.AddCategory(context.Background(), CategoryRequest{Name: "  "})
+
+		assert.Equal(t, http.StatusBadRequest, err.(*common.Error).Code)
+		assert.False(t, storage.insertWasCalled)
+	})
+}
+
+func TestUpdateCategory(t *testing.T) {
+	t.Run("should return error code 400 when storage.Update() detects a cycle", func(t *testing.T) {
+		service := NewService(&StorageStub{err: ErrCycle}, logrus.New())
+
+		_, err := service.UpdateCategory(context.Background(), 1, CategoryRequest{Name: "Travel"})
+
+		assert.Equal(t, http.StatusBadRequest, err.(*common.Error).Code)
+	})
+}
+
+func TestDeleteCategory(t *testing.T) {
+	t.Run("should return error code 409 when storage.Delete() reports children", func(t *testing.T) {
+		service := NewService(&StorageStub{err: common.ErrConflict}, logrus.New())
+
+		err := service.DeleteCategory(context.Background(), 1)
+
+		assert.Equal(t, http.StatusConflict, err.(*common.Error).Code)
+	})
+}
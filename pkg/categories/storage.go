@@ -0,0 +1,17 @@
+package categories
+
+import (
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+)
+
+// NewStorage creates the Storage of the configured backend. Categories need
+// recursive queries and are only kept by postgres, the other backends return
+// common.ErrUnsupported.
+func NewStorage(ins *config.Instance) (Storage, error) {
+	cf := ins.Config.Get()
+	if cf.StorageBackend() != config.BackendPostgres {
+		return nil, common.ErrUnsupported
+	}
+	return New(ins.DB, cf.DbTimeout()), nil
+}
@@ -0,0 +1,33 @@
+package common
+
+import "context"
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIdKey
+)
+
+// WithActor returns a copy of ctx carrying the name of the authenticated
+// caller.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns the caller set by WithActor, empty when there is none.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// WithRequestId returns a copy of ctx carrying the id of the request.
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey, id)
+}
+
+// RequestId returns the id set by WithRequestId, empty when there is none.
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
@@ -1,24 +1,52 @@
 package common
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
 type DbConfig struct {
 	DriverName    string
 	Url           string
 	SqlInitialize string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// ConnectTimeout is the deadline for the database to become reachable at
+	// startup. Zero tries only once.
+	ConnectTimeout time.Duration
+	// Log receives a warning for every failed connection attempt, optional.
+	Log Log
 }
 
+const (
+	connectRetryWait    = 500 * time.Millisecond
+	connectRetryMaxWait = 5 * time.Second
+)
+
 func NewDb(config DbConfig) (*sql.DB, error) {
 	db, err := sql.Open(config.DriverName, config.Url)
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
 
-	err = prepareDB(config.SqlInitialize, db)
+	err = connect(db, config)
+	if err == nil {
+		err = prepareDB(config.SqlInitialize, db)
+	}
 	if err != nil {
 		db.Close()
 		return nil, err
@@ -27,6 +55,77 @@ func NewDb(config DbConfig) (*sql.DB, error) {
 	return db, nil
 }
 
+// connect waits for the database to accept connections, so the application
+// survives a database that starts a few seconds after it.
+func connect(db *sql.DB, config DbConfig) error {
+	if config.ConnectTimeout <= 0 {
+		return db.Ping()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+	defer cancel()
+
+	attempt := 0
+	return Retry(ctx, 0, connectRetryWait, connectRetryMaxWait, func() error {
+		attempt++
+		err := db.PingContext(ctx)
+		if err != nil && config.Log != nil {
+			config.Log.Warnf("Database not ready (attempt %d) : %s", attempt, err)
+		}
+		return err
+	}, nil)
+}
+
+// Retry calls fn until it succeeds, returns an error that retryable rejects,
+// has been called attempts times or ctx is done. The wait between calls
+// starts at wait and doubles up to maxWait. Zero attempts means no limit and
+// a nil retryable retries every error.
+func Retry(ctx context.Context, attempts int, wait time.Duration, maxWait time.Duration, fn func() error, retryable func(error) bool) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if (retryable != nil && !retryable(err)) || (attempts > 0 && attempt >= attempts) {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		if wait *= 2; wait > maxWait {
+			wait = maxWait
+		}
+	}
+}
+
+// IsTransient reports whether err is a connection failure that may succeed
+// when retried, as opposed to an error of the statement itself.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && len(pqErr.Code) == 5 {
+		// class 08 is connection exception, 57P0x the server shutting down or
+		// starting up.
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	}
+	return false
+}
+
 type DB interface {
 	Exec(query string, args ...any) (sql.Result, error)
 }
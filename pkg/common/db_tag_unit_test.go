@@ -3,8 +3,14 @@
 package common
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type mockDB struct {
@@ -36,3 +42,80 @@ func TestPrepareDB(t *testing.T) {
 		t.Error("should have been call db.Exec with query but it not.")
 	}
 }
+
+func TestRetry(t *testing.T) {
+	t.Run("should call fn until it succeeds", func(t *testing.T) {
+		calls := 0
+
+		err := Retry(context.Background(), 0, time.Millisecond, time.Millisecond, func() error {
+			calls++
+			if calls < 3 {
+				return errors.New("not ready")
+			}
+			return nil
+		}, nil)
+
+		if err != nil || calls != 3 {
+			t.Errorf("err=%v calls=%d; want nil and 3 calls", err, calls)
+		}
+	})
+
+	t.Run("should stop after attempts", func(t *testing.T) {
+		calls := 0
+
+		err := Retry(context.Background(), 2, time.Millisecond, time.Millisecond, func() error {
+			calls++
+			return errors.New("not ready")
+		}, nil)
+
+		if err == nil || calls != 2 {
+			t.Errorf("err=%v calls=%d; want error and 2 calls", err, calls)
+		}
+	})
+
+	t.Run("should stop on error not retryable", func(t *testing.T) {
+		calls := 0
+
+		Retry(context.Background(), 5, time.Millisecond, time.Millisecond, func() error {
+			calls++
+			return sql.ErrNoRows
+		}, IsTransient)
+
+		if calls != 1 {
+			t.Errorf("calls=%d; want 1", calls)
+		}
+	})
+
+	t.Run("should stop when context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		err := Retry(ctx, 0, 5*time.Millisecond, 5*time.Millisecond, func() error {
+			return errors.New("not ready")
+		}, nil)
+
+		if err == nil || ctx.Err() == nil {
+			t.Errorf("err=%v; want error after deadline", err)
+		}
+	})
+}
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{driver.ErrBadConn, true},
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "57P03"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{&pq.Error{Message: "no code"}, false},
+		{sql.ErrNoRows, false},
+		{context.DeadlineExceeded, false},
+	}
+	for _, c := range cases {
+		if got := IsTransient(c.err); got != c.want {
+			t.Errorf("IsTransient(%v)=%v; want %v", c.err, got, c.want)
+		}
+	}
+}
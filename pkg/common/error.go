@@ -1,10 +1,31 @@
 package common
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"strconv"
 	"strings"
 )
 
+// ErrNotFound is returned by storages when the requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned by storages when a write would break a uniqueness rule.
+var ErrConflict = errors.New("conflict")
+
+// ErrInvalid is returned by storages when a write refers to a record that does
+// not exist or would break a rule of the data model.
+var ErrInvalid = errors.New("invalid")
+
+// ErrUnsupported is returned when the configured storage backend does not
+// implement a feature.
+var ErrUnsupported = errors.New("not supported by the storage backend")
+
+// StatusClientClosedRequest is the non-standard status (nginx 499) used when
+// the client went away before the response was written.
+const StatusClientClosedRequest = 499
+
 type Error struct {
 	Code          int
 	Desc          string
@@ -17,3 +38,28 @@ func (e Error) Error() string {
 	}
 	return strings.Join([]string{strconv.Itoa(e.Code), e.Desc}, ":")
 }
+
+func (e Error) Unwrap() error {
+	return e.OriginalError
+}
+
+// StatusFromError maps an error returned by a lower layer to the http status
+// that should be reported to the client.
+func StatusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,36 +1,449 @@
 package config
 
-import "os"
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-func getenv(name string, require bool, defaultVAlue string) string {
-	v := os.Getenv(name)
-	if v == "" {
-		if require {
-			panic("missing required environment variable: " + name)
-		}
-		return defaultVAlue
-	}
-	return v
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "xxxxx"
+
+const (
+	BackendPostgres = "postgres"
+	BackendSqlite   = "sqlite"
+	BackendMemory   = "memory"
+)
+
+const (
+	AttachmentStoreLocal = "local"
+	AttachmentStoreS3    = "s3"
+)
+
+const (
+	OutboxSinkNone   = "none"
+	OutboxSinkStdout = "stdout"
+	OutboxSinkFile   = "file"
+	OutboxSinkHttp   = "http"
+	OutboxSinkNats   = "nats"
+)
+
+// dictionaryName is an unquoted postgres identifier, the dictionary is
+// spliced into DDL.
+var dictionaryName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// ValidationError lists every invalid setting found while loading.
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Errors, "; ")
 }
 
 type Config struct {
-	port    string
-	dbUrl   string
-	authKey string
+	port      string
+	adminPort string
+	grpcPort  string
+	backend   string
+	dbUrl     string
+	authKey   string
+	authKeys  map[string]string
+	dbTimeout time.Duration
+	logLevel  logrus.Level
+
+	dbMaxOpenConns    int
+	dbMaxIdleConns    int
+	dbConnMaxLifetime time.Duration
+	dbConnectTimeout  time.Duration
+	dbReadRetries     int
+
+	features map[string]bool
+
+	rateLimit      float64
+	rateBurst      int
+	trustedProxies []*net.IPNet
+
+	healthTimeout      time.Duration
+	shutdownDrainDelay time.Duration
+
+	traceExporter string
+	traceEndpoint string
+	traceInsecure bool
+
+	attachmentStore        string
+	attachmentDir          string
+	attachmentMaxSize      int64
+	attachmentAllowedTypes []string
+	s3Endpoint             string
+	s3Bucket               string
+	s3Region               string
+	s3AccessKey            string
+	s3SecretKey            string
+	s3UseSSL               bool
+
+	searchDictionary string
+
+	outboxSink           string
+	outboxFile           string
+	outboxUrl            string
+	outboxNatsUrl        string
+	outboxSubject        string
+	outboxInterval       time.Duration
+	outboxBatchSize      int
+	outboxPublishTimeout time.Duration
+	outboxMaxAttempts    int
+
+	webhookInterval     time.Duration
+	webhookTimeout      time.Duration
+	webhookBackoff      time.Duration
+	webhookMaxAttempts  int
+	webhookAllowPrivate bool
+
+	streamInterval  time.Duration
+	streamHeartbeat time.Duration
+	streamBuffer    int
+
+	cacheEnabled bool
+	cacheSize    int
+	cacheTtl     time.Duration
+
+	graphqlMaxDepth      int
+	graphqlMaxComplexity int
+	graphqlPersistedSize int
+
+	validateRequests  bool
+	validateResponses bool
+
+	v1Deprecated time.Time
+	v1Sunset     time.Time
+
+	file        string
+	printConfig bool
+	values      map[string]value
+}
+
+// validator collects the errors of every setting instead of stopping at the
+// first one.
+type validator struct {
+	values map[string]value
+	errors []string
+}
+
+func (v *validator) fail(key string, format string, args ...any) {
+	raw := v.values[key]
+	v.errors = append(v.errors, fmt.Sprintf("%s (%s): %s", key, raw.source, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) required(key string) string {
+	raw := v.values[key].raw
+	if raw == "" {
+		v.fail(key, "is required")
+	}
+	return raw
+}
+
+func (v *validator) port(key string) string {
+	raw := v.required(key)
+	if raw == "" {
+		return raw
+	}
+	if p, err := strconv.Atoi(raw); err != nil || p < 1 || p > 65535 {
+		v.fail(key, "must be a port number, got %q", raw)
+	}
+	return raw
+}
+
+func (v *validator) duration(key string) time.Duration {
+	raw := v.values[key].raw
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		v.fail(key, "must be a positive duration, got %q", raw)
+	}
+	return d
 }
 
-func NewConfig() Config {
-	return Config{
-		port:    getenv("PORT", true, ""),
-		dbUrl:   getenv("DATABASE_URL", true, ""),
-		authKey: getenv("AUTH_KEY", false, "November 10, 2009"),
+func (v *validator) date(key string) time.Time {
+	raw := v.values[key].raw
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		v.fail(key, "must be a date as 2006-01-02, got %q", raw)
 	}
+	return t
+}
+
+func (v *validator) float(key string) float64 {
+	raw := v.values[key].raw
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f < 0 {
+		v.fail(key, "must be a positive number, got %q", raw)
+	}
+	return f
+}
+
+func (v *validator) int(key string) int {
+	raw := v.values[key].raw
+	i, err := strconv.Atoi(raw)
+	if err != nil || i < 0 {
+		v.fail(key, "must be a positive integer, got %q", raw)
+	}
+	return i
+}
+
+func (v *validator) bool(key string) bool {
+	raw := v.values[key].raw
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		v.fail(key, "must be a boolean, got %q", raw)
+	}
+	return b
+}
+
+func (v *validator) oneOf(key string, allowed ...string) string {
+	raw := v.values[key].raw
+	for _, a := range allowed {
+		if raw == a {
+			return raw
+		}
+	}
+	v.fail(key, "must be one of %s, got %q", strings.Join(allowed, ", "), raw)
+	return raw
+}
+
+func build(values map[string]value) (Config, error) {
+	v := &validator{values: values}
+	cf := Config{values: values}
+
+	cf.port = v.port("server.port")
+	cf.adminPort = v.port("server.admin_port")
+	if cf.port != "" && cf.port == cf.adminPort {
+		v.fail("server.admin_port", "must differ from server.port")
+	}
+	cf.grpcPort = v.port("server.grpc_port")
+	if cf.grpcPort != "" && (cf.grpcPort == cf.port || cf.grpcPort == cf.adminPort) {
+		v.fail("server.grpc_port", "must differ from server.port and server.admin_port")
+	}
+	cf.healthTimeout = v.duration("server.health_timeout")
+	cf.shutdownDrainDelay = v.duration("server.shutdown_drain_delay")
+
+	cf.backend = v.oneOf("storage.backend", BackendPostgres, BackendSqlite, BackendMemory)
+	if cf.backend != BackendMemory {
+		cf.dbUrl = v.required("database.url")
+	}
+	if cf.dbUrl != "" && cf.backend == BackendPostgres {
+		if _, err := url.Parse(cf.dbUrl); err != nil {
+			v.fail("database.url", "must be a url")
+		}
+	}
+	cf.dbTimeout = v.duration("database.timeout")
+	cf.dbMaxOpenConns = v.int("database.max_open_conns")
+	cf.dbMaxIdleConns = v.int("database.max_idle_conns")
+	if cf.dbMaxOpenConns > 0 && cf.dbMaxIdleConns > cf.dbMaxOpenConns {
+		v.fail("database.max_idle_conns", "must not exceed database.max_open_conns")
+	}
+	cf.dbConnMaxLifetime = v.duration("database.conn_max_lifetime")
+	cf.dbConnectTimeout = v.duration("database.connect_timeout")
+	cf.dbReadRetries = v.int("database.read_retries")
+
+	cf.authKey = v.required("auth.key")
+	cf.authKeys = map[string]string{}
+	for _, pair := range strings.Split(values["auth.keys"].raw, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(pair, ":")
+		if !ok || name == "" || key == "" {
+			v.fail("auth.keys", "must be name:key pairs")
+			continue
+		}
+		cf.authKeys[name] = key
+	}
+
+	cf.rateLimit = v.float("rate_limit.requests_per_second")
+	cf.rateBurst = v.int("rate_limit.burst")
+	if cf.rateLimit > 0 && cf.rateBurst < 1 {
+		v.fail("rate_limit.burst", "must be positive when rate_limit.requests_per_second is set")
+	}
+	for _, cidr := range strings.Split(values["rate_limit.trusted_proxies"].raw, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			v.fail("rate_limit.trusted_proxies", "must be CIDRs, got %q", cidr)
+			continue
+		}
+		cf.trustedProxies = append(cf.trustedProxies, ipNet)
+	}
+
+	level, err := logrus.ParseLevel(values["log.level"].raw)
+	if err != nil {
+		v.fail("log.level", "%s", err)
+	}
+	cf.logLevel = level
+
+	cf.traceExporter = v.oneOf("tracing.exporter", "none", "stdout", "otlp")
+	cf.traceEndpoint = values["tracing.endpoint"].raw
+	cf.traceInsecure = v.bool("tracing.insecure")
+
+	cf.attachmentStore = v.oneOf("attachments.store", AttachmentStoreLocal, AttachmentStoreS3)
+	cf.attachmentDir = values["attachments.dir"].raw
+	if cf.attachmentStore == AttachmentStoreLocal {
+		cf.attachmentDir = v.required("attachments.dir")
+	}
+	cf.attachmentMaxSize = int64(v.int("attachments.max_size"))
+	for _, t := range strings.Split(values["attachments.allowed_types"].raw, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			cf.attachmentAllowedTypes = append(cf.attachmentAllowedTypes, t)
+		}
+	}
+	cf.s3Endpoint = values["attachments.s3.endpoint"].raw
+	cf.s3Bucket = values["attachments.s3.bucket"].raw
+	if cf.attachmentStore == AttachmentStoreS3 {
+		cf.s3Endpoint = v.required("attachments.s3.endpoint")
+		cf.s3Bucket = v.required("attachments.s3.bucket")
+	}
+	cf.s3Region = values["attachments.s3.region"].raw
+	cf.s3AccessKey = values["attachments.s3.access_key"].raw
+	cf.s3SecretKey = values["attachments.s3.secret_key"].raw
+	cf.s3UseSSL = v.bool("attachments.s3.use_ssl")
+
+	cf.searchDictionary = values["search.dictionary"].raw
+	if !dictionaryName.MatchString(cf.searchDictionary) {
+		v.fail("search.dictionary", "must be the name of a text search dictionary")
+	}
+
+	cf.outboxSink = v.oneOf("outbox.sink", OutboxSinkNone, OutboxSinkStdout, OutboxSinkFile, OutboxSinkHttp, OutboxSinkNats)
+	cf.outboxFile = values["outbox.file"].raw
+	if cf.outboxSink == OutboxSinkFile {
+		cf.outboxFile = v.required("outbox.file")
+	}
+	cf.outboxUrl = values["outbox.url"].raw
+	if cf.outboxSink == OutboxSinkHttp {
+		cf.outboxUrl = v.required("outbox.url")
+	}
+	if cf.outboxUrl != "" {
+		u, err := url.Parse(cf.outboxUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.fail("outbox.url", "must be an http or https url")
+		}
+	}
+	cf.outboxNatsUrl = values["outbox.nats_url"].raw
+	cf.outboxSubject = values["outbox.subject"].raw
+	if cf.outboxSink == OutboxSinkNats {
+		cf.outboxNatsUrl = v.required("outbox.nats_url")
+		cf.outboxSubject = v.required("outbox.subject")
+	}
+	cf.outboxInterval = v.duration("outbox.interval")
+	if cf.outboxInterval == 0 {
+		v.fail("outbox.interval", "must not be zero")
+	}
+	cf.outboxBatchSize = v.int("outbox.batch_size")
+	if cf.outboxBatchSize == 0 {
+		v.fail("outbox.batch_size", "must not be zero")
+	}
+	cf.outboxPublishTimeout = v.duration("outbox.publish_timeout")
+	cf.outboxMaxAttempts = v.int("outbox.max_attempts")
+	if cf.outboxMaxAttempts == 0 {
+		v.fail("outbox.max_attempts", "must not be zero")
+	}
+
+	cf.webhookInterval = v.duration("webhooks.interval")
+	if cf.webhookInterval == 0 {
+		v.fail("webhooks.interval", "must not be zero")
+	}
+	cf.webhookTimeout = v.duration("webhooks.timeout")
+	if cf.webhookTimeout == 0 {
+		v.fail("webhooks.timeout", "must not be zero")
+	}
+	cf.webhookBackoff = v.duration("webhooks.backoff")
+	cf.webhookMaxAttempts = v.int("webhooks.max_attempts")
+	if cf.webhookMaxAttempts == 0 {
+		v.fail("webhooks.max_attempts", "must not be zero")
+	}
+	cf.webhookAllowPrivate = v.bool("webhooks.allow_private")
+
+	cf.streamInterval = v.duration("stream.interval")
+	if cf.streamInterval == 0 {
+		v.fail("stream.interval", "must not be zero")
+	}
+	cf.streamHeartbeat = v.duration("stream.heartbeat")
+	if cf.streamHeartbeat == 0 {
+		v.fail("stream.heartbeat", "must not be zero")
+	}
+	cf.streamBuffer = v.int("stream.buffer")
+
+	cf.cacheEnabled = v.bool("cache.enabled")
+	cf.cacheSize = v.int("cache.size")
+	cf.cacheTtl = v.duration("cache.ttl")
+	if cf.cacheEnabled && cf.cacheSize == 0 {
+		v.fail("cache.size", "must not be zero when the cache is enabled")
+	}
+	if cf.cacheEnabled && cf.cacheTtl == 0 {
+		v.fail("cache.ttl", "must not be zero when the cache is enabled")
+	}
+
+	cf.graphqlMaxDepth = v.int("graphql.max_depth")
+	if cf.graphqlMaxDepth == 0 {
+		v.fail("graphql.max_depth", "must not be zero")
+	}
+	cf.graphqlMaxComplexity = v.int("graphql.max_complexity")
+	if cf.graphqlMaxComplexity == 0 {
+		v.fail("graphql.max_complexity", "must not be zero")
+	}
+	cf.graphqlPersistedSize = v.int("graphql.persisted_size")
+	if cf.graphqlPersistedSize == 0 {
+		v.fail("graphql.persisted_size", "must not be zero")
+	}
+
+	cf.validateRequests = v.bool("openapi.validate_requests")
+	cf.validateResponses = v.bool("openapi.validate_responses")
+
+	cf.v1Deprecated = v.date("api.v1_deprecated")
+	cf.v1Sunset = v.date("api.v1_sunset")
+	if !cf.v1Deprecated.IsZero() && !cf.v1Sunset.IsZero() && !cf.v1Deprecated.Before(cf.v1Sunset) {
+		v.fail("api.v1_deprecated", "must be before api.v1_sunset")
+	}
+
+	cf.features = map[string]bool{}
+	for _, f := range strings.Split(values["features"].raw, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			cf.features[f] = true
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return cf, &ValidationError{Errors: v.errors}
+	}
+	return cf, nil
 }
 
 func (c Config) Port() string {
 	return c.port
 }
 
+func (c Config) AdminPort() string {
+	return c.adminPort
+}
+
+// GrpcPort is the port of the gRPC api.
+func (c Config) GrpcPort() string {
+	return c.grpcPort
+}
+
+// StorageBackend is where expenses are stored, BackendPostgres, BackendSqlite
+// or BackendMemory. It is also the driver name of the database.
+func (c Config) StorageBackend() string {
+	return c.backend
+}
+
 func (c Config) DbUrl() string {
 	return c.dbUrl
 }
@@ -38,3 +451,331 @@ func (c Config) DbUrl() string {
 func (c Config) AuthKey() string {
 	return c.authKey
 }
+
+// Authenticate returns the name of the auth key equal to key. The key of
+// auth.key is named "default".
+func (c Config) Authenticate(key string) (string, bool) {
+	name, ok := "", false
+	if subtle.ConstantTimeCompare([]byte(key), []byte(c.authKey)) == 1 {
+		name, ok = "default", true
+	}
+	for n, k := range c.authKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			name, ok = n, true
+		}
+	}
+	return name, ok
+}
+
+// RateLimit is the requests per second allowed per client, 0 when disabled,
+// and the burst size.
+func (c Config) RateLimit() (float64, int) {
+	return c.rateLimit, c.rateBurst
+}
+
+// TrustedProxies are the networks of the proxies trusted to tell the client ip
+// in X-Forwarded-For, none when the client ip is the peer address.
+func (c Config) TrustedProxies() []*net.IPNet {
+	return c.trustedProxies
+}
+
+func (c Config) DbTimeout() time.Duration {
+	return c.dbTimeout
+}
+
+func (c Config) DbMaxOpenConns() int {
+	return c.dbMaxOpenConns
+}
+
+func (c Config) DbMaxIdleConns() int {
+	return c.dbMaxIdleConns
+}
+
+func (c Config) DbConnMaxLifetime() time.Duration {
+	return c.dbConnMaxLifetime
+}
+
+// DbConnectTimeout is how long startup waits for the database to be reachable.
+func (c Config) DbConnectTimeout() time.Duration {
+	return c.dbConnectTimeout
+}
+
+// DbReadRetries is how many times a read is retried on a transient error.
+func (c Config) DbReadRetries() int {
+	return c.dbReadRetries
+}
+
+func (c Config) LogLevel() logrus.Level {
+	return c.logLevel
+}
+
+// Feature reports whether the feature toggle name is enabled.
+func (c Config) Feature(name string) bool {
+	return c.features[name]
+}
+
+func (c Config) HealthTimeout() time.Duration {
+	return c.healthTimeout
+}
+
+// ShutdownDrainDelay is how long readiness fails before the server stops.
+func (c Config) ShutdownDrainDelay() time.Duration {
+	return c.shutdownDrainDelay
+}
+
+// TraceExporter is one of none, stdout or otlp.
+func (c Config) TraceExporter() string {
+	return c.traceExporter
+}
+
+func (c Config) TraceEndpoint() string {
+	return c.traceEndpoint
+}
+
+func (c Config) TraceInsecure() bool {
+	return c.traceInsecure
+}
+
+// AttachmentStore is one of local or s3.
+func (c Config) AttachmentStore() string {
+	return c.attachmentStore
+}
+
+func (c Config) AttachmentDir() string {
+	return c.attachmentDir
+}
+
+// AttachmentMaxSize is the maximum size of an attachment in bytes.
+func (c Config) AttachmentMaxSize() int64 {
+	return c.attachmentMaxSize
+}
+
+func (c Config) AttachmentAllowedTypes() []string {
+	return append([]string{}, c.attachmentAllowedTypes...)
+}
+
+func (c Config) S3Endpoint() string {
+	return c.s3Endpoint
+}
+
+func (c Config) S3Bucket() string {
+	return c.s3Bucket
+}
+
+func (c Config) S3Region() string {
+	return c.s3Region
+}
+
+func (c Config) S3AccessKey() string {
+	return c.s3AccessKey
+}
+
+func (c Config) S3SecretKey() string {
+	return c.s3SecretKey
+}
+
+func (c Config) S3UseSSL() bool {
+	return c.s3UseSSL
+}
+
+// SearchDictionary is the text search dictionary of the expense search.
+func (c Config) SearchDictionary() string {
+	return c.searchDictionary
+}
+
+// OutboxSink is where the relay publishes the domain events, one of none,
+// stdout, file, http or nats. none leaves the events in the outbox.
+func (c Config) OutboxSink() string {
+	return c.outboxSink
+}
+
+func (c Config) OutboxFile() string {
+	return c.outboxFile
+}
+
+// OutboxUrl is the url the http sink posts the events to.
+func (c Config) OutboxUrl() string {
+	return c.outboxUrl
+}
+
+func (c Config) OutboxNatsUrl() string {
+	return c.outboxNatsUrl
+}
+
+// OutboxSubject is the NATS subject of the events, the event type is appended
+// to it.
+func (c Config) OutboxSubject() string {
+	return c.outboxSubject
+}
+
+// OutboxInterval is how long the relay waits when the outbox is drained.
+func (c Config) OutboxInterval() time.Duration {
+	return c.outboxInterval
+}
+
+// OutboxBatchSize is how many events the relay publishes per transaction.
+func (c Config) OutboxBatchSize() int {
+	return c.outboxBatchSize
+}
+
+// OutboxPublishTimeout bounds the publishing of a single event.
+func (c Config) OutboxPublishTimeout() time.Duration {
+	return c.outboxPublishTimeout
+}
+
+// OutboxMaxAttempts is the number of failed publications after which an event
+// is parked.
+func (c Config) OutboxMaxAttempts() int {
+	return c.outboxMaxAttempts
+}
+
+// WebhookInterval is how often the due webhook deliveries are sent.
+func (c Config) WebhookInterval() time.Duration {
+	return c.webhookInterval
+}
+
+// WebhookTimeout bounds a single webhook delivery.
+func (c Config) WebhookTimeout() time.Duration {
+	return c.webhookTimeout
+}
+
+// WebhookBackoff is the wait before the first retry of a failed delivery, it
+// doubles with every attempt.
+func (c Config) WebhookBackoff() time.Duration {
+	return c.webhookBackoff
+}
+
+// WebhookMaxAttempts is the number of attempts after which a delivery is dead.
+func (c Config) WebhookMaxAttempts() int {
+	return c.webhookMaxAttempts
+}
+
+// WebhookAllowPrivate lets webhooks target loopback and private addresses,
+// for local development only.
+func (c Config) WebhookAllowPrivate() bool {
+	return c.webhookAllowPrivate
+}
+
+// StreamInterval is how often the expense stream polls the outbox for new
+// events.
+func (c Config) StreamInterval() time.Duration {
+	return c.streamInterval
+}
+
+// StreamHeartbeat is the idle time after which the expense stream sends a
+// comment, keeping proxies from closing the connection.
+func (c Config) StreamHeartbeat() time.Duration {
+	return c.streamHeartbeat
+}
+
+// StreamBuffer is the number of recent events kept in memory, an older
+// Last-Event-ID is resumed from the database.
+func (c Config) StreamBuffer() int {
+	return c.streamBuffer
+}
+
+// CacheEnabled tells whether the expense reads by id are cached. Only the
+// postgres backend caches them, its notifications keep the caches of the
+// instances up to date.
+func (c Config) CacheEnabled() bool {
+	return c.cacheEnabled
+}
+
+// CacheSize is the number of expenses kept in the cache.
+func (c Config) CacheSize() int {
+	return c.cacheSize
+}
+
+// CacheTtl bounds how long an expense stays in the cache, in case a
+// notification is lost.
+func (c Config) CacheTtl() time.Duration {
+	return c.cacheTtl
+}
+
+// GraphqlMaxDepth is the deepest selection a graphql query may have.
+func (c Config) GraphqlMaxDepth() int {
+	return c.graphqlMaxDepth
+}
+
+// GraphqlMaxComplexity bounds the estimated cost of a graphql query, a field
+// costs one and the fields under a list count for every item.
+func (c Config) GraphqlMaxComplexity() int {
+	return c.graphqlMaxComplexity
+}
+
+// GraphqlPersistedSize is the number of persisted graphql queries kept, the
+// least recently used are evicted.
+func (c Config) GraphqlPersistedSize() int {
+	return c.graphqlPersistedSize
+}
+
+// ValidateRequests tells whether the requests are checked against the OpenAPI
+// document before the handlers run.
+func (c Config) ValidateRequests() bool {
+	return c.validateRequests
+}
+
+// ValidateResponses tells whether the responses are checked against the
+// OpenAPI document. They are buffered for it, which suits tests and debugging
+// rather than production.
+func (c Config) ValidateResponses() bool {
+	return c.validateResponses
+}
+
+// V1Deprecated is the day the /v2 expenses replaced the /v1 ones, midnight
+// UTC.
+func (c Config) V1Deprecated() time.Time {
+	return c.v1Deprecated
+}
+
+// V1Sunset is the day the /v1 expenses stop being served, midnight UTC.
+func (c Config) V1Sunset() time.Time {
+	return c.v1Sunset
+}
+
+// File is the path of the config file, empty when none was given.
+func (c Config) File() string {
+	return c.file
+}
+
+// PrintConfig reports whether -print-config was given.
+func (c Config) PrintConfig() bool {
+	return c.printConfig
+}
+
+// Dump renders the effective configuration, one key per line with the layer
+// it came from. Secrets are redacted and only the password of urls is hidden.
+func (c Config) Dump() string {
+	secrets := map[string]bool{}
+	for _, s := range settings {
+		secrets[s.key] = s.secret
+	}
+
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b := strings.Builder{}
+	for _, key := range keys {
+		v := c.values[key]
+		raw := v.raw
+		if secrets[key] && raw != "" {
+			raw = redact(raw)
+		}
+		fmt.Fprintf(&b, "%s=%s (%s)\n", key, raw, v.source)
+	}
+	return b.String()
+}
+
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.User == nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
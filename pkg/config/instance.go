@@ -9,5 +9,5 @@ import (
 type Instance struct {
 	DB     *sql.DB
 	Log    *logrus.Logger
-	Config *Config
+	Config *Store
 }
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// setting describes one configuration key and where it can be set from. The
+// key is the dotted path in the config file.
+type setting struct {
+	key    string
+	env    string
+	flag   string
+	def    string
+	usage  string
+	secret bool
+	// reloadable settings are applied by Store.Reload, the others need a
+	// restart.
+	reloadable bool
+}
+
+var settings = []setting{
+	{key: "server.port", env: "PORT", flag: "port", usage: "api port"},
+	{key: "server.admin_port", env: "ADMIN_PORT", flag: "admin-port", def: "2566", usage: "metrics port"},
+	{key: "server.grpc_port", env: "GRPC_PORT", flag: "grpc-port", def: "2567", usage: "gRPC api port"},
+	{key: "server.health_timeout", env: "HEALTH_TIMEOUT", flag: "health-timeout", def: "2s", usage: "timeout of the readiness checks"},
+	{key: "server.shutdown_drain_delay", env: "SHUTDOWN_DRAIN_DELAY", flag: "shutdown-drain-delay", def: "5s", usage: "how long readiness fails before the server stops"},
+	{key: "storage.backend", env: "STORAGE_BACKEND", flag: "storage-backend", def: BackendPostgres, usage: "postgres, sqlite or memory"},
+	{key: "database.url", env: "DATABASE_URL", flag: "database-url", usage: "database connection url, the file name for sqlite", secret: true},
+	{key: "database.timeout", env: "DB_TIMEOUT", flag: "db-timeout", def: "5s", usage: "timeout of a single query"},
+	{key: "database.max_open_conns", env: "DB_MAX_OPEN_CONNS", flag: "db-max-open-conns", def: "25", usage: "maximum open connections, 0 is unlimited"},
+	{key: "database.max_idle_conns", env: "DB_MAX_IDLE_CONNS", flag: "db-max-idle-conns", def: "25", usage: "maximum idle connections"},
+	{key: "database.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", flag: "db-conn-max-lifetime", def: "30m", usage: "maximum lifetime of a connection, 0 is unlimited"},
+	{key: "database.connect_timeout", env: "DB_CONNECT_TIMEOUT", flag: "db-connect-timeout", def: "30s", usage: "how long startup waits for the database"},
+	{key: "database.read_retries", env: "DB_READ_RETRIES", flag: "db-read-retries", def: "2", usage: "retries of a read on a transient connection error"},
+	{key: "auth.key", env: "AUTH_KEY", flag: "auth-key", def: "November 10, 2009", usage: "value of the Authorization header", secret: true, reloadable: true},
+	{key: "auth.keys", env: "AUTH_KEYS", flag: "auth-keys", usage: "comma separated name:key pairs accepted besides auth.key, for rotation", secret: true, reloadable: true},
+	{key: "log.level", env: "LOG_LEVEL", flag: "log-level", def: "info", usage: "log level", reloadable: true},
+	{key: "rate_limit.requests_per_second", env: "RATE_LIMIT_RPS", flag: "rate-limit-rps", def: "0", usage: "requests per second allowed per client ip, 0 disables the limit", reloadable: true},
+	{key: "rate_limit.trusted_proxies", env: "RATE_LIMIT_TRUSTED_PROXIES", flag: "rate-limit-trusted-proxies", usage: "comma separated CIDRs of the proxies whose X-Forwarded-For names the client ip, the peer address is the client ip without them"},
+	{key: "rate_limit.burst", env: "RATE_LIMIT_BURST", flag: "rate-limit-burst", def: "20", usage: "requests a client ip may send at once", reloadable: true},
+	{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", flag: "trace-exporter", def: "none", usage: "none, stdout or otlp"},
+	{key: "tracing.endpoint", env: "OTEL_EXPORTER_OTLP_ENDPOINT", flag: "trace-endpoint", def: "localhost:4318", usage: "host:port of the OTLP/HTTP collector"},
+	{key: "tracing.insecure", env: "OTEL_EXPORTER_OTLP_INSECURE", flag: "trace-insecure", def: "true", usage: "disable TLS towards the collector"},
+	{key: "attachments.store", env: "ATTACHMENTS_STORE", flag: "attachments-store", def: AttachmentStoreLocal, usage: "local or s3, where receipt files are kept"},
+	{key: "attachments.dir", env: "ATTACHMENTS_DIR", flag: "attachments-dir", def: "attachments", usage: "directory of the local attachment store"},
+	{key: "attachments.max_size", env: "ATTACHMENTS_MAX_SIZE", flag: "attachments-max-size", def: "10485760", usage: "maximum size of an attachment in bytes"},
+	{key: "attachments.allowed_types", env: "ATTACHMENTS_ALLOWED_TYPES", flag: "attachments-allowed-types", def: "image/jpeg,image/png,image/gif,image/webp,application/pdf", usage: "comma separated MIME types accepted as attachments"},
+	{key: "attachments.s3.endpoint", env: "S3_ENDPOINT", flag: "s3-endpoint", usage: "host:port of the S3-compatible store"},
+	{key: "attachments.s3.bucket", env: "S3_BUCKET", flag: "s3-bucket", usage: "bucket of the attachments"},
+	{key: "attachments.s3.region", env: "S3_REGION", flag: "s3-region", def: "us-east-1", usage: "region of the bucket"},
+	{key: "attachments.s3.access_key", env: "S3_ACCESS_KEY", flag: "s3-access-key", usage: "access key of the S3-compatible store", secret: true},
+	{key: "attachments.s3.secret_key", env: "S3_SECRET_KEY", flag: "s3-secret-key", usage: "secret key of the S3-compatible store", secret: true},
+	{key: "attachments.s3.use_ssl", env: "S3_USE_SSL", flag: "s3-use-ssl", def: "true", usage: "use https towards the S3-compatible store"},
+	{key: "search.dictionary", env: "SEARCH_DICTIONARY", flag: "search-dictionary", def: "simple", usage: "postgres text search dictionary of the expense search, simple keeps Thai and English words as written"},
+	{key: "outbox.sink", env: "OUTBOX_SINK", flag: "outbox-sink", def: OutboxSinkNone, usage: "none, stdout, file, http or nats, where the expense events are published"},
+	{key: "outbox.file", env: "OUTBOX_FILE", flag: "outbox-file", def: "events.jsonl", usage: "file the file sink appends the events to"},
+	{key: "outbox.url", env: "OUTBOX_URL", flag: "outbox-url", usage: "url the http sink posts the events to", secret: true},
+	{key: "outbox.nats_url", env: "OUTBOX_NATS_URL", flag: "outbox-nats-url", def: "nats://127.0.0.1:4222", usage: "url of the NATS server of the nats sink", secret: true},
+	{key: "outbox.subject", env: "OUTBOX_SUBJECT", flag: "outbox-subject", def: "expenses.events", usage: "NATS subject prefix of the events"},
+	{key: "outbox.interval", env: "OUTBOX_INTERVAL", flag: "outbox-interval", def: "1s", usage: "how often the relay polls the outbox"},
+	{key: "outbox.batch_size", env: "OUTBOX_BATCH_SIZE", flag: "outbox-batch-size", def: "100", usage: "events published per relay transaction"},
+	{key: "outbox.publish_timeout", env: "OUTBOX_PUBLISH_TIMEOUT", flag: "outbox-publish-timeout", def: "10s", usage: "timeout of publishing one event"},
+	{key: "outbox.max_attempts", env: "OUTBOX_MAX_ATTEMPTS", flag: "outbox-max-attempts", def: "10", usage: "failed publications after which an event is parked"},
+	{key: "webhooks.interval", env: "WEBHOOKS_INTERVAL", flag: "webhooks-interval", def: "1s", usage: "how often the due webhook deliveries are sent"},
+	{key: "webhooks.timeout", env: "WEBHOOKS_TIMEOUT", flag: "webhooks-timeout", def: "10s", usage: "timeout of a webhook delivery"},
+	{key: "webhooks.backoff", env: "WEBHOOKS_BACKOFF", flag: "webhooks-backoff", def: "30s", usage: "wait before the first retry of a failed delivery, doubled by every attempt"},
+	{key: "webhooks.max_attempts", env: "WEBHOOKS_MAX_ATTEMPTS", flag: "webhooks-max-attempts", def: "8", usage: "attempts after which a delivery is dead"},
+	{key: "webhooks.allow_private", env: "WEBHOOKS_ALLOW_PRIVATE", flag: "webhooks-allow-private", def: "false", usage: "allow webhooks to loopback and private addresses, for local development only"},
+	{key: "stream.interval", env: "STREAM_INTERVAL", flag: "stream-interval", def: "1s", usage: "how often the expense stream polls the outbox"},
+	{key: "stream.heartbeat", env: "STREAM_HEARTBEAT", flag: "stream-heartbeat", def: "15s", usage: "idle time after which the expense stream sends a heartbeat"},
+	{key: "stream.buffer", env: "STREAM_BUFFER", flag: "stream-buffer", def: "1000", usage: "recent events kept in memory to resume the expense streams"},
+	{key: "cache.enabled", env: "CACHE_ENABLED", flag: "cache-enabled", def: "true", usage: "cache the expense reads by id, invalidated by postgres notifications"},
+	{key: "cache.size", env: "CACHE_SIZE", flag: "cache-size", def: "10000", usage: "expenses kept in the cache, the least recently used are evicted"},
+	{key: "cache.ttl", env: "CACHE_TTL", flag: "cache-ttl", def: "5m", usage: "time after which a cached expense is read again"},
+	{key: "graphql.max_depth", env: "GRAPHQL_MAX_DEPTH", flag: "graphql-max-depth", def: "8", usage: "deepest selection a graphql query may have"},
+	{key: "graphql.max_complexity", env: "GRAPHQL_MAX_COMPLEXITY", flag: "graphql-max-complexity", def: "1000", usage: "highest estimated cost of a graphql query"},
+	{key: "graphql.persisted_size", env: "GRAPHQL_PERSISTED_SIZE", flag: "graphql-persisted-size", def: "1000", usage: "persisted graphql queries kept in memory"},
+	{key: "openapi.validate_requests", env: "OPENAPI_VALIDATE_REQUESTS", flag: "openapi-validate-requests", def: "false", usage: "reject the requests which do not match the OpenAPI document"},
+	{key: "openapi.validate_responses", env: "OPENAPI_VALIDATE_RESPONSES", flag: "openapi-validate-responses", def: "false", usage: "answer 500 to the responses which do not match the OpenAPI document, for tests and debugging"},
+	{key: "api.v1_deprecated", env: "API_V1_DEPRECATED", flag: "api-v1-deprecated", def: "2026-10-19", usage: "date the /v1 expenses were deprecated at, told in their Deprecation header"},
+	{key: "api.v1_sunset", env: "API_V1_SUNSET", flag: "api-v1-sunset", def: "2027-04-30", usage: "date the /v1 expenses are removed after, told in their Sunset header"},
+	{key: "features", env: "FEATURES", flag: "features", usage: "comma separated list of enabled feature toggles", reloadable: true},
+}
+
+type value struct {
+	raw    string
+	source string
+}
+
+// Load builds the configuration from, in increasing precedence, the defaults,
+// the YAML file given by -config or CONFIG_FILE, the environment variables and
+// the command-line flags. Every invalid value is reported in a single
+// *ValidationError.
+func Load(args []string) (Config, error) {
+	cf, _, err := load(args)
+	return cf, err
+}
+
+func load(args []string) (Config, map[string]value, error) {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML config file")
+	printConfig := fs.Bool("print-config", false, "print the effective config and exit")
+	flags := map[string]*string{}
+	for _, s := range settings {
+		flags[s.flag] = fs.String(s.flag, s.def, s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	values, err := resolve(*file, os.Getenv, func(visit func(name, raw string)) {
+		fs.Visit(func(f *flag.Flag) {
+			if p, ok := flags[f.Name]; ok {
+				visit(f.Name, *p)
+			}
+		})
+	})
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	cf, err := build(values)
+	cf.file = *file
+	cf.printConfig = *printConfig
+	return cf, values, err
+}
+
+// resolve merges the layers into one value per setting.
+func resolve(file string, getenv func(string) string, visitFlags func(func(name, raw string))) (map[string]value, error) {
+	values := map[string]value{}
+	for _, s := range settings {
+		values[s.key] = value{raw: s.def, source: sourceDefault}
+	}
+
+	if file != "" {
+		fileValues, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for key, raw := range fileValues {
+			values[key] = value{raw: raw, source: sourceFile}
+		}
+	}
+
+	for _, s := range settings {
+		if raw := getenv(s.env); raw != "" {
+			values[s.key] = value{raw: raw, source: sourceEnv}
+		}
+	}
+
+	byFlag := map[string]string{}
+	for _, s := range settings {
+		byFlag[s.flag] = s.key
+	}
+	visitFlags(func(name, raw string) {
+		values[byFlag[name]] = value{raw: raw, source: sourceFlag}
+	})
+
+	return values, nil
+}
+
+func readFile(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	doc := map[string]any{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", file, err)
+	}
+
+	values := map[string]string{}
+	flatten("", doc, values)
+
+	known := map[string]bool{}
+	for _, s := range settings {
+		known[s.key] = true
+	}
+	unknown := []string{}
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, &ValidationError{Errors: []string{fmt.Sprintf("unknown keys in %s: %s", file, strings.Join(unknown, ", "))}}
+	}
+	return values, nil
+}
+
+// flatten turns nested maps into dotted keys and lists into comma separated
+// values, the same shape as the environment variables.
+func flatten(prefix string, node any, out map[string]string) {
+	switch n := node.(type) {
+	case map[string]any:
+		for k, v := range n {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, v, out)
+		}
+	case []any:
+		items := make([]string, 0, len(n))
+		for _, item := range n {
+			items = append(items, fmt.Sprint(item))
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+		out[prefix] = ""
+	default:
+		out[prefix] = fmt.Sprint(n)
+	}
+}
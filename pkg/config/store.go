@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds the running Config. Reload swaps it atomically, so readers
+// always see either the old or the new settings, never a mix.
+type Store struct {
+	args     []string
+	mu       sync.Mutex
+	current  atomic.Pointer[Config]
+	values   map[string]value
+	onReload []func(Config)
+}
+
+// NewStore loads the configuration from args like Load and keeps args to
+// load it again on Reload.
+func NewStore(args []string) (*Store, error) {
+	cf, values, err := load(args)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{args: args, values: values}
+	s.current.Store(&cf)
+	return s, nil
+}
+
+// Get returns the current configuration.
+func (s *Store) Get() Config {
+	return *s.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after each
+// successful reload.
+func (s *Store) OnReload(fn func(Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReload = append(s.onReload, fn)
+}
+
+// Reload loads the configuration again and applies its reloadable settings.
+// An invalid configuration is rejected and the current one is kept. Changes
+// to settings that need a restart are ignored and returned as warnings.
+func (s *Store) Reload() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, values, err := load(s.args)
+	if values == nil {
+		return nil, err
+	}
+
+	warnings := []string{}
+	for _, st := range settings {
+		if st.reloadable {
+			continue
+		}
+		if values[st.key].raw != s.values[st.key].raw {
+			warnings = append(warnings, fmt.Sprintf("%s changed, restart to apply", st.key))
+		}
+		values[st.key] = s.values[st.key]
+	}
+
+	cf, err := build(values)
+	if err != nil {
+		return warnings, err
+	}
+	old := s.current.Load()
+	cf.file = old.file
+	cf.printConfig = old.printConfig
+
+	s.values = values
+	s.current.Store(&cf)
+	for _, fn := range s.onReload {
+		fn(cf)
+	}
+	return warnings, nil
+}
+
+// Changed reports whether the config file was modified since the given
+// time. It is used to poll for changes, which also works for files mounted
+// from a configmap where inotify events are unreliable.
+func (s *Store) Changed(since time.Time) (bool, time.Time) {
+	file := s.Get().File()
+	if file == "" {
+		return false, since
+	}
+	info, err := os.Stat(file)
+	if err != nil || !info.ModTime().After(since) {
+		return false, since
+	}
+	return true, info.ModTime()
+}
@@ -0,0 +1,123 @@
+//go:build unit
+
+package config_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("should apply reloadable settings and notify listeners on Reload", func(t *testing.T) {
+		teardown := setup(ConfigEnv{
+			DbUrl: "postgres://localhost:5432/postgres",
+			Port:  "2565",
+		})
+		defer teardown()
+		store, err := config.NewStore(nil)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		var notified config.Config
+		store.OnReload(func(cf config.Config) { notified = cf })
+		os.Setenv("LOG_LEVEL", "debug")
+		os.Setenv("AUTH_KEYS", "ci:rotated-key")
+
+		warnings, err := store.Reload()
+
+		if err != nil || len(warnings) != 0 {
+			t.Fatalf("unexpected error %v warnings %v", err, warnings)
+		}
+		if store.Get().LogLevel() != logrus.DebugLevel {
+			t.Errorf("LogLevel=%v; want %v", store.Get().LogLevel(), logrus.DebugLevel)
+		}
+		if name, ok := store.Get().Authenticate("rotated-key"); !ok || name != "ci" {
+			t.Errorf("Authenticate(rotated-key)=%v,%v; want ci,true", name, ok)
+		}
+		if notified.LogLevel() != logrus.DebugLevel {
+			t.Errorf("listener not notified with new config")
+		}
+	})
+
+	t.Run("should keep settings that need a restart and warn on Reload", func(t *testing.T) {
+		teardown := setup(ConfigEnv{
+			DbUrl: "postgres://localhost:5432/postgres",
+			Port:  "2565",
+		})
+		defer teardown()
+		store, _ := config.NewStore(nil)
+		os.Setenv("PORT", "3000")
+
+		warnings, err := store.Reload()
+
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(warnings) != 1 {
+			t.Errorf("warnings=%v; want one for server.port", warnings)
+		}
+		if store.Get().Port() != "2565" {
+			t.Errorf("Port=%v; want 2565", store.Get().Port())
+		}
+	})
+
+	t.Run("should keep current config when reloaded config is invalid", func(t *testing.T) {
+		teardown := setup(ConfigEnv{
+			DbUrl: "postgres://localhost:5432/postgres",
+			Port:  "2565",
+		})
+		defer teardown()
+		store, _ := config.NewStore(nil)
+		os.Setenv("RATE_LIMIT_RPS", "fast")
+
+		_, err := store.Reload()
+
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if rps, _ := store.Get().RateLimit(); rps != 0 {
+			t.Errorf("RateLimit=%v; want 0", rps)
+		}
+	})
+
+	t.Run("should report change when config file is modified", func(t *testing.T) {
+		teardown := setup(ConfigEnv{
+			DbUrl: "postgres://localhost:5432/postgres",
+			Port:  "2565",
+		})
+		defer teardown()
+		file := writeFile(t, "log:\n  level: info\n")
+		store, _ := config.NewStore([]string{"-config", file})
+		since := time.Now().Add(-time.Hour)
+
+		changed, at := store.Changed(since)
+		unchanged, _ := store.Changed(at)
+
+		if !changed || unchanged {
+			t.Errorf("Changed=%v then %v; want true then false", changed, unchanged)
+		}
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Run("should accept auth.key as default and reject unknown keys", func(t *testing.T) {
+		teardown := setup(ConfigEnv{
+			DbUrl: "postgres://localhost:5432/postgres",
+			Port:  "2565",
+		})
+		defer teardown()
+
+		cf, _ := config.Load(nil)
+
+		if name, ok := cf.Authenticate("November 10, 2009"); !ok || name != "default" {
+			t.Errorf("Authenticate(default key)=%v,%v; want default,true", name, ok)
+		}
+		if _, ok := cf.Authenticate("guess"); ok {
+			t.Errorf("Authenticate(guess) accepted")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package expenses
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/cache"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+)
+
+// Channel is the postgres notification channel of the expenses written, the
+// payload is their id.
+const Channel = "expenses"
+
+// Cached is a Storage reading the expenses by id through an LRU cache. Its
+// writes drop the expense from the cache, the writes of the other instances
+// are told by the notifications of Channel.
+type Cached struct {
+	Storage
+	cache *cache.LRU[int64, ExpensesResponse]
+}
+
+func NewCached(s Storage, size int, ttl time.Duration) *Cached {
+	return &Cached{Storage: s, cache: cache.NewLRU[int64, ExpensesResponse](size, ttl)}
+}
+
+func (c *Cached) SearchById(ctx context.Context, id int64) (*ExpensesResponse, error) {
+	if exp, ok := c.cache.Get(id); ok {
+		metrics.CacheHit()
+		exp.Tags = copyTags(exp.Tags)
+		return &exp, nil
+	}
+	metrics.CacheMiss()
+
+	generation := c.cache.Generation()
+	resp, err := c.Storage.SearchById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	exp := *resp
+	exp.Tags = copyTags(resp.Tags)
+	c.cache.Add(id, exp, generation)
+	return resp, nil
+}
+
+func (c *Cached) Update(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+	defer c.Invalidate(strconv.FormatInt(id, 10))
+	return c.Storage.Update(ctx, id, req)
+}
+
+func (c *Cached) Delete(ctx context.Context, id int64) error {
+	defer c.Invalidate(strconv.FormatInt(id, 10))
+	return c.Storage.Delete(ctx, id)
+}
+
+func (c *Cached) Revert(ctx context.Context, id int64, version int64) (*ExpensesResponse, error) {
+	defer c.Invalidate(strconv.FormatInt(id, 10))
+	return c.Storage.Revert(ctx, id, version)
+}
+
+// Invalidate drops the expense of the id payload, every expense when payload
+// is not an id.
+func (c *Cached) Invalidate(payload string) {
+	metrics.CacheInvalidated()
+	id, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		c.cache.Purge()
+		return
+	}
+	c.cache.Remove(id)
+}
+
+// SetEnabled enables the cache, empty, or disables it.
+func (c *Cached) SetEnabled(enabled bool) {
+	c.cache.SetEnabled(enabled)
+}
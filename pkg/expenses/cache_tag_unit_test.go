@@ -0,0 +1,101 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// CountingStorage counts the reads of its Memory.
+type CountingStorage struct {
+	*Memory
+	reads int
+}
+
+func (s *CountingStorage) SearchById(ctx context.Context, id int64) (*ExpensesResponse, error) {
+	s.reads++
+	return s.Memory.SearchById(ctx, id)
+}
+
+func newCached(t *testing.T) (*Cached, *CountingStorage) {
+	storage := &CountingStorage{Memory: NewMemory()}
+	_, err := storage.Insert(context.Background(), ExpensesRequest{Title: "Coffee", Amount: 60, Tags: []string{"drink"}})
+	assert.NoError(t, err)
+	return NewCached(storage, 10, time.Minute), storage
+}
+
+func TestCached(t *testing.T) {
+	t.Run("should read an expense from the database once", func(t *testing.T) {
+		cached, storage := newCached(t)
+
+		first, err := cached.SearchById(context.Background(), 1)
+		assert.NoError(t, err)
+		first.Tags[0] = "changed by the caller"
+		second, err := cached.SearchById(context.Background(), 1)
+		assert.NoError(t, err)
+
+		assert.Equal(t, 1, storage.reads)
+		assert.Equal(t, []string{"drink"}, second.Tags)
+	})
+
+	t.Run("should read the expense again after its update", func(t *testing.T) {
+		cached, storage := newCached(t)
+		cached.SearchById(context.Background(), 1)
+
+		_, err := cached.Update(context.Background(), 1, ExpensesRequest{Title: "Tea", Amount: 40})
+		assert.NoError(t, err)
+		resp, err := cached.SearchById(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Tea", resp.Title)
+		assert.Equal(t, 2, storage.reads)
+	})
+
+	t.Run("should read the expense again after a notification", func(t *testing.T) {
+		cached, storage := newCached(t)
+		cached.SearchById(context.Background(), 1)
+
+		// another instance updates the expense
+		storage.Memory.Update(context.Background(), 1, ExpensesRequest{Title: "Tea", Amount: 40})
+		cached.Invalidate("1")
+		resp, err := cached.SearchById(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Tea", resp.Title)
+		assert.Equal(t, 2, storage.reads)
+	})
+
+	t.Run("should drop every expense for an unexpected notification", func(t *testing.T) {
+		cached, storage := newCached(t)
+		cached.SearchById(context.Background(), 1)
+
+		cached.Invalidate("")
+		cached.SearchById(context.Background(), 1)
+
+		assert.Equal(t, 2, storage.reads)
+	})
+
+	t.Run("should not cache a missing expense", func(t *testing.T) {
+		cached, storage := newCached(t)
+
+		_, err := cached.SearchById(context.Background(), 2)
+		assert.Error(t, err)
+		cached.SearchById(context.Background(), 2)
+
+		assert.Equal(t, 2, storage.reads)
+	})
+
+	t.Run("should read from the database while disabled", func(t *testing.T) {
+		cached, storage := newCached(t)
+		cached.SetEnabled(false)
+
+		cached.SearchById(context.Background(), 1)
+		cached.SearchById(context.Background(), 1)
+
+		assert.Equal(t, 2, storage.reads)
+	})
+}
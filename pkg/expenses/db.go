@@ -1,79 +1,437 @@
 package expenses
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/outbox"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tags"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
 	"github.com/lib/pq"
 )
 
+// Tags are upserted by key and linked through expense_tags in the same
+// statement as the expense. expenses.tags keeps the display names of the tags
+// in the requested order, $4 are the names and $5 their keys.
+const (
+	insertQuery = `WITH input AS (select name, key, pos from unnest($4::text[], $5::text[]) WITH ORDINALITY AS t(name, key, pos)),
+upserted AS (INSERT INTO tags (name, key) select name, key from input ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key RETURNING id, name, key),
+expense AS (INSERT INTO expenses (title, amount, note, tags, category_id) values ($1, $2, $3, coalesce((select array_agg(u.name order by i.pos) from input i join upserted u on u.key = i.key), $4::text[]), $6) RETURNING id, title, amount, note, tags, category_id),
+linked AS (INSERT INTO expense_tags (expense_id, tag_id) select e.id, u.id from expense e, upserted u)
+select id, title, amount, note, tags, category_id from expense`
+	searchByIdQuery = "select id, title, amount, note, tags, category_id from expenses where id = $1"
+	updateQuery     = `WITH input AS (select name, key, pos from unnest($4::text[], $5::text[]) WITH ORDINALITY AS t(name, key, pos)),
+upserted AS (INSERT INTO tags (name, key) select name, key from input where exists (select 1 from expenses where id = $7) ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key RETURNING id, name, key),
+expense AS (UPDATE expenses SET title = $1, amount = $2, note = $3, tags = coalesce((select array_agg(u.name order by i.pos) from input i join upserted u on u.key = i.key), $4::text[]), category_id = $6 WHERE id = $7 RETURNING id, title, amount, note, tags, category_id),
+unlinked AS (DELETE FROM expense_tags WHERE expense_id IN (select id from expense) AND tag_id NOT IN (select id from upserted)),
+linked AS (INSERT INTO expense_tags (expense_id, tag_id) select e.id, u.id from expense e, upserted u ON CONFLICT DO NOTHING)
+select id, title, amount, note, tags, category_id from expense`
+	searchAllQuery = "select id, title, amount, note, tags, category_id from expenses where cardinality($1::text[]) = 0 or $1::text[] <@ array(select t.key from expense_tags et join tags t on t.id = et.tag_id where et.expense_id = expenses.id) order by id"
+	lockQuery      = "select id, title, amount, note, tags, category_id from expenses where id = $1 for update"
+	deleteQuery    = "DELETE FROM expenses WHERE id = $1"
+)
+
+// Every write adds the next version of the expense to its history in the same
+// transaction, the expense row is locked while the version is numbered. The
+// history as of a time replays every version up to the last one changed by
+// then, a changed_at is not in the order of the versions.
+const (
+	insertHistoryQuery = "INSERT INTO expense_history (expense_id, version, action, actor, request_id, changes) select $1, coalesce(max(version), 0) + 1, $2, $3, $4, $5::jsonb from expense_history where expense_id = $1"
+	historyQuery       = "select version, action, actor, request_id, changed_at, changes from expense_history where expense_id = $1 order by version"
+	historyAsOfQuery   = "select version, action, actor, request_id, changed_at, changes from expense_history where expense_id = $1 and version <= (select max(version) from expense_history where expense_id = $1 and changed_at <= $2) order by version"
+)
+
+// foreignKeyViolation is the postgres error code of a reference to a missing row.
+const foreignKeyViolation = "23503"
+
+const (
+	readRetryWait    = 50 * time.Millisecond
+	readRetryMaxWait = time.Second
+)
+
 type DataMgmt struct {
-	dataMgmt *sql.DB
+	dataMgmt    *sql.DB
+	timeout     time.Duration
+	readRetries int
 }
 
-func New(d *sql.DB) *DataMgmt {
-	return &DataMgmt{d}
+// New creates a DataMgmt whose queries time out after timeout. Reads are
+// retried up to readRetries times on transient connection errors, writes
+// never are as they may have been applied.
+func New(d *sql.DB, timeout time.Duration, readRetries int) *DataMgmt {
+	return &DataMgmt{dataMgmt: d, timeout: timeout, readRetries: readRetries}
 }
 
-func (mgmt DataMgmt) Insert(req ExpensesRequest) (*ExpensesResponse, error) {
-	stmt, err := mgmt.dataMgmt.Prepare("INSERT INTO expenses (title, amount, note, tags) values ($1, $2, $3, $4) RETURNING id, title, amount, note, tags")
-	if err != nil {
-		return nil, err
+// withTimeout derives the per-query context. A zero timeout only inherits the
+// deadline of the caller.
+func (mgmt DataMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
-	defer stmt.Close()
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+func (mgmt DataMgmt) retryRead(ctx context.Context, read func() error) error {
+	return common.Retry(ctx, mgmt.readRetries+1, readRetryWait, readRetryMaxWait, read, common.IsTransient)
+}
 
-	row := stmt.QueryRow(req.Title, req.Amount, req.Note, pq.Array(req.Tags))
+// queryError prefers the context error so callers can tell a timeout or a
+// cancelled request apart from a database failure, maps a missing row to
+// common.ErrNotFound, an unknown category to common.ErrInvalid and records the
+// error on the query span.
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		err = fmt.Errorf("%w: %s", common.ErrInvalid, pqErr.Message)
+	}
+	tracing.RecordError(ctx, err)
+	return err
+}
 
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanExpense(row scanner) (*ExpensesResponse, error) {
 	result := &ExpensesResponse{}
-	err = row.Scan(&result.Id, &result.Title, &result.Amount, &result.Note, pq.Array(&result.Tags))
+	var category sql.NullInt64
+	err := row.Scan(&result.Id, &result.Title, &result.Amount, &result.Note, pq.Array(&result.Tags), &category)
 	if err != nil {
 		return nil, err
 	}
+	if category.Valid {
+		result.CategoryId = &category.Int64
+	}
 	return result, nil
 }
 
-func (mgmt DataMgmt) SearchById(id int64) (*ExpensesResponse, error) {
-	stmt, err := mgmt.dataMgmt.Prepare("select id, title, amount, note, tags from expenses where id = $1")
-	if err != nil {
+func scanHistory(row scanner) (*HistoryResponse, error) {
+	result := &HistoryResponse{}
+	var actor, requestId sql.NullString
+	var changes []byte
+	if err := row.Scan(&result.Version, &result.Action, &actor, &requestId, &result.ChangedAt, &changes); err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
+	result.Actor, result.RequestId = actor.String, requestId.String
+	if err := json.Unmarshal(changes, &result.Changes); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-	rows := stmt.QueryRow(id)
+func (mgmt DataMgmt) Insert(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("Insert", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "DataMgmt.Insert", insertQuery)
+	defer span.End()
 
-	result := &ExpensesResponse{}
-	err = rows.Scan(&result.Id, &result.Title, &result.Amount, &result.Note, pq.Array(&result.Tags))
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result *ExpensesResponse
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) (err error) {
+		names := tags.Normalize(req.Tags)
+		row := tx.QueryRowContext(ctx, insertQuery, req.Title, req.Amount, req.Note, pq.Array(names), pq.Array(tags.Keys(names)), req.CategoryId)
+		if result, err = scanExpense(row); err != nil {
+			return err
+		}
+		return addHistory(ctx, tx, result.Id, ActionInsert, nil, result)
+	})
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
+	return result, nil
+}
 
+// inTx runs fn in a transaction committed only when fn succeeds.
+func (mgmt DataMgmt) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := mgmt.dataMgmt.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lock returns the expense id locked until the end of tx.
+func lock(ctx context.Context, tx *sql.Tx, id int64) (*ExpensesResponse, error) {
+	return scanExpense(tx.QueryRowContext(ctx, lockQuery, id))
+}
+
+// addHistory records the change of the expense id from before to after with
+// the actor and request id of ctx, and adds its event to the outbox.
+func addHistory(ctx context.Context, tx *sql.Tx, id int64, action string, before *ExpensesResponse, after *ExpensesResponse) error {
+	changes, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, insertHistoryQuery, id, action, nullString(common.Actor(ctx)), nullString(common.RequestId(ctx)), string(encoded)); err != nil {
+		return err
+	}
+	event, err := newEvent(ctx, id, action, before, after, changes)
+	if err != nil {
+		return err
+	}
+	return outbox.Add(ctx, tx, event)
+}
+
+// nullString stores an empty s as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (mgmt DataMgmt) SearchById(ctx context.Context, id int64) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("SearchById", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "DataMgmt.SearchById", searchByIdQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result *ExpensesResponse
+	err := mgmt.retryRead(ctx, func() (err error) {
+		result, err = mgmt.searchById(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
 	return result, nil
 }
 
-func (mgmt DataMgmt) Update(id int64, req ExpensesRequest) (*ExpensesResponse, error) {
-	stmt, err := mgmt.dataMgmt.Prepare("UPDATE expenses SET title = $1, amount = $2, note = $3, tags = $4 WHERE id = $5 RETURNING id, title, amount, note, tags")
+func (mgmt DataMgmt) searchById(ctx context.Context, id int64) (*ExpensesResponse, error) {
+	stmt, err := mgmt.dataMgmt.PrepareContext(ctx, searchByIdQuery)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRow(req.Title, req.Amount, req.Note, pq.Array(req.Tags), id)
+	return scanExpense(stmt.QueryRowContext(ctx, id))
+}
 
-	result := &ExpensesResponse{}
-	err = row.Scan(&result.Id, &result.Title, &result.Amount, &result.Note, pq.Array(&result.Tags))
+func (mgmt DataMgmt) Update(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("Update", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "DataMgmt.Update", updateQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result *ExpensesResponse
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lock(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		names := tags.Normalize(req.Tags)
+		row := tx.QueryRowContext(ctx, updateQuery, req.Title, req.Amount, req.Note, pq.Array(names), pq.Array(tags.Keys(names)), req.CategoryId, id)
+		if result, err = scanExpense(row); err != nil {
+			return err
+		}
+		return addHistory(ctx, tx, id, ActionUpdate, before, result)
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Delete removes the expense, its history is kept. common.ErrConflict is
+// returned while it has attachments, which are deleted with their blobs first.
+func (mgmt DataMgmt) Delete(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("Delete", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "DataMgmt.Delete", deleteQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lock(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, deleteQuery, id)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return common.ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		return addHistory(ctx, tx, id, ActionDelete, before, nil)
+	})
 	if err != nil {
+		return queryError(ctx, err)
+	}
+	return nil
+}
+
+// History returns the versions of the expense, the oldest first, also after
+// the expense was deleted. common.ErrNotFound is returned when the expense
+// never existed.
+func (mgmt DataMgmt) History(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	defer metrics.ObserveQuery("History", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "DataMgmt.History", historyQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result []HistoryResponse
+	err := mgmt.retryRead(ctx, func() (err error) {
+		result, err = queryHistory(ctx, mgmt.dataMgmt, historyQuery, id)
+		return err
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// querier is a *sql.DB or a *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// queryHistory returns the versions selected by query, common.ErrNotFound
+// when there is none.
+func queryHistory(ctx context.Context, q querier, query string, args ...any) ([]HistoryResponse, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []HistoryResponse{}
+	for rows.Next() {
+		version, err := scanHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *version)
+	}
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	if len(result) == 0 {
+		return nil, common.ErrNotFound
+	}
 	return result, nil
 }
 
-func (mgmt DataMgmt) SearchAll() ([]ExpensesResponse, error) {
-	stmt, err := mgmt.dataMgmt.Prepare("select id, title, amount, note, tags from expenses")
+// SearchAsOf returns the expense as it was at the moment at, replayed from
+// its history. common.ErrNotFound is returned when it did not exist then.
+func (mgmt DataMgmt) SearchAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("SearchAsOf", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "DataMgmt.SearchAsOf", historyAsOfQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var history []HistoryResponse
+	err := mgmt.retryRead(ctx, func() (err error) {
+		history, err = queryHistory(ctx, mgmt.dataMgmt, historyAsOfQuery, id, at)
+		return err
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	result, err := replay(id, history)
+	if err == nil && result == nil {
+		err = common.ErrNotFound
+	}
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Revert restores the expense to its state at version and records it as a
+// new version. common.ErrInvalid is returned for an unknown version or a
+// version that deleted the expense.
+func (mgmt DataMgmt) Revert(ctx context.Context, id int64, version int64) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("Revert", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "DataMgmt.Revert", updateQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result *ExpensesResponse
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lock(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		history, err := queryHistory(ctx, tx, historyQuery, id)
+		if err != nil {
+			return err
+		}
+		req, err := revertTo(id, history, version)
+		if err != nil {
+			return err
+		}
+		names := tags.Normalize(req.Tags)
+		row := tx.QueryRowContext(ctx, updateQuery, req.Title, req.Amount, req.Note, pq.Array(names), pq.Array(tags.Keys(names)), req.CategoryId, id)
+		if result, err = scanExpense(row); err != nil {
+			return err
+		}
+		return addHistory(ctx, tx, id, ActionRevert, before, result)
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) SearchAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
+	defer metrics.ObserveQuery("SearchAll", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "DataMgmt.SearchAll", searchAllQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result []ExpensesResponse
+	err := mgmt.retryRead(ctx, func() (err error) {
+		result, err = mgmt.searchAll(ctx, filter)
+		return err
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) searchAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
+	stmt, err := mgmt.dataMgmt.PrepareContext(ctx, searchAllQuery)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx, pq.Array(append([]string{}, tags.Keys(filter.Tags)...)))
 	if err != nil {
 		return nil, err
 	}
@@ -81,13 +439,15 @@ func (mgmt DataMgmt) SearchAll() ([]ExpensesResponse, error) {
 
 	result := []ExpensesResponse{}
 	for rows.Next() {
-		exp := &ExpensesResponse{}
-		err = rows.Scan(&exp.Id, &exp.Title, &exp.Amount, &exp.Note, pq.Array(&exp.Tags))
+		exp, err := scanExpense(rows)
 		if err != nil {
 			return nil, err
 		}
 		result = append(result, *exp)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
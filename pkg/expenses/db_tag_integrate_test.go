@@ -0,0 +1,79 @@
+//go:build integration
+
+package expenses
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/cache"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataMgmtConformance(t *testing.T) {
+	db, err := sql.Open("postgres", databaseUrl())
+	assert.NoError(t, err)
+	defer db.Close()
+
+	runStorageConformance(t, func(t *testing.T) Storage {
+		_, err := db.Exec("TRUNCATE expenses, expense_history, outbox, tags, categories RESTART IDENTITY CASCADE")
+		assert.NoError(t, err)
+		return New(db, 5*time.Second, 2)
+	})
+}
+
+func TestCachedNotifyIntegration(t *testing.T) {
+	db, err := sql.Open("postgres", databaseUrl())
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("TRUNCATE expenses, expense_history, outbox, tags, categories RESTART IDENTITY CASCADE")
+	assert.NoError(t, err)
+
+	// two instances caching the same database
+	replica := NewCached(New(db, 5*time.Second, 2), 10, time.Minute)
+	listener := cache.Listen(databaseUrl(), Channel, replica, logrus.New())
+	defer listener.Close()
+	writer := New(db, 5*time.Second, 2)
+
+	exp, err := writer.Insert(context.Background(), ExpensesRequest{Title: "Coffee", Amount: 60, Tags: []string{}})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		resp, err := replica.SearchById(context.Background(), exp.Id)
+		return err == nil && replica.cache.Len() == 1 && resp.Title == "Coffee"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = writer.Update(context.Background(), exp.Id, ExpensesRequest{Title: "Tea", Amount: 40, Tags: []string{}})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		resp, err := replica.SearchById(context.Background(), exp.Id)
+		return err == nil && resp.Title == "Tea"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWebhookDeliveriesIntegration(t *testing.T) {
+	db, err := sql.Open("postgres", databaseUrl())
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("TRUNCATE expenses, expense_history, outbox, tags, categories, webhooks RESTART IDENTITY CASCADE")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO webhooks (url, events, secret) VALUES ('https://example.com/hook', '{ExpenseCreated}', 'secret')")
+	assert.NoError(t, err)
+	mgmt := New(db, 5*time.Second, 2)
+
+	exp, err := mgmt.Insert(context.Background(), ExpensesRequest{Title: "Coffee", Amount: 60, Tags: []string{}})
+	assert.NoError(t, err)
+	_, err = mgmt.Update(context.Background(), exp.Id, ExpensesRequest{Title: "Tea", Amount: 40, Tags: []string{}})
+	assert.NoError(t, err)
+
+	var count int
+	var event, title string
+	err = db.QueryRow("select count(*) over (), event, payload->'expense'->>'title' from webhook_deliveries").Scan(&count, &event, &title)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, EventCreated, event)
+	assert.Equal(t, "Coffee", title)
+}
@@ -3,10 +3,14 @@
 package expenses
 
 import (
+	"context"
+	"encoding/json"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
@@ -22,14 +26,23 @@ func TestInsert(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		defer db.Close()
 		assert.NoError(t, err)
-		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).AddRow(1, req.Title, req.Amount, req.Note, pq.Array(req.Tags))
-		get := mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO expenses (title, amount, note, tags) values ($1, $2, $3, $4) RETURNING id, title, amount, note, tags"))
-		get.ExpectQuery().WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags)).WillReturnRows(row)
+		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(1, req.Title, req.Amount, req.Note, pq.Array(req.Tags), nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags), pq.Array([]string{"mocktags"}), nil).WillReturnRows(row)
+		mock.ExpectExec(regexp.QuoteMeta(insertHistoryQuery)).
+			WithArgs(1, ActionInsert, "alice", "req-1", `{"amount":{"from":null,"to":10},"note":{"from":null,"to":"mockNote"},"tags":{"from":null,"to":["mockTags"]},"title":{"from":null,"to":"mockTitle"}}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox")).
+			WithArgs(Aggregate, 1, EventCreated, `{"expense":{"id":1,"title":"mockTitle","amount":10,"note":"mockNote","tags":["mockTags"]},"changes":{"amount":{"from":null,"to":10},"note":{"from":null,"to":"mockNote"},"tags":{"from":null,"to":["mockTags"]},"title":{"from":null,"to":"mockTitle"}}}`, "alice", "req-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
-		dataMgmt := New(db)
-		result, err := dataMgmt.Insert(req)
+		dataMgmt := New(db, time.Second, 0)
+		ctx := common.WithRequestId(common.WithActor(context.Background(), "alice"), "req-1")
+		result, err := dataMgmt.Insert(ctx, req)
 
 		assert.Nil(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.Equal(t, req.Title, result.Title)
 		assert.Equal(t, req.Amount, result.Amount)
 		assert.Equal(t, req.Note, result.Note)
@@ -47,15 +60,50 @@ func TestInsert(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		defer db.Close()
 		assert.NoError(t, err)
-		get := mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO expenses (title, amount, note, tags) values ($1, $2, $3, $4) RETURNING id, title, amount, note, tags"))
-		get.ExpectQuery().WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags)).WillReturnError(&pq.Error{Message: "error connection db"})
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags), pq.Array([]string{"mocktags"}), nil).WillReturnError(&pq.Error{Message: "error connection db"})
 
-		dataMgmt := New(db)
-		result, err := dataMgmt.Insert(req)
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.Insert(context.Background(), req)
 
 		assert.NotNil(t, err)
 		assert.Nil(t, result)
 	})
+	t.Run("should return common.ErrInvalid when the category does not exist", func(t *testing.T) {
+		category := int64(404)
+		req := ExpensesRequest{Title: "mockTitle", CategoryId: &category}
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs(req.Title, req.Amount, req.Note, pq.Array([]string(nil)), pq.Array([]string(nil)), 404).WillReturnError(&pq.Error{Code: "23503"})
+
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.Insert(context.Background(), req)
+
+		assert.ErrorIs(t, err, common.ErrInvalid)
+		assert.Nil(t, result)
+	})
+	t.Run("should return context.DeadlineExceeded when query takes longer than timeout", func(t *testing.T) {
+		req := ExpensesRequest{
+			Title:  "mockTitle",
+			Amount: 10,
+			Note:   "mockNote",
+			Tags:   []string{"mockTags"},
+		}
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(1, req.Title, req.Amount, req.Note, pq.Array(req.Tags), nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags), pq.Array([]string{"mocktags"}), nil).WillDelayFor(time.Second).WillReturnRows(row)
+
+		dataMgmt := New(db, 10*time.Millisecond, 0)
+		result, err := dataMgmt.Insert(context.Background(), req)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Nil(t, result)
+	})
 }
 
 func TestSearchById(t *testing.T) {
@@ -70,12 +118,12 @@ func TestSearchById(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		defer db.Close()
 		assert.NoError(t, err)
-		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).AddRow(id, mockData.Title, mockData.Amount, mockData.Note, pq.Array(mockData.Tags))
-		get := mock.ExpectPrepare(regexp.QuoteMeta("select id, title, amount, note, tags from expenses where id = $1"))
+		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(id, mockData.Title, mockData.Amount, mockData.Note, pq.Array(mockData.Tags), nil)
+		get := mock.ExpectPrepare(regexp.QuoteMeta(searchByIdQuery))
 		get.ExpectQuery().WithArgs(id).WillReturnRows(row)
 
-		dataMgmt := New(db)
-		result, err := dataMgmt.SearchById(id)
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.SearchById(context.Background(), id)
 
 		assert.Nil(t, err)
 		assert.Equal(t, mockData.Title, result.Title)
@@ -90,15 +138,56 @@ func TestSearchById(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		defer db.Close()
 		assert.NoError(t, err)
-		get := mock.ExpectPrepare(regexp.QuoteMeta("select id, title, amount, note, tags from expenses where id = $1"))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(searchByIdQuery))
 		get.ExpectQuery().WithArgs(id).WillReturnError(&pq.Error{Message: "error connection db"})
 
-		dataMgmt := New(db)
-		result, err := dataMgmt.SearchById(id)
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.SearchById(context.Background(), id)
 
 		assert.NotNil(t, err)
 		assert.Nil(t, result)
 	})
+	t.Run("should retry when a transient connection error occurs", func(t *testing.T) {
+		id := int64(1)
+		mockData := ExpensesRequest{
+			Title:  "mockTitle",
+			Amount: 10,
+			Note:   "mockNote",
+			Tags:   []string{"mockTags"},
+		}
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(id, mockData.Title, mockData.Amount, mockData.Note, pq.Array(mockData.Tags), nil)
+		mock.ExpectPrepare(regexp.QuoteMeta(searchByIdQuery)).
+			ExpectQuery().WithArgs(id).WillReturnError(&pq.Error{Code: "08006", Message: "connection failure"})
+		mock.ExpectPrepare(regexp.QuoteMeta(searchByIdQuery)).
+			ExpectQuery().WithArgs(id).WillReturnRows(row)
+
+		dataMgmt := New(db, time.Second, 1)
+		result, err := dataMgmt.SearchById(context.Background(), id)
+
+		assert.Nil(t, err)
+		assert.Equal(t, id, result.Id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSearchByIdNotFound(t *testing.T) {
+	t.Run("should return common.ErrNotFound when no row", func(t *testing.T) {
+		id := int64(1)
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		get := mock.ExpectPrepare(regexp.QuoteMeta(searchByIdQuery))
+		get.ExpectQuery().WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}))
+
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.SearchById(context.Background(), id)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, result)
+	})
 }
 
 func TestUpdate(t *testing.T) {
@@ -113,14 +202,23 @@ func TestUpdate(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		defer db.Close()
 		assert.NoError(t, err)
-		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).AddRow(id, req.Title, req.Amount, req.Note, pq.Array(req.Tags))
-		get := mock.ExpectPrepare(regexp.QuoteMeta("UPDATE expenses SET title = $1, amount = $2, note = $3, tags = $4 WHERE id = $5 RETURNING id, title, amount, note, tags"))
-		get.ExpectQuery().WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags), id).WillReturnRows(row)
+		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(id, req.Title, req.Amount, req.Note, pq.Array(req.Tags), nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(id, req.Title, 5, req.Note, pq.Array(req.Tags), nil))
+		mock.ExpectQuery(regexp.QuoteMeta(updateQuery)).WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags), pq.Array([]string{"mocktags"}), nil, id).WillReturnRows(row)
+		mock.ExpectExec(regexp.QuoteMeta(insertHistoryQuery)).
+			WithArgs(id, ActionUpdate, nil, nil, `{"amount":{"from":5,"to":10}}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox")).
+			WithArgs(Aggregate, id, EventUpdated, sqlmock.AnyArg(), nil, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
-		dataMgmt := New(db)
-		result, err := dataMgmt.Update(id, req)
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.Update(context.Background(), id, req)
 
 		assert.Nil(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.Equal(t, req.Title, result.Title)
 		assert.Equal(t, req.Amount, result.Amount)
 		assert.Equal(t, req.Note, result.Note)
@@ -139,17 +237,90 @@ func TestUpdate(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		defer db.Close()
 		assert.NoError(t, err)
-		get := mock.ExpectPrepare(regexp.QuoteMeta("UPDATE expenses SET title = $1, amount = $2, note = $3, tags = $4 WHERE id = $5 RETURNING id, title, amount, note, tags"))
-		get.ExpectQuery().WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags), id).WillReturnError(&pq.Error{Message: "error connection db"})
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(id, req.Title, 5, req.Note, pq.Array(req.Tags), nil))
+		mock.ExpectQuery(regexp.QuoteMeta(updateQuery)).WithArgs(req.Title, req.Amount, req.Note, pq.Array(req.Tags), pq.Array([]string{"mocktags"}), nil, id).WillReturnError(&pq.Error{Message: "error connection db"})
 
-		dataMgmt := New(db)
-		result, err := dataMgmt.Update(id, req)
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.Update(context.Background(), id, req)
 
 		assert.NotNil(t, err)
 		assert.Nil(t, result)
 	})
 }
 
+func TestUpdateNotFound(t *testing.T) {
+	t.Run("should return common.ErrNotFound without updating when no row", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(404).WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}))
+		mock.ExpectRollback()
+
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.Update(context.Background(), 404, ExpensesRequest{Title: "mockTitle"})
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("should delete and record every field as removed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(1, "mockTitle", 10, "", nil, 3))
+		mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(insertHistoryQuery)).
+			WithArgs(1, ActionDelete, "alice", nil, `{"amount":{"from":10,"to":null},"category_id":{"from":3,"to":null},"note":{"from":"","to":null},"title":{"from":"mockTitle","to":null}}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox")).
+			WithArgs(Aggregate, 1, EventDeleted, `{"expense":{"id":1,"title":"mockTitle","amount":10,"note":"","tags":null,"category_id":3},"changes":{"amount":{"from":10,"to":null},"category_id":{"from":3,"to":null},"note":{"from":"","to":null},"title":{"from":"mockTitle","to":null}}}`, "alice", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		dataMgmt := New(db, time.Second, 0)
+		err = dataMgmt.Delete(common.WithActor(context.Background(), "alice"), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return common.ErrNotFound when no row", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(404).WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}))
+		mock.ExpectRollback()
+
+		dataMgmt := New(db, time.Second, 0)
+		err = dataMgmt.Delete(context.Background(), 404)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("should return common.ErrConflict while the expense has attachments", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).AddRow(1, "mockTitle", 10, "", nil, nil))
+		mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(1).WillReturnError(&pq.Error{Code: foreignKeyViolation})
+		mock.ExpectRollback()
+
+		dataMgmt := New(db, time.Second, 0)
+		err = dataMgmt.Delete(context.Background(), 1)
+
+		assert.ErrorIs(t, err, common.ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestSearchAll(t *testing.T) {
 	t.Run("should search success when no error", func(t *testing.T) {
 		mockData := ExpensesRequest{
@@ -161,14 +332,14 @@ func TestSearchAll(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		defer db.Close()
 		assert.NoError(t, err)
-		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"})
-		row.AddRow(1, mockData.Title, mockData.Amount, mockData.Note, pq.Array(mockData.Tags))
-		row.AddRow(2, mockData.Title, mockData.Amount, mockData.Note, pq.Array(mockData.Tags))
-		get := mock.ExpectPrepare(regexp.QuoteMeta("select id, title, amount, note, tags from expenses"))
-		get.ExpectQuery().WithArgs().WillReturnRows(row)
+		row := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"})
+		row.AddRow(1, mockData.Title, mockData.Amount, mockData.Note, pq.Array(mockData.Tags), nil)
+		row.AddRow(2, mockData.Title, mockData.Amount, mockData.Note, pq.Array(mockData.Tags), nil)
+		get := mock.ExpectPrepare(regexp.QuoteMeta(searchAllQuery))
+		get.ExpectQuery().WithArgs(pq.Array([]string{"mocktags"})).WillReturnRows(row)
 
-		dataMgmt := New(db)
-		result, err := dataMgmt.SearchAll()
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.SearchAll(context.Background(), Filter{Tags: []string{"mockTags"}})
 
 		assert.Nil(t, err)
 		assert.NotNil(t, result)
@@ -179,13 +350,84 @@ func TestSearchAll(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		defer db.Close()
 		assert.NoError(t, err)
-		get := mock.ExpectPrepare(regexp.QuoteMeta("select id, title, amount, note, tags from expenses"))
-		get.ExpectQuery().WithArgs().WillReturnError(&pq.Error{Message: "error connection db"})
+		get := mock.ExpectPrepare(regexp.QuoteMeta(searchAllQuery))
+		get.ExpectQuery().WithArgs(pq.Array([]string{})).WillReturnError(&pq.Error{Message: "error connection db"})
 
-		dataMgmt := New(db)
-		result, err := dataMgmt.SearchAll()
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.SearchAll(context.Background(), Filter{})
 
 		assert.NotNil(t, err)
 		assert.Nil(t, result)
 	})
 }
+
+func TestHistory(t *testing.T) {
+	t.Run("should return the versions of the expense", func(t *testing.T) {
+		changedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		rows := sqlmock.NewRows([]string{"version", "action", "actor", "request_id", "changed_at", "changes"}).
+			AddRow(1, ActionInsert, nil, nil, changedAt, []byte(`{"title": {"from": null, "to": "mockTitle"}}`)).
+			AddRow(2, ActionUpdate, "alice", "req-1", changedAt, []byte(`{}`))
+		mock.ExpectQuery(regexp.QuoteMeta(historyQuery)).WithArgs(1).WillReturnRows(rows)
+
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.History(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []HistoryResponse{
+			{Version: 1, Action: ActionInsert, ChangedAt: changedAt, Changes: map[string]Change{"title": {From: json.RawMessage("null"), To: json.RawMessage(`"mockTitle"`)}}},
+			{Version: 2, Action: ActionUpdate, Actor: "alice", RequestId: "req-1", ChangedAt: changedAt, Changes: map[string]Change{}},
+		}, result)
+	})
+
+	t.Run("should return common.ErrNotFound when the expense has no history", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectQuery(regexp.QuoteMeta(historyQuery)).WithArgs(404).WillReturnRows(sqlmock.NewRows([]string{"version", "action", "actor", "request_id", "changed_at", "changes"}))
+
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.History(context.Background(), 404)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, result)
+	})
+}
+
+func TestSearchAsOf(t *testing.T) {
+	t.Run("should replay the versions recorded until the given time", func(t *testing.T) {
+		at := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		rows := sqlmock.NewRows([]string{"version", "action", "actor", "request_id", "changed_at", "changes"}).
+			AddRow(1, ActionInsert, nil, nil, at, []byte(`{"title": {"from": null, "to": "mockTitle"}, "amount": {"from": null, "to": 10}, "tags": {"from": null, "to": ["food"]}}`)).
+			AddRow(2, ActionUpdate, nil, nil, at, []byte(`{"amount": {"from": 10, "to": 20}}`))
+		mock.ExpectQuery(regexp.QuoteMeta(historyAsOfQuery)).WithArgs(1, at).WillReturnRows(rows)
+
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.SearchAsOf(context.Background(), 1, at)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &ExpensesResponse{Id: 1, Title: "mockTitle", Amount: 20, Tags: []string{"food"}}, result)
+	})
+
+	t.Run("should return common.ErrNotFound when the expense was deleted at the given time", func(t *testing.T) {
+		at := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		rows := sqlmock.NewRows([]string{"version", "action", "actor", "request_id", "changed_at", "changes"}).
+			AddRow(1, ActionInsert, nil, nil, at, []byte(`{"title": {"from": null, "to": "mockTitle"}}`)).
+			AddRow(2, ActionDelete, nil, nil, at, []byte(`{"title": {"from": "mockTitle", "to": null}}`))
+		mock.ExpectQuery(regexp.QuoteMeta(historyAsOfQuery)).WithArgs(1, at).WillReturnRows(rows)
+
+		dataMgmt := New(db, time.Second, 0)
+		result, err := dataMgmt.SearchAsOf(context.Background(), 1, at)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, result)
+	})
+}
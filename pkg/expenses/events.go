@@ -0,0 +1,57 @@
+package expenses
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/outbox"
+)
+
+// The domain events of the expenses, added to the outbox by every write of
+// the database backends.
+const (
+	EventCreated = "ExpenseCreated"
+	EventUpdated = "ExpenseUpdated"
+	EventDeleted = "ExpenseDeleted"
+)
+
+// Aggregate is the outbox aggregate of the expense events.
+const Aggregate = "expense"
+
+// eventTypes maps the history actions to their events, a revert updates the
+// expense.
+var eventTypes = map[string]string{
+	ActionInsert: EventCreated,
+	ActionUpdate: EventUpdated,
+	ActionRevert: EventUpdated,
+	ActionDelete: EventDeleted,
+}
+
+// EventPayload is the payload of the expense events. Expense is the expense
+// after the write, before it for ExpenseDeleted.
+type EventPayload struct {
+	Expense *ExpensesResponse `json:"expense"`
+	Changes map[string]Change `json:"changes"`
+}
+
+// newEvent is the event of the write action changing the expense id from
+// before to after, with the actor and request id of ctx.
+func newEvent(ctx context.Context, id int64, action string, before *ExpensesResponse, after *ExpensesResponse, changes map[string]Change) (outbox.Event, error) {
+	exp := after
+	if exp == nil {
+		exp = before
+	}
+	payload, err := json.Marshal(EventPayload{Expense: exp, Changes: changes})
+	if err != nil {
+		return outbox.Event{}, err
+	}
+	return outbox.Event{
+		Aggregate:   Aggregate,
+		AggregateId: id,
+		Type:        eventTypes[action],
+		Payload:     payload,
+		Actor:       common.Actor(ctx),
+		RequestId:   common.RequestId(ctx),
+	}, nil
+}
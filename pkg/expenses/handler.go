@@ -1,18 +1,28 @@
 package expenses
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/render"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
 	"github.com/labstack/echo/v4"
 )
 
 type Services interface {
-	AddExpenses(req ExpensesRequest) (*ExpensesResponse, error)
-	SearchExpensesById(id int64) (*ExpensesResponse, error)
-	UpdateExpenses(id int64, req ExpensesRequest) (*ExpensesResponse, error)
-	SearchExpensesAll() ([]ExpensesResponse, error)
+	AddExpenses(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error)
+	SearchExpensesById(ctx context.Context, id int64) (*ExpensesResponse, error)
+	UpdateExpenses(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error)
+	SearchExpensesAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error)
+	DeleteExpenses(ctx context.Context, id int64) error
+	ExpensesHistory(ctx context.Context, id int64) ([]HistoryResponse, error)
+	SearchExpensesAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error)
+	RevertExpenses(ctx context.Context, id int64, req RevertRequest) (*ExpensesResponse, error)
 }
 type Handler struct {
 	log     common.Log
@@ -24,12 +34,17 @@ func NewHandler(s Services, l common.Log) *Handler {
 }
 
 func (h Handler) AddExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.AddExpenses")
+	defer span.End()
+
 	req := ExpensesRequest{}
-	if err := c.Bind(&req); err != nil {
+	if err := render.Bind(c, &req); errors.Is(err, render.ErrUnsupportedMediaType) {
+		return c.NoContent(http.StatusUnsupportedMediaType)
+	} else if err != nil {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
-	resp, err := h.service.AddExpenses(req)
+	resp, err := h.service.AddExpenses(ctx, req)
 	if err != nil {
 		if cmErr, ok := err.(*common.Error); ok {
 			return c.NoContent(cmErr.Code)
@@ -38,17 +53,29 @@ func (h Handler) AddExpenses(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusCreated, resp)
+	return render.Render(c, http.StatusCreated, resp)
 }
 
 func (h Handler) SearchExpensesById(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.SearchExpensesById")
+	defer span.End()
+
 	paramId := c.Param("id")
 	id, err := strconv.ParseInt(paramId, 10, 64)
 	if err != nil {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
-	resp, err := h.service.SearchExpensesById(id)
+	var resp *ExpensesResponse
+	if asOf := c.QueryParam("as_of"); asOf != "" {
+		var at time.Time
+		if at, err = time.Parse(time.RFC3339Nano, asOf); err != nil {
+			return c.NoContent(http.StatusBadRequest)
+		}
+		resp, err = h.service.SearchExpensesAsOf(ctx, id, at)
+	} else {
+		resp, err = h.service.SearchExpensesById(ctx, id)
+	}
 	if err != nil {
 		if cmErr, ok := err.(*common.Error); ok {
 			return c.NoContent(cmErr.Code)
@@ -57,10 +84,13 @@ func (h Handler) SearchExpensesById(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, resp)
+	return render.Render(c, http.StatusOK, resp)
 }
 
 func (h Handler) UpdateExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.UpdateExpenses")
+	defer span.End()
+
 	paramId := c.Param("id")
 	id, err := strconv.ParseInt(paramId, 10, 64)
 	if err != nil {
@@ -68,11 +98,13 @@ func (h Handler) UpdateExpenses(c echo.Context) error {
 	}
 
 	req := ExpensesRequest{}
-	if err := c.Bind(&req); err != nil {
+	if err := render.Bind(c, &req); errors.Is(err, render.ErrUnsupportedMediaType) {
+		return c.NoContent(http.StatusUnsupportedMediaType)
+	} else if err != nil {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
-	resp, err := h.service.UpdateExpenses(id, req)
+	resp, err := h.service.UpdateExpenses(ctx, id, req)
 	if err != nil {
 		if cmErr, ok := err.(*common.Error); ok {
 			return c.NoContent(cmErr.Code)
@@ -81,11 +113,21 @@ func (h Handler) UpdateExpenses(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, resp)
+	return render.Render(c, http.StatusOK, resp)
 }
 
 func (h Handler) SearchExpensesAll(c echo.Context) error {
-	resp, err := h.service.SearchExpensesAll()
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.SearchExpensesAll")
+	defer span.End()
+
+	filter := Filter{}
+	for _, tag := range strings.Split(c.QueryParam("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	resp, err := h.service.SearchExpensesAll(ctx, filter)
 	if err != nil {
 		if cmErr, ok := err.(*common.Error); ok {
 			return c.NoContent(cmErr.Code)
@@ -94,5 +136,77 @@ func (h Handler) SearchExpensesAll(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, resp)
+	return render.List(c, http.StatusOK, "expenses", resp)
+}
+
+func (h Handler) DeleteExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.DeleteExpenses")
+	defer span.End()
+
+	paramId := c.Param("id")
+	id, err := strconv.ParseInt(paramId, 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if err := h.service.DeleteExpenses(ctx, id); err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler DeleteExpenses Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h Handler) ExpensesHistory(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.ExpensesHistory")
+	defer span.End()
+
+	paramId := c.Param("id")
+	id, err := strconv.ParseInt(paramId, 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.ExpensesHistory(ctx, id)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler ExpensesHistory Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return render.List(c, http.StatusOK, "history", resp)
+}
+
+func (h Handler) RevertExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.RevertExpenses")
+	defer span.End()
+
+	paramId := c.Param("id")
+	id, err := strconv.ParseInt(paramId, 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	req := RevertRequest{}
+	if err := render.Bind(c, &req); errors.Is(err, render.ErrUnsupportedMediaType) {
+		return c.NoContent(http.StatusUnsupportedMediaType)
+	} else if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.RevertExpenses(ctx, id, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler RevertExpenses Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return render.Render(c, http.StatusOK, resp)
 }
@@ -12,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"testing"
@@ -25,16 +26,25 @@ import (
 
 const serverPort = 80
 
+// databaseUrl is the DATABASE_URL environment variable, defaulting to the db
+// service of docker-compose.test.yml.
+func databaseUrl() string {
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		return url
+	}
+	return "postgresql://root:root@db/go-example-db?sslmode=disable"
+}
+
 func setup(t *testing.T) (*sql.DB, func()) {
 	// Setup server
 	eh := echo.New()
-	db, err := sql.Open("postgres", "postgresql://root:root@db/go-example-db?sslmode=disable")
+	db, err := sql.Open("postgres", databaseUrl())
 	if err != nil {
 		assert.Error(t, err)
 	}
 	go func(e *echo.Echo, db *sql.DB) {
 		logRus := logrus.New()
-		storage := New(db)
+		storage := New(db, 5*time.Second, 2)
 		service := NewService(storage, logRus)
 		handler := NewHandler(service, logRus)
 
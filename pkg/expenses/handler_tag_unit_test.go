@@ -3,17 +3,24 @@
 package expenses
 
 import (
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/EknarongAphiphutthikul/assessment/pkg/apiversion"
 	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/render"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 type ServiceSuccess struct {
@@ -21,9 +28,13 @@ type ServiceSuccess struct {
 	searchExpensesByIdWasCalled bool
 	updateExpensesWasCalled     bool
 	searchExpensesAllWasCalled  bool
+	deleteExpensesWasCalled     bool
+	filter                      Filter
+	asOf                        time.Time
+	revert                      RevertRequest
 }
 
-func (s *ServiceSuccess) AddExpenses(req ExpensesRequest) (*ExpensesResponse, error) {
+func (s *ServiceSuccess) AddExpenses(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
 	s.addExpensesWasCalled = true
 	resp := &ExpensesResponse{
 		Id:     1,
@@ -35,7 +46,7 @@ func (s *ServiceSuccess) AddExpenses(req ExpensesRequest) (*ExpensesResponse, er
 	return resp, nil
 }
 
-func (s *ServiceSuccess) SearchExpensesById(id int64) (*ExpensesResponse, error) {
+func (s *ServiceSuccess) SearchExpensesById(ctx context.Context, id int64) (*ExpensesResponse, error) {
 	s.searchExpensesByIdWasCalled = true
 	resp := &ExpensesResponse{
 		Id:     id,
@@ -47,7 +58,7 @@ func (s *ServiceSuccess) SearchExpensesById(id int64) (*ExpensesResponse, error)
 	return resp, nil
 }
 
-func (s *ServiceSuccess) UpdateExpenses(id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+func (s *ServiceSuccess) UpdateExpenses(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
 	s.updateExpensesWasCalled = true
 	resp := &ExpensesResponse{
 		Id:     id,
@@ -59,8 +70,9 @@ func (s *ServiceSuccess) UpdateExpenses(id int64, req ExpensesRequest) (*Expense
 	return resp, nil
 }
 
-func (s *ServiceSuccess) SearchExpensesAll() ([]ExpensesResponse, error) {
+func (s *ServiceSuccess) SearchExpensesAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
 	s.searchExpensesAllWasCalled = true
+	s.filter = filter
 	resp := []ExpensesResponse{
 		{
 			Id:     1,
@@ -80,6 +92,33 @@ func (s *ServiceSuccess) SearchExpensesAll() ([]ExpensesResponse, error) {
 	return resp, nil
 }
 
+func (s *ServiceSuccess) DeleteExpenses(ctx context.Context, id int64) error {
+	s.deleteExpensesWasCalled = true
+	return nil
+}
+
+func (s *ServiceSuccess) ExpensesHistory(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	resp := []HistoryResponse{
+		{
+			Version: 1,
+			Action:  ActionInsert,
+			Actor:   "default",
+			Changes: map[string]Change{"title": {From: json.RawMessage("null"), To: json.RawMessage(`"mockTitle"`)}},
+		},
+	}
+	return resp, nil
+}
+
+func (s *ServiceSuccess) SearchExpensesAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error) {
+	s.asOf = at
+	return &ExpensesResponse{Id: id, Title: "oldTitle"}, nil
+}
+
+func (s *ServiceSuccess) RevertExpenses(ctx context.Context, id int64, req RevertRequest) (*ExpensesResponse, error) {
+	s.revert = req
+	return &ExpensesResponse{Id: id, Title: "oldTitle"}, nil
+}
+
 type ServiceError struct {
 	addExpensesWasCalled        bool
 	searchExpensesByIdWasCalled bool
@@ -88,26 +127,53 @@ type ServiceError struct {
 	statusCodeError             int
 }
 
-func (s *ServiceError) AddExpenses(req ExpensesRequest) (*ExpensesResponse, error) {
+func (s *ServiceError) AddExpenses(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
 	s.addExpensesWasCalled = true
 	return nil, &common.Error{Code: s.statusCodeError}
 }
 
-func (s *ServiceError) SearchExpensesById(id int64) (*ExpensesResponse, error) {
+func (s *ServiceError) SearchExpensesById(ctx context.Context, id int64) (*ExpensesResponse, error) {
 	s.searchExpensesByIdWasCalled = true
 	return nil, &common.Error{Code: s.statusCodeError}
 }
 
-func (s *ServiceError) UpdateExpenses(id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+func (s *ServiceError) UpdateExpenses(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
 	s.updateExpensesWasCalled = true
 	return nil, &common.Error{Code: s.statusCodeError}
 }
 
-func (s *ServiceError) SearchExpensesAll() ([]ExpensesResponse, error) {
+func (s *ServiceError) SearchExpensesAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
 	s.searchExpensesAllWasCalled = true
 	return nil, &common.Error{Code: s.statusCodeError}
 }
 
+func (s *ServiceError) DeleteExpenses(ctx context.Context, id int64) error {
+	return &common.Error{Code: s.statusCodeError}
+}
+
+func (s *ServiceError) ExpensesHistory(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	return nil, &common.Error{Code: s.statusCodeError}
+}
+
+func (s *ServiceError) SearchExpensesAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error) {
+	return nil, &common.Error{Code: s.statusCodeError}
+}
+
+func (s *ServiceError) RevertExpenses(ctx context.Context, id int64, req RevertRequest) (*ExpensesResponse, error) {
+	return nil, &common.Error{Code: s.statusCodeError}
+}
+
+// setupRoutes serves the Routes of storage with the default config.
+func setupRoutes(t *testing.T, storage Storage) *echo.Echo {
+	store, err := config.NewStore([]string{"-port", "2565", "-database-url", "postgres://localhost:5432/postgres"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	Routes(e, &config.Instance{Log: logrus.New(), Config: store}, storage)
+	return e
+}
+
 func TestAddExpensesHandler(t *testing.T) {
 	t.Run("should return http status code = 201 and ExpensesResponse when no error that service.AddExpenses()", func(t *testing.T) {
 		// Arrange
@@ -397,6 +463,28 @@ func TestSearchExpensesAllHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("should pass tags query param as filter to service.SearchExpensesAll()", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/expenses?tags=food,%20beverage,", nil)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		service := &ServiceSuccess{}
+		log := logrus.New()
+		handler := NewHandler(service, log)
+
+		// Act
+		err := handler.SearchExpensesAll(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, []string{"food", "beverage"}, service.filter.Tags)
+		}
+	})
+
 	t.Run("should return http status code = 500 when error that service.SearchExpensesAll()", func(t *testing.T) {
 		// Arrange
 		e := echo.New()
@@ -419,3 +507,497 @@ func TestSearchExpensesAllHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestDeleteExpensesHandler(t *testing.T) {
+	t.Run("should return http status code = 204 when no error that service.DeleteExpenses()", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		service := &ServiceSuccess{}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.DeleteExpenses(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			assert.True(t, service.deleteExpensesWasCalled)
+		}
+	})
+
+	t.Run("should return the status code of the error of service.DeleteExpenses()", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("404")
+
+		service := &ServiceError{statusCodeError: http.StatusNotFound}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.DeleteExpenses(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestExpensesHistoryHandler(t *testing.T) {
+	t.Run("should return http status code = 200 and the versions of the expense", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id/history")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		handler := NewHandler(&ServiceSuccess{}, logrus.New())
+
+		err := handler.ExpensesHistory(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, `[{"version":1,"action":"insert","actor":"default","changed_at":"0001-01-01T00:00:00Z","changes":{"title":{"from":null,"to":"mockTitle"}}}]`, rec.Body.String())
+		}
+	})
+
+	t.Run("should return http status code = 400 when id is not a number", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id/history")
+		c.SetParamNames("id")
+		c.SetParamValues("abc")
+
+		handler := NewHandler(&ServiceSuccess{}, logrus.New())
+
+		err := handler.ExpensesHistory(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestSearchExpensesAsOfHandler(t *testing.T) {
+	t.Run("should return the expense of service.SearchExpensesAsOf() when as_of is given", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?as_of=2023-01-02T03:04:05%2B07:00", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		service := &ServiceSuccess{}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.SearchExpensesById(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.True(t, service.asOf.Equal(time.Date(2023, 1, 1, 20, 4, 5, 0, time.UTC)))
+			assert.False(t, service.searchExpensesByIdWasCalled)
+			assert.Contains(t, rec.Body.String(), "oldTitle")
+		}
+	})
+
+	t.Run("should return http status code = 400 when as_of is not a RFC 3339 time", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?as_of=yesterday", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		handler := NewHandler(&ServiceSuccess{}, logrus.New())
+
+		err := handler.SearchExpensesById(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should return the status code of the error of service.SearchExpensesAsOf()", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?as_of=2023-01-02T03:04:05Z", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		handler := NewHandler(&ServiceError{statusCodeError: http.StatusNotFound}, logrus.New())
+
+		err := handler.SearchExpensesById(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestRevertExpensesHandler(t *testing.T) {
+	t.Run("should return http status code = 200 and the restored expense", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"version":2}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id/revert")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		service := &ServiceSuccess{}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.RevertExpenses(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, RevertRequest{Version: 2}, service.revert)
+		}
+	})
+
+	t.Run("should return http status code = 400 when the body is not json", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`version=2`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/expenses/:id/revert")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		handler := NewHandler(&ServiceSuccess{}, logrus.New())
+
+		err := handler.RevertExpenses(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestExpensesFormatsHandler(t *testing.T) {
+	t.Run("should read and write xml when Content-Type and Accept are xml", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := "<expense><title>mockTitle</title><amount>10</amount><note>mockNote</note><tags><tag>mockTags</tag></tags></expense>"
+		req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationXML)
+		req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationXML)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		service := &ServiceSuccess{}
+		log := logrus.New()
+		handler := NewHandler(service, log)
+
+		// Act
+		err := handler.AddExpenses(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			resp := ExpensesResponse{}
+			assert.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, int64(1), resp.Id)
+			assert.Equal(t, "mockTitle", resp.Title)
+			assert.Equal(t, float64(10), resp.Amount)
+			assert.Equal(t, []string{"mockTags"}, resp.Tags)
+		}
+	})
+
+	t.Run("should read a csv row and write msgpack", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		body := "title,amount,note,tags\nmockTitle,10,mockNote,\"food,beverage\"\n"
+		req := httptest.NewRequest(http.MethodPut, "/expenses/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, render.MIMETextCSV)
+		req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationMsgpack)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("3")
+
+		service := &ServiceSuccess{}
+		log := logrus.New()
+		handler := NewHandler(service, log)
+
+		// Act
+		err := handler.UpdateExpenses(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, echo.MIMEApplicationMsgpack, rec.Header().Get(echo.HeaderContentType))
+			resp := ExpensesResponse{}
+			dec := msgpack.NewDecoder(rec.Body)
+			dec.SetCustomStructTag("json")
+			assert.NoError(t, dec.Decode(&resp))
+			assert.Equal(t, int64(3), resp.Id)
+			assert.Equal(t, []string{"food", "beverage"}, resp.Tags)
+		}
+	})
+
+	t.Run("should write the expenses as csv rows", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+		req.Header.Set(echo.HeaderAccept, render.MIMETextCSV)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		service := &ServiceSuccess{}
+		log := logrus.New()
+		handler := NewHandler(service, log)
+
+		// Act
+		err := handler.SearchExpensesAll(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+			assert.Len(t, lines, 3)
+			assert.Equal(t, "id,title,amount,note,tags,category_id", lines[0])
+			assert.Equal(t, "1,mockTitle,10,mockNote,mockTags,", lines[1])
+		}
+	})
+
+	t.Run("should return http status code = 415 when the body is of another media type", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader("title=mockTitle"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		service := &ServiceSuccess{}
+		log := logrus.New()
+		handler := NewHandler(service, log)
+
+		// Act
+		err := handler.AddExpenses(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+			assert.False(t, service.addExpensesWasCalled)
+		}
+	})
+
+	t.Run("should return http status code = 406 when no media type is accepted", func(t *testing.T) {
+		// Arrange
+		e := setupRoutes(t, NewMemory())
+		req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+		req.Header.Set(echo.HeaderAccept, "text/html")
+		rec := httptest.NewRecorder()
+
+		// Act
+		e.ServeHTTP(rec, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	})
+	t.Run("should return http status code = 406 when the history is not accepted as json", func(t *testing.T) {
+		// Arrange
+		e := setupRoutes(t, NewMemory())
+		req := httptest.NewRequest(http.MethodGet, "/v1/expenses/1/history", nil)
+		req.Header.Set(echo.HeaderAccept, render.MIMETextCSV)
+		rec := httptest.NewRecorder()
+
+		// Act
+		e.ServeHTTP(rec, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	})
+}
+
+func TestExpensesVersions(t *testing.T) {
+	e := setupRoutes(t, NewMemory())
+	serve := func(method string, path string, body string, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		if version != "" {
+			req.Header.Set(apiversion.Header, version)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := serve(http.MethodPost, "/v2/expenses", `{"title":"mockTitle","amount":10,"note":"mockNote","category_id":null}`, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /v2/expenses status=%d; want %d", rec.Code, http.StatusCreated)
+	}
+
+	t.Run("should write the expense of v2 in an envelope with a string id", func(t *testing.T) {
+		assert.JSONEq(t, `{"data":{"id":"1","title":"mockTitle","amount":10,"note":"mockNote","tags":[]}}`, rec.Body.String())
+		assert.Empty(t, rec.Header().Get(apiversion.HeaderDeprecation))
+
+		rec := serve(http.MethodGet, "/v2/expenses", "", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"data":[{"id":"1","title":"mockTitle","amount":10,"note":"mockNote","tags":[]}]}`, rec.Body.String())
+	})
+
+	t.Run("should write the expense of v1 as before with the deprecation headers", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/v1/expenses/1", "", "")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"id":1,"title":"mockTitle","amount":10,"note":"mockNote","tags":null}`, rec.Body.String())
+		assert.NotEmpty(t, rec.Header().Get(apiversion.HeaderDeprecation))
+		assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", rec.Header().Get(apiversion.HeaderSunset))
+		assert.Equal(t, `</v2/expenses/1>; rel="successor-version"`, rec.Header().Get(apiversion.HeaderLink))
+	})
+
+	t.Run("should serve the unversioned paths by the API-Version header, v1 without it", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/expenses/1", "", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"id":1,"title":"mockTitle","amount":10,"note":"mockNote","tags":null}`, rec.Body.String())
+		assert.NotEmpty(t, rec.Header().Get(apiversion.HeaderDeprecation))
+
+		rec = serve(http.MethodGet, "/expenses/1", "", "2")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"data":{"id":"1","title":"mockTitle","amount":10,"note":"mockNote","tags":[]}}`, rec.Body.String())
+		assert.Empty(t, rec.Header().Get(apiversion.HeaderDeprecation))
+
+		rec = serve(http.MethodGet, "/expenses/1", "", "3")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("should return http status code = 400 when the category id of v2 is not a number", func(t *testing.T) {
+		rec := serve(http.MethodPut, "/v2/expenses/1", `{"title":"mockTitle","amount":10,"category_id":"food"}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("should answer 406 and 415 to v2 for the media types other than json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v2/expenses/1", nil)
+		req.Header.Set(echo.HeaderAccept, render.MIMETextCSV)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+
+		req = httptest.NewRequest(http.MethodPut, "/v2/expenses/1", strings.NewReader(`<expense><title>mockTitle</title></expense>`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationXML)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+}
+
+func TestHandlerV2(t *testing.T) {
+	t.Run("should map the category id of v2 to the service and back", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/v2/expenses", strings.NewReader(`{"title":"mockTitle","amount":10,"category_id":"7"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		service := &ServiceCategory{}
+		log := logrus.New()
+		handler := NewHandlerV2(service, log)
+
+		// Act
+		err := handler.AddExpenses(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.JSONEq(t, `{"data":{"id":"1","title":"mockTitle","amount":10,"note":"","tags":[],"category_id":"7"}}`, rec.Body.String())
+		}
+	})
+
+	t.Run("should map the category ids of the history of v2 to strings", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/v2/expenses/1/history", nil)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		service := &ServiceCategory{}
+		log := logrus.New()
+		handler := NewHandlerV2(service, log)
+
+		// Act
+		err := handler.ExpensesHistory(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, `{"data":[{"version":2,"action":"update","changed_at":"0001-01-01T00:00:00Z","changes":{"category_id":{"from":null,"to":"7"},"title":{"from":"a","to":"b"}}}]}`, rec.Body.String())
+		}
+	})
+
+	t.Run("should return http status code of the service error", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/v2/expenses", nil)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		service := &ServiceError{statusCodeError: http.StatusNotFound}
+		log := logrus.New()
+		handler := NewHandlerV2(service, log)
+
+		// Act
+		err := handler.SearchExpensesAll(c)
+
+		// Assertions
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+// ServiceCategory answers the category of the requests.
+type ServiceCategory struct {
+	ServiceSuccess
+}
+
+func (s *ServiceCategory) AddExpenses(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
+	resp, err := s.ServiceSuccess.AddExpenses(ctx, req)
+	resp.CategoryId = req.CategoryId
+	return resp, err
+}
+
+func (s *ServiceCategory) ExpensesHistory(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	resp := []HistoryResponse{
+		{
+			Version: 2,
+			Action:  ActionUpdate,
+			Changes: map[string]Change{
+				"category_id": {From: json.RawMessage("null"), To: json.RawMessage("7")},
+				"title":       {From: json.RawMessage(`"a"`), To: json.RawMessage(`"b"`)},
+			},
+		},
+	}
+	return resp, nil
+}
@@ -0,0 +1,191 @@
+package expenses
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/render"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/labstack/echo/v4"
+)
+
+// HandlerV2 serves the expenses of the v2 api, in json only: its routes are
+// negotiated with render.Negotiate(echo.MIMEApplicationJSON).
+type HandlerV2 struct {
+	log     common.Log
+	service Services
+}
+
+func NewHandlerV2(s Services, l common.Log) *HandlerV2 {
+	return &HandlerV2{service: s, log: l}
+}
+
+func (h HandlerV2) AddExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "HandlerV2.AddExpenses")
+	defer span.End()
+
+	body := ExpenseRequestV2{}
+	if err := render.Bind(c, &body); errors.Is(err, render.ErrUnsupportedMediaType) {
+		return c.NoContent(http.StatusUnsupportedMediaType)
+	} else if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	req, err := body.request()
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.AddExpenses(ctx, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("HandlerV2 AddExpenses Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return render.Render(c, http.StatusCreated, ExpenseEnvelopeV2{Data: toV2(*resp)})
+}
+
+func (h HandlerV2) SearchExpensesById(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "HandlerV2.SearchExpensesById")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	var resp *ExpensesResponse
+	if asOf := c.QueryParam("as_of"); asOf != "" {
+		var at time.Time
+		if at, err = time.Parse(time.RFC3339Nano, asOf); err != nil {
+			return c.NoContent(http.StatusBadRequest)
+		}
+		resp, err = h.service.SearchExpensesAsOf(ctx, id, at)
+	} else {
+		resp, err = h.service.SearchExpensesById(ctx, id)
+	}
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("HandlerV2 SearchExpensesById Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return render.Render(c, http.StatusOK, ExpenseEnvelopeV2{Data: toV2(*resp)})
+}
+
+func (h HandlerV2) UpdateExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "HandlerV2.UpdateExpenses")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	body := ExpenseRequestV2{}
+	if err := render.Bind(c, &body); errors.Is(err, render.ErrUnsupportedMediaType) {
+		return c.NoContent(http.StatusUnsupportedMediaType)
+	} else if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	req, err := body.request()
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.UpdateExpenses(ctx, id, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("HandlerV2 UpdateExpenses Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return render.Render(c, http.StatusOK, ExpenseEnvelopeV2{Data: toV2(*resp)})
+}
+
+func (h HandlerV2) SearchExpensesAll(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "HandlerV2.SearchExpensesAll")
+	defer span.End()
+
+	filter := Filter{}
+	for _, tag := range strings.Split(c.QueryParam("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	resp, err := h.service.SearchExpensesAll(ctx, filter)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("HandlerV2 SearchExpensesAll Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return render.Render(c, http.StatusOK, toListV2(resp))
+}
+
+func (h HandlerV2) ExpensesHistory(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "HandlerV2.ExpensesHistory")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.ExpensesHistory(ctx, id)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("HandlerV2 ExpensesHistory Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	list, err := toHistoryListV2(resp)
+	if err != nil {
+		h.log.Errorf("HandlerV2 ExpensesHistory Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return render.Render(c, http.StatusOK, list)
+}
+
+func (h HandlerV2) RevertExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "HandlerV2.RevertExpenses")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	req := RevertRequest{}
+	if err := render.Bind(c, &req); errors.Is(err, render.ErrUnsupportedMediaType) {
+		return c.NoContent(http.StatusUnsupportedMediaType)
+	} else if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.RevertExpenses(ctx, id, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("HandlerV2 RevertExpenses Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return render.Render(c, http.StatusOK, ExpenseEnvelopeV2{Data: toV2(*resp)})
+}
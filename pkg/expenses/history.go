@@ -0,0 +1,106 @@
+package expenses
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+)
+
+// The actions of the history.
+const (
+	ActionInsert = "insert"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionRevert = "revert"
+)
+
+// historyFields are the json names of the audited fields with their values.
+func historyFields(exp *ExpensesResponse) map[string]any {
+	if exp == nil {
+		return map[string]any{}
+	}
+	return map[string]any{
+		"title":       exp.Title,
+		"amount":      exp.Amount,
+		"note":        exp.Note,
+		"tags":        exp.Tags,
+		"category_id": exp.CategoryId,
+	}
+}
+
+// diff returns the fields that differ between before and after, a nil
+// expense has every field null.
+func diff(before *ExpensesResponse, after *ExpensesResponse) (map[string]Change, error) {
+	from, to := historyFields(before), historyFields(after)
+	changes := map[string]Change{}
+	for _, field := range []string{"title", "amount", "note", "tags", "category_id"} {
+		f, err := json.Marshal(from[field])
+		if err != nil {
+			return nil, err
+		}
+		t, err := json.Marshal(to[field])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(f, t) {
+			changes[field] = Change{From: f, To: t}
+		}
+	}
+	return changes, nil
+}
+
+// historyTargets are the fields of exp by their json names, for replay.
+func historyTargets(exp *ExpensesResponse) map[string]any {
+	return map[string]any{
+		"title":       &exp.Title,
+		"amount":      &exp.Amount,
+		"note":        &exp.Note,
+		"tags":        &exp.Tags,
+		"category_id": &exp.CategoryId,
+	}
+}
+
+// replay applies the versions of history in order and returns the expense
+// id as it was after the last of them, nil when it did not exist then.
+func replay(id int64, history []HistoryResponse) (*ExpensesResponse, error) {
+	var exp *ExpensesResponse
+	for _, version := range history {
+		if version.Action == ActionDelete {
+			exp = nil
+			continue
+		}
+		if exp == nil {
+			exp = &ExpensesResponse{Id: id}
+		}
+		targets := historyTargets(exp)
+		for field, change := range version.Changes {
+			target, ok := targets[field]
+			if !ok {
+				continue
+			}
+			if err := json.Unmarshal(change.To, target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return exp, nil
+}
+
+// revertTo returns the request restoring the expense id to the version of
+// history, common.ErrInvalid when the version does not exist or the expense
+// was deleted by it.
+func revertTo(id int64, history []HistoryResponse, version int64) (ExpensesRequest, error) {
+	if version < 1 || version > int64(len(history)) {
+		return ExpensesRequest{}, fmt.Errorf("%w: unknown version %d", common.ErrInvalid, version)
+	}
+	exp, err := replay(id, history[:version])
+	if err != nil {
+		return ExpensesRequest{}, err
+	}
+	if exp == nil {
+		return ExpensesRequest{}, fmt.Errorf("%w: version %d is deleted", common.ErrInvalid, version)
+	}
+	return ExpensesRequest{Title: exp.Title, Amount: exp.Amount, Note: exp.Note, Tags: exp.Tags, CategoryId: exp.CategoryId}, nil
+}
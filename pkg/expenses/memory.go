@@ -0,0 +1,238 @@
+package expenses
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tags"
+)
+
+// Memory is a Storage kept in process memory, for tests and local
+// development without a database. Everything is lost on restart and no event
+// is added to an outbox.
+type Memory struct {
+	mu       sync.RWMutex
+	lastId   int64
+	expenses map[int64]ExpensesResponse
+	history  map[int64][]HistoryResponse
+}
+
+func NewMemory() *Memory {
+	return &Memory{expenses: map[int64]ExpensesResponse{}, history: map[int64][]HistoryResponse{}}
+}
+
+// addHistory records the change of the expense id from before to after with
+// the actor and request id of ctx, m.mu must be held.
+func (m *Memory) addHistory(ctx context.Context, id int64, action string, before *ExpensesResponse, after *ExpensesResponse) error {
+	changes, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+	m.history[id] = append(m.history[id], HistoryResponse{
+		Version:   int64(len(m.history[id]) + 1),
+		Action:    action,
+		Actor:     common.Actor(ctx),
+		RequestId: common.RequestId(ctx),
+		ChangedAt: time.Now().UTC(),
+		Changes:   changes,
+	})
+	return nil
+}
+
+// copyTags keeps the stored tags from being shared with callers.
+func copyTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	return append([]string{}, tags...)
+}
+
+func (m *Memory) Insert(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if req.CategoryId != nil {
+		return nil, common.ErrUnsupported
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastId++
+	exp := ExpensesResponse{Id: m.lastId, Title: req.Title, Amount: req.Amount, Note: req.Note, Tags: copyTags(req.Tags)}
+	if err := m.addHistory(ctx, exp.Id, ActionInsert, nil, &exp); err != nil {
+		return nil, err
+	}
+	m.expenses[exp.Id] = exp
+
+	exp.Tags = copyTags(exp.Tags)
+	return &exp, nil
+}
+
+func (m *Memory) SearchById(ctx context.Context, id int64) (*ExpensesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	exp, ok := m.expenses[id]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+	exp.Tags = copyTags(exp.Tags)
+	return &exp, nil
+}
+
+func (m *Memory) Update(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if req.CategoryId != nil {
+		return nil, common.ErrUnsupported
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, ok := m.expenses[id]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+	exp := ExpensesResponse{Id: id, Title: req.Title, Amount: req.Amount, Note: req.Note, Tags: copyTags(req.Tags)}
+	if err := m.addHistory(ctx, id, ActionUpdate, &before, &exp); err != nil {
+		return nil, err
+	}
+	m.expenses[id] = exp
+
+	exp.Tags = copyTags(exp.Tags)
+	return &exp, nil
+}
+
+// Delete removes the expense, its history is kept.
+func (m *Memory) Delete(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, ok := m.expenses[id]
+	if !ok {
+		return common.ErrNotFound
+	}
+	if err := m.addHistory(ctx, id, ActionDelete, &before, nil); err != nil {
+		return err
+	}
+	delete(m.expenses, id)
+	return nil
+}
+
+// History returns the versions of the expense, the oldest first, also after
+// the expense was deleted.
+func (m *Memory) History(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history, ok := m.history[id]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+	return append([]HistoryResponse{}, history...), nil
+}
+
+// SearchAsOf returns the expense as it was at the moment at, replayed from
+// its history.
+func (m *Memory) SearchAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := []HistoryResponse{}
+	for _, version := range m.history[id] {
+		if !version.ChangedAt.After(at) {
+			history = append(history, version)
+		}
+	}
+	exp, err := replay(id, history)
+	if err != nil {
+		return nil, err
+	}
+	if exp == nil {
+		return nil, common.ErrNotFound
+	}
+	exp.Tags = copyTags(exp.Tags)
+	return exp, nil
+}
+
+// Revert restores the expense to its state at version and records it as a
+// new version.
+func (m *Memory) Revert(ctx context.Context, id int64, version int64) (*ExpensesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, ok := m.expenses[id]
+	if !ok {
+		return nil, common.ErrNotFound
+	}
+	req, err := revertTo(id, m.history[id], version)
+	if err != nil {
+		return nil, err
+	}
+	if req.CategoryId != nil {
+		return nil, common.ErrUnsupported
+	}
+	exp := ExpensesResponse{Id: id, Title: req.Title, Amount: req.Amount, Note: req.Note, Tags: copyTags(req.Tags)}
+	if err := m.addHistory(ctx, id, ActionRevert, &before, &exp); err != nil {
+		return nil, err
+	}
+	m.expenses[id] = exp
+
+	exp.Tags = copyTags(exp.Tags)
+	return &exp, nil
+}
+
+func (m *Memory) SearchAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := []ExpensesResponse{}
+	for _, exp := range m.expenses {
+		if !hasTags(exp.Tags, filter.Tags) {
+			continue
+		}
+		exp.Tags = copyTags(exp.Tags)
+		result = append(result, exp)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
+
+// hasTags reports whether names contains every tag of want.
+func hasTags(names []string, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, n := range names {
+			if tags.Key(n) == tags.Key(w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	runStorageConformance(t, func(t *testing.T) Storage {
+		return NewMemory()
+	})
+}
+
+func TestMemoryConcurrentInsert(t *testing.T) {
+	t.Run("should assign unique ids when inserting concurrently", func(t *testing.T) {
+		storage := NewMemory()
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				storage.Insert(context.Background(), ExpensesRequest{Title: "concurrent"})
+			}()
+		}
+		wg.Wait()
+
+		got, err := storage.SearchAll(context.Background(), Filter{})
+
+		assert.NoError(t, err)
+		assert.Len(t, got, 50)
+		assert.Equal(t, int64(50), got[49].Id)
+	})
+}
+
+func TestMemoryCategory(t *testing.T) {
+	t.Run("should return common.ErrUnsupported for an expense with a category", func(t *testing.T) {
+		storage := NewMemory()
+		category := int64(1)
+
+		got, err := storage.Insert(context.Background(), ExpensesRequest{Title: "flight", CategoryId: &category})
+
+		assert.ErrorIs(t, err, common.ErrUnsupported)
+		assert.Nil(t, got)
+	})
+}
@@ -1,8 +1,23 @@
 package expenses
 
+// ExpensesRequest is read from json, xml, csv or msgpack, the tags are
+// <tags><tag> elements in xml and a comma separated cell in csv.
 type ExpensesRequest struct {
-	Title  string   `json:"title"`
-	Amount float64  `json:"amount"`
-	Note   string   `json:"note"`
-	Tags   []string `json:"tags"`
+	Title  string   `json:"title" xml:"title"`
+	Amount float64  `json:"amount" xml:"amount"`
+	Note   string   `json:"note" xml:"note"`
+	Tags   []string `json:"tags" xml:"tags>tag"`
+	// CategoryId is only supported by the postgres backend.
+	CategoryId *int64 `json:"category_id" xml:"category_id"`
+}
+
+// Filter narrows SearchAll. An expense matches when it has every tag of Tags,
+// tags are compared by tags.Key.
+type Filter struct {
+	Tags []string
+}
+
+// RevertRequest names the version of the history an expense is restored to.
+type RevertRequest struct {
+	Version int64 `json:"version" xml:"version"`
 }
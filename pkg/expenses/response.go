@@ -1,9 +1,35 @@
 package expenses
 
+import (
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
+
+// ExpensesResponse is written in json, xml, csv or msgpack, an <expense>
+// element in xml.
 type ExpensesResponse struct {
-	Id     int64    `json:"id"`
-	Title  string   `json:"title"`
-	Amount float64  `json:"amount"`
-	Note   string   `json:"note"`
-	Tags   []string `json:"tags"`
+	XMLName    xml.Name `json:"-" xml:"expense"`
+	Id         int64    `json:"id" xml:"id"`
+	Title      string   `json:"title" xml:"title"`
+	Amount     float64  `json:"amount" xml:"amount"`
+	Note       string   `json:"note" xml:"note"`
+	Tags       []string `json:"tags" xml:"tags>tag"`
+	CategoryId *int64   `json:"category_id,omitempty" xml:"category_id,omitempty"`
+}
+
+// HistoryResponse is a version of an expense. Changes maps the json name of
+// each changed field to its values before and after the change.
+type HistoryResponse struct {
+	Version   int64             `json:"version"`
+	Action    string            `json:"action"`
+	Actor     string            `json:"actor,omitempty"`
+	RequestId string            `json:"request_id,omitempty"`
+	ChangedAt time.Time         `json:"changed_at"`
+	Changes   map[string]Change `json:"changes"`
+}
+
+type Change struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
 }
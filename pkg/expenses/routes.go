@@ -1,17 +1,157 @@
 package expenses
 
 import (
+	"net/http"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/apiversion"
 	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/render"
 	"github.com/labstack/echo/v4"
 )
 
-func Routes(echo *echo.Echo, ins *config.Instance) {
-	expenDb := New(ins.DB)
-	expenService := NewService(expenDb, ins.Log)
-	expenHandler := NewHandler(expenService, ins.Log)
+// operationsV1 describe the routes of the v1 api, the expenses are also read
+// and written as xml, csv and msgpack. Their history, of json changes, is
+// json only.
+var operationsV1 = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/expenses", Id: "AddExpenses", Formats: render.Formats, Summary: "Create an expense", Tag: "expenses",
+		Body: ExpensesRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusCreated, Description: "the created expense", Body: ExpensesResponse{}},
+			openapi.BadRequest,
+		}},
+	{Method: http.MethodGet, Path: "/expenses/:id", Id: "SearchExpensesById", Formats: render.Formats, Summary: "Read an expense, as it was at as_of when set", Tag: "expenses",
+		Params: []openapi.Param{
+			{Name: "as_of", In: "query", Description: "RFC 3339 time of the version to read, an expense created before the history was added has no version before it", Schema: time.Time{}},
+		}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the expense", Body: ExpensesResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodPut, Path: "/expenses/:id", Id: "UpdateExpenses", Formats: render.Formats, Summary: "Replace an expense", Tag: "expenses",
+		Body: ExpensesRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the updated expense", Body: ExpensesResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodGet, Path: "/expenses", Id: "SearchExpensesAll", Formats: render.Formats, Summary: "List the expenses", Tag: "expenses",
+		Params: []openapi.Param{
+			{Name: "tags", In: "query", Description: "comma separated tags an expense must all have", Schema: ""},
+		}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the expenses", Body: []ExpensesResponse{}},
+		}},
+	{Method: http.MethodDelete, Path: "/expenses/:id", Id: "DeleteExpenses", Summary: "Delete an expense, once its attachments are deleted", Tag: "expenses",
+		Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "deleted"},
+			openapi.BadRequest, openapi.NotFound,
+			{Status: http.StatusConflict, Description: "the expense has attachments"},
+		}},
+	{Method: http.MethodGet, Path: "/expenses/:id/history", Id: "ExpensesHistory", Summary: "List the versions of an expense", Tag: "expenses",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the versions, oldest first", Body: []HistoryResponse{}},
+			openapi.BadRequest, openapi.NotFound, openapi.NotAcceptable,
+		}},
+	{Method: http.MethodPost, Path: "/expenses/:id/revert", Id: "RevertExpenses", Formats: render.Formats, Summary: "Restore a version of an expense", Tag: "expenses",
+		Body: RevertRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the restored expense", Body: ExpensesResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+}
+
+// operationsV2 describe the routes of the v2 api.
+var operationsV2 = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/expenses", Id: "AddExpenses", Summary: "Create an expense", Tag: "expenses",
+		Body: ExpenseRequestV2{}, Responses: []openapi.Response{
+			{Status: http.StatusCreated, Description: "the created expense", Body: ExpenseEnvelopeV2{}},
+			openapi.BadRequest, openapi.NotAcceptable, openapi.UnsupportedMediaType,
+		}},
+	{Method: http.MethodGet, Path: "/expenses/:id", Id: "SearchExpensesById", Summary: "Read an expense, as it was at as_of when set", Tag: "expenses",
+		Params: []openapi.Param{
+			{Name: "as_of", In: "query", Description: "RFC 3339 time of the version to read, an expense created before the history was added has no version before it", Schema: time.Time{}},
+		}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the expense", Body: ExpenseEnvelopeV2{}},
+			openapi.BadRequest, openapi.NotFound, openapi.NotAcceptable,
+		}},
+	{Method: http.MethodPut, Path: "/expenses/:id", Id: "UpdateExpenses", Summary: "Replace an expense", Tag: "expenses",
+		Body: ExpenseRequestV2{}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the updated expense", Body: ExpenseEnvelopeV2{}},
+			openapi.BadRequest, openapi.NotFound, openapi.NotAcceptable, openapi.UnsupportedMediaType,
+		}},
+	{Method: http.MethodGet, Path: "/expenses", Id: "SearchExpensesAll", Summary: "List the expenses", Tag: "expenses",
+		Params: []openapi.Param{
+			{Name: "tags", In: "query", Description: "comma separated tags an expense must all have", Schema: ""},
+		}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the expenses", Body: ExpenseListV2{}},
+			openapi.NotAcceptable,
+		}},
+	{Method: http.MethodDelete, Path: "/expenses/:id", Id: "DeleteExpenses", Summary: "Delete an expense, once its attachments are deleted", Tag: "expenses",
+		Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "deleted"},
+			openapi.BadRequest, openapi.NotFound,
+			{Status: http.StatusConflict, Description: "the expense has attachments"},
+		}},
+	{Method: http.MethodGet, Path: "/expenses/:id/history", Id: "ExpensesHistory", Summary: "List the versions of an expense", Tag: "expenses",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the versions, oldest first", Body: HistoryListV2{}},
+			openapi.BadRequest, openapi.NotFound, openapi.NotAcceptable,
+		}},
+	{Method: http.MethodPost, Path: "/expenses/:id/revert", Id: "RevertExpenses", Summary: "Restore a version of an expense", Tag: "expenses",
+		Body: RevertRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the restored expense", Body: ExpenseEnvelopeV2{}},
+			openapi.BadRequest, openapi.NotFound, openapi.NotAcceptable, openapi.UnsupportedMediaType,
+		}},
+}
+
+// Operations describe the routes of Routes in the OpenAPI document, the v1
+// ones deprecated when versions deprecates v1.
+func Operations(versions apiversion.Versions) []openapi.Operation {
+	return append(append(
+		apiversion.Operations(1, versions.Deprecated(1), operationsV1),
+		apiversion.Operations(2, versions.Deprecated(2), operationsV2)...),
+		apiversion.Unversioned(1, map[int][]openapi.Operation{1: operationsV1, 2: operationsV2})...)
+}
+
+// route is a route of the expenses in both versions.
+type route struct {
+	method string
+	path   string
+	v1     echo.HandlerFunc
+	v2     echo.HandlerFunc
+}
+
+// routes serves v1 with its deprecation headers, by the media type of the
+// request and the Accept header when it renders expenses, and the history and
+// v2 in json only.
+func routes(v1 *Handler, v2 *HandlerV2, deprecated echo.MiddlewareFunc) []route {
+	negotiate := render.Negotiate()
+	json := render.Negotiate(echo.MIMEApplicationJSON)
+	return []route{
+		{http.MethodPost, "/expenses", deprecated(negotiate(v1.AddExpenses)), json(v2.AddExpenses)},
+		{http.MethodGet, "/expenses/:id", deprecated(negotiate(v1.SearchExpensesById)), json(v2.SearchExpensesById)},
+		{http.MethodPut, "/expenses/:id", deprecated(negotiate(v1.UpdateExpenses)), json(v2.UpdateExpenses)},
+		{http.MethodGet, "/expenses", deprecated(negotiate(v1.SearchExpensesAll)), json(v2.SearchExpensesAll)},
+		{http.MethodDelete, "/expenses/:id", deprecated(v1.DeleteExpenses), v1.DeleteExpenses},
+		{http.MethodGet, "/expenses/:id/history", deprecated(json(v1.ExpensesHistory)), json(v2.ExpensesHistory)},
+		{http.MethodPost, "/expenses/:id/revert", deprecated(negotiate(v1.RevertExpenses)), json(v2.RevertExpenses)},
+	}
+}
+
+// unversioned serves r by the API-Version header, v1 without it.
+func (r route) unversioned() echo.HandlerFunc {
+	return apiversion.Select(1, map[int]echo.HandlerFunc{1: r.v1, 2: r.v2})
+}
+
+// Routes serves the expenses of storage under /v1 and /v2, and on the
+// unversioned paths by the API-Version header. The Service is returned for the
+// other apis to serve the same expenses.
+func Routes(echo *echo.Echo, ins *config.Instance, storage Storage) *Service {
+	expenService := NewService(storage, ins.Log)
+	deprecated := apiversion.DeprecateV1(ins.Config.Get())
 
-	echo.POST("/expenses", expenHandler.AddExpenses)
-	echo.GET("/expenses/:id", expenHandler.SearchExpensesById)
-	echo.PUT("/expenses/:id", expenHandler.UpdateExpenses)
-	echo.GET("/expenses", expenHandler.SearchExpensesAll)
+	v1 := echo.Group(apiversion.Prefix(1))
+	v2 := echo.Group(apiversion.Prefix(2))
+	for _, r := range routes(NewHandler(expenService, ins.Log), NewHandlerV2(expenService, ins.Log), deprecated) {
+		v1.Add(r.method, r.path, r.v1)
+		v2.Add(r.method, r.path, r.v2)
+		echo.Add(r.method, r.path, r.unversioned())
+	}
+	return expenService
 }
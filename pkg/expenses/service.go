@@ -1,16 +1,32 @@
 package expenses
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tags"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
 )
 
 type Storage interface {
-	Insert(req ExpensesRequest) (*ExpensesResponse, error)
-	SearchById(id int64) (*ExpensesResponse, error)
-	Update(id int64, req ExpensesRequest) (*ExpensesResponse, error)
-	SearchAll() ([]ExpensesResponse, error)
+	Insert(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error)
+	SearchById(ctx context.Context, id int64) (*ExpensesResponse, error)
+	Update(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error)
+	SearchAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error)
+	Delete(ctx context.Context, id int64) error
+	// History returns the versions of the expense, the oldest first, and
+	// common.ErrNotFound when the expense never existed.
+	History(ctx context.Context, id int64) ([]HistoryResponse, error)
+	// SearchAsOf returns the expense as it was at the moment at and
+	// common.ErrNotFound when it did not exist then. The history of an
+	// expense created before the history existed starts when the history
+	// was added, the expense is not found before.
+	SearchAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error)
+	// Revert restores the expense to its state at version of its history.
+	Revert(ctx context.Context, id int64, version int64) (*ExpensesResponse, error)
 }
 
 type Service struct {
@@ -22,38 +38,112 @@ func NewService(s Storage, l common.Log) *Service {
 	return &Service{storage: s, log: l}
 }
 
-func (s Service) AddExpenses(req ExpensesRequest) (*ExpensesResponse, error) {
-	resp, err := s.storage.Insert(req)
+func (s Service) AddExpenses(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.AddExpenses")
+	defer span.End()
+
+	req.Tags = tags.Normalize(req.Tags)
+	resp, err := s.storage.Insert(ctx, req)
 	if err != nil {
+		tracing.RecordError(ctx, err)
 		s.log.Errorf("Insert Expenses Error : %s", err)
-		return nil, &common.Error{Code: http.StatusInternalServerError, Desc: "Insert Expenses Error", OriginalError: err}
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Insert Expenses Error", OriginalError: err}
 	}
+	metrics.ExpenseCreated(resp.Amount)
 	return resp, nil
 }
 
-func (s Service) SearchExpensesById(id int64) (*ExpensesResponse, error) {
-	resp, err := s.storage.SearchById(id)
+func (s Service) SearchExpensesById(ctx context.Context, id int64) (*ExpensesResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.SearchExpensesById")
+	defer span.End()
+
+	resp, err := s.storage.SearchById(ctx, id)
 	if err != nil {
+		tracing.RecordError(ctx, err)
 		s.log.Errorf("Search Expenses By Id Error : %s", err)
-		return nil, &common.Error{Code: http.StatusInternalServerError, Desc: "Search Expenses By Id Error", OriginalError: err}
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Search Expenses By Id Error", OriginalError: err}
 	}
 	return resp, nil
 }
 
-func (s Service) UpdateExpenses(id int64, req ExpensesRequest) (*ExpensesResponse, error) {
-	resp, err := s.storage.Update(id, req)
+func (s Service) UpdateExpenses(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateExpenses")
+	defer span.End()
+
+	req.Tags = tags.Normalize(req.Tags)
+	resp, err := s.storage.Update(ctx, id, req)
 	if err != nil {
+		tracing.RecordError(ctx, err)
 		s.log.Errorf("Update Expenses Error : %s", err)
-		return nil, &common.Error{Code: http.StatusInternalServerError, Desc: "Update Expenses Error", OriginalError: err}
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Update Expenses Error", OriginalError: err}
 	}
 	return resp, nil
 }
 
-func (s Service) SearchExpensesAll() ([]ExpensesResponse, error) {
-	resp, err := s.storage.SearchAll()
+func (s Service) SearchExpensesAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.SearchExpensesAll")
+	defer span.End()
+
+	filter.Tags = tags.Normalize(filter.Tags)
+	resp, err := s.storage.SearchAll(ctx, filter)
 	if err != nil {
+		tracing.RecordError(ctx, err)
 		s.log.Errorf("Search Expenses All Error: %s", err)
-		return nil, &common.Error{Code: http.StatusInternalServerError, Desc: "Search Expenses All Error", OriginalError: err}
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Search Expenses All Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) DeleteExpenses(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteExpenses")
+	defer span.End()
+
+	if err := s.storage.Delete(ctx, id); err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Delete Expenses Error : %s", err)
+		return &common.Error{Code: common.StatusFromError(err), Desc: "Delete Expenses Error", OriginalError: err}
+	}
+	return nil
+}
+
+func (s Service) ExpensesHistory(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.ExpensesHistory")
+	defer span.End()
+
+	resp, err := s.storage.History(ctx, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Expenses History Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Expenses History Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) SearchExpensesAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.SearchExpensesAsOf")
+	defer span.End()
+
+	resp, err := s.storage.SearchAsOf(ctx, id, at)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Search Expenses As Of Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Search Expenses As Of Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) RevertExpenses(ctx context.Context, id int64, req RevertRequest) (*ExpensesResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.RevertExpenses")
+	defer span.End()
+
+	if req.Version < 1 {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "Revert Expenses Error : version must be positive"}
+	}
+	resp, err := s.storage.Revert(ctx, id, req.Version)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Revert Expenses Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Revert Expenses Error", OriginalError: err}
 	}
 	return resp, nil
 }
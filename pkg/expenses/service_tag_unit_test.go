@@ -3,7 +3,12 @@
 package expenses
 
 import (
+	"context"
+	"net/http"
 	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
 
 	"github.com/stretchr/testify/assert"
 
@@ -15,9 +20,11 @@ type DBCaseSuccess struct {
 	searchByIdWasCalled bool
 	updateWasCalled     bool
 	searchAllWasCalled  bool
+	deleteWasCalled     bool
+	revertWasCalled     bool
 }
 
-func (db *DBCaseSuccess) Insert(req ExpensesRequest) (*ExpensesResponse, error) {
+func (db *DBCaseSuccess) Insert(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
 	db.insertWasCalled = true
 	resp := &ExpensesResponse{
 		Id:     1,
@@ -29,7 +36,7 @@ func (db *DBCaseSuccess) Insert(req ExpensesRequest) (*ExpensesResponse, error)
 	return resp, nil
 }
 
-func (db *DBCaseSuccess) SearchById(id int64) (*ExpensesResponse, error) {
+func (db *DBCaseSuccess) SearchById(ctx context.Context, id int64) (*ExpensesResponse, error) {
 	db.searchByIdWasCalled = true
 	resp := &ExpensesResponse{
 		Id:     id,
@@ -41,7 +48,7 @@ func (db *DBCaseSuccess) SearchById(id int64) (*ExpensesResponse, error) {
 	return resp, nil
 }
 
-func (db *DBCaseSuccess) Update(id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+func (db *DBCaseSuccess) Update(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
 	db.updateWasCalled = true
 	resp := &ExpensesResponse{
 		Id:     id,
@@ -53,7 +60,7 @@ func (db *DBCaseSuccess) Update(id int64, req ExpensesRequest) (*ExpensesRespons
 	return resp, nil
 }
 
-func (db *DBCaseSuccess) SearchAll() ([]ExpensesResponse, error) {
+func (db *DBCaseSuccess) SearchAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
 	db.searchAllWasCalled = true
 	resp := []ExpensesResponse{
 		{
@@ -74,6 +81,24 @@ func (db *DBCaseSuccess) SearchAll() ([]ExpensesResponse, error) {
 	return resp, nil
 }
 
+func (db *DBCaseSuccess) Delete(ctx context.Context, id int64) error {
+	db.deleteWasCalled = true
+	return nil
+}
+
+func (db *DBCaseSuccess) History(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	return []HistoryResponse{{Version: 1, Action: ActionInsert}}, nil
+}
+
+func (db *DBCaseSuccess) SearchAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error) {
+	return &ExpensesResponse{Id: id, Title: "oldTitle"}, nil
+}
+
+func (db *DBCaseSuccess) Revert(ctx context.Context, id int64, version int64) (*ExpensesResponse, error) {
+	db.revertWasCalled = true
+	return &ExpensesResponse{Id: id, Title: "oldTitle"}, nil
+}
+
 type Err struct {
 	msg string
 }
@@ -87,26 +112,50 @@ type DBCaseError struct {
 	searchByIdWasCalled bool
 	updateWasCalled     bool
 	searchAllWasCalled  bool
+	err                 error
 }
 
-func (db *DBCaseError) Insert(req ExpensesRequest) (*ExpensesResponse, error) {
+func (db *DBCaseError) error() error {
+	if db.err != nil {
+		return db.err
+	}
+	return &Err{}
+}
+
+func (db *DBCaseError) Insert(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
 	db.insertWasCalled = true
-	return nil, &Err{}
+	return nil, db.error()
 }
 
-func (db *DBCaseError) SearchById(id int64) (*ExpensesResponse, error) {
+func (db *DBCaseError) SearchById(ctx context.Context, id int64) (*ExpensesResponse, error) {
 	db.searchByIdWasCalled = true
-	return nil, &Err{}
+	return nil, db.error()
 }
 
-func (db *DBCaseError) Update(id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+func (db *DBCaseError) Update(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
 	db.updateWasCalled = true
-	return nil, &Err{}
+	return nil, db.error()
 }
 
-func (db *DBCaseError) SearchAll() ([]ExpensesResponse, error) {
+func (db *DBCaseError) SearchAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
 	db.searchAllWasCalled = true
-	return nil, &Err{}
+	return nil, db.error()
+}
+
+func (db *DBCaseError) Delete(ctx context.Context, id int64) error {
+	return db.error()
+}
+
+func (db *DBCaseError) History(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	return nil, db.error()
+}
+
+func (db *DBCaseError) SearchAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error) {
+	return nil, db.error()
+}
+
+func (db *DBCaseError) Revert(ctx context.Context, id int64, version int64) (*ExpensesResponse, error) {
+	return nil, db.error()
 }
 
 func TestAddExpenses(t *testing.T) {
@@ -121,7 +170,7 @@ func TestAddExpenses(t *testing.T) {
 			Tags:   []string{"mockTags"},
 		}
 
-		resp, err := service.AddExpenses(req)
+		resp, err := service.AddExpenses(context.Background(), req)
 
 		assert.Equal(t, true, storage.insertWasCalled)
 		assert.NotNil(t, resp)
@@ -133,6 +182,17 @@ func TestAddExpenses(t *testing.T) {
 		assert.Equal(t, req.Tags, resp.Tags)
 	})
 
+	t.Run("should pass normalized tags to storage.Insert()", func(t *testing.T) {
+		storage := &DBCaseSuccess{}
+		service := NewService(storage, logrus.New())
+		req := ExpensesRequest{Title: "mockTitle", Tags: []string{" Food ", "food", "street  food", ""}}
+
+		resp, err := service.AddExpenses(context.Background(), req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Food", "street food"}, resp.Tags)
+	})
+
 	t.Run("should return error when  error that storage.Insert()", func(t *testing.T) {
 		storage := &DBCaseError{}
 		log := logrus.New()
@@ -144,12 +204,37 @@ func TestAddExpenses(t *testing.T) {
 			Tags:   []string{"mockTags"},
 		}
 
-		resp, err := service.AddExpenses(req)
+		resp, err := service.AddExpenses(context.Background(), req)
 
 		assert.Equal(t, true, storage.insertWasCalled)
 		assert.NotNil(t, err)
 		assert.Nil(t, resp)
 	})
+	t.Run("should return error code 504 when storage.Insert() exceeds its deadline", func(t *testing.T) {
+		storage := &DBCaseError{err: context.DeadlineExceeded}
+		log := logrus.New()
+		service := NewService(storage, log)
+
+		resp, err := service.AddExpenses(context.Background(), ExpensesRequest{Title: "mockTitle"})
+
+		cmErr := &common.Error{}
+		assert.ErrorAs(t, err, &cmErr)
+		assert.Equal(t, http.StatusGatewayTimeout, cmErr.Code)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("should return error code 499 when request is cancelled during storage.Insert()", func(t *testing.T) {
+		storage := &DBCaseError{err: context.Canceled}
+		log := logrus.New()
+		service := NewService(storage, log)
+
+		resp, err := service.AddExpenses(context.Background(), ExpensesRequest{Title: "mockTitle"})
+
+		cmErr := &common.Error{}
+		assert.ErrorAs(t, err, &cmErr)
+		assert.Equal(t, common.StatusClientClosedRequest, cmErr.Code)
+		assert.Nil(t, resp)
+	})
 }
 
 func TestSearchExpensesById(t *testing.T) {
@@ -159,7 +244,7 @@ func TestSearchExpensesById(t *testing.T) {
 		service := NewService(storage, log)
 		id := int64(43)
 
-		resp, err := service.SearchExpensesById(id)
+		resp, err := service.SearchExpensesById(context.Background(), id)
 
 		assert.Equal(t, true, storage.searchByIdWasCalled)
 		assert.NotNil(t, resp)
@@ -177,7 +262,7 @@ func TestSearchExpensesById(t *testing.T) {
 		service := NewService(storage, log)
 		id := int64(43)
 
-		resp, err := service.SearchExpensesById(id)
+		resp, err := service.SearchExpensesById(context.Background(), id)
 
 		assert.Equal(t, true, storage.searchByIdWasCalled)
 		assert.NotNil(t, err)
@@ -185,6 +270,21 @@ func TestSearchExpensesById(t *testing.T) {
 	})
 }
 
+func TestSearchExpensesByIdNotFound(t *testing.T) {
+	t.Run("should return error code 404 when storage.SearchById() does not find the expense", func(t *testing.T) {
+		storage := &DBCaseError{err: common.ErrNotFound}
+		log := logrus.New()
+		service := NewService(storage, log)
+
+		resp, err := service.SearchExpensesById(context.Background(), 43)
+
+		cmErr := &common.Error{}
+		assert.ErrorAs(t, err, &cmErr)
+		assert.Equal(t, http.StatusNotFound, cmErr.Code)
+		assert.Nil(t, resp)
+	})
+}
+
 func TestUpdateExpenses(t *testing.T) {
 	t.Run("should return ExpensesResponse when no error that storage.Update()", func(t *testing.T) {
 		storage := &DBCaseSuccess{}
@@ -198,7 +298,7 @@ func TestUpdateExpenses(t *testing.T) {
 			Tags:   []string{"mockTags"},
 		}
 
-		resp, err := service.UpdateExpenses(id, req)
+		resp, err := service.UpdateExpenses(context.Background(), id, req)
 
 		assert.Equal(t, true, storage.updateWasCalled)
 		assert.NotNil(t, resp)
@@ -222,7 +322,7 @@ func TestUpdateExpenses(t *testing.T) {
 			Tags:   []string{"mockTags"},
 		}
 
-		resp, err := service.UpdateExpenses(id, req)
+		resp, err := service.UpdateExpenses(context.Background(), id, req)
 
 		assert.Equal(t, true, storage.updateWasCalled)
 		assert.NotNil(t, err)
@@ -236,7 +336,7 @@ func TestSearchExpensesAll(t *testing.T) {
 		log := logrus.New()
 		service := NewService(storage, log)
 
-		resp, err := service.SearchExpensesAll()
+		resp, err := service.SearchExpensesAll(context.Background(), Filter{})
 
 		assert.Equal(t, true, storage.searchAllWasCalled)
 		assert.NotNil(t, resp)
@@ -249,10 +349,89 @@ func TestSearchExpensesAll(t *testing.T) {
 		log := logrus.New()
 		service := NewService(storage, log)
 
-		resp, err := service.SearchExpensesAll()
+		resp, err := service.SearchExpensesAll(context.Background(), Filter{})
 
 		assert.Equal(t, true, storage.searchAllWasCalled)
 		assert.NotNil(t, err)
 		assert.Nil(t, resp)
 	})
 }
+
+func TestDeleteExpenses(t *testing.T) {
+	t.Run("should call storage.Delete()", func(t *testing.T) {
+		storage := &DBCaseSuccess{}
+		service := NewService(storage, logrus.New())
+
+		err := service.DeleteExpenses(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.True(t, storage.deleteWasCalled)
+	})
+
+	t.Run("should return http status code = 404 when storage.Delete() returns common.ErrNotFound", func(t *testing.T) {
+		storage := &DBCaseError{err: common.ErrNotFound}
+		service := NewService(storage, logrus.New())
+
+		err := service.DeleteExpenses(context.Background(), 1)
+
+		cmErr, ok := err.(*common.Error)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusNotFound, cmErr.Code)
+	})
+}
+
+func TestExpensesHistory(t *testing.T) {
+	t.Run("should return the versions of storage.History()", func(t *testing.T) {
+		service := NewService(&DBCaseSuccess{}, logrus.New())
+
+		resp, err := service.ExpensesHistory(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []HistoryResponse{{Version: 1, Action: ActionInsert}}, resp)
+	})
+
+	t.Run("should return http status code = 404 when storage.History() returns common.ErrNotFound", func(t *testing.T) {
+		service := NewService(&DBCaseError{err: common.ErrNotFound}, logrus.New())
+
+		_, err := service.ExpensesHistory(context.Background(), 1)
+
+		cmErr, ok := err.(*common.Error)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusNotFound, cmErr.Code)
+	})
+}
+
+func TestRevertExpenses(t *testing.T) {
+	t.Run("should return the expense of storage.Revert()", func(t *testing.T) {
+		storage := &DBCaseSuccess{}
+		service := NewService(storage, logrus.New())
+
+		resp, err := service.RevertExpenses(context.Background(), 1, RevertRequest{Version: 2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "oldTitle", resp.Title)
+		assert.True(t, storage.revertWasCalled)
+	})
+
+	t.Run("should return http status code = 400 without calling storage when the version is not positive", func(t *testing.T) {
+		storage := &DBCaseSuccess{}
+		service := NewService(storage, logrus.New())
+
+		_, err := service.RevertExpenses(context.Background(), 1, RevertRequest{})
+
+		cmErr, ok := err.(*common.Error)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusBadRequest, cmErr.Code)
+		assert.False(t, storage.revertWasCalled)
+	})
+
+	t.Run("should return http status code = 400 when storage.Revert() returns common.ErrInvalid", func(t *testing.T) {
+		service := NewService(&DBCaseError{err: common.ErrInvalid}, logrus.New())
+
+		_, err := service.RevertExpenses(context.Background(), 1, RevertRequest{Version: 9})
+
+		cmErr, ok := err.(*common.Error)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusBadRequest, cmErr.Code)
+	})
+}
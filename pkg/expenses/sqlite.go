@@ -0,0 +1,378 @@
+package expenses
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/outbox"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tags"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+)
+
+// The tags column holds a JSON array, NULL when the expense has no tags. An
+// expense matches the filter when no key of the filter is missing from its
+// lower-cased tags.
+const (
+	sqliteInsertQuery     = "INSERT INTO expenses (title, amount, note, tags) values (?, ?, ?, ?) RETURNING id, title, amount, note, tags"
+	sqliteSearchByIdQuery = "select id, title, amount, note, tags from expenses where id = ?"
+	sqliteUpdateQuery     = "UPDATE expenses SET title = ?, amount = ?, note = ?, tags = ? WHERE id = ? RETURNING id, title, amount, note, tags"
+	sqliteSearchAllQuery  = "select id, title, amount, note, tags from expenses where not exists (select 1 from json_each(?) f where f.value not in (select lower(value) from json_each(coalesce(expenses.tags, '[]')))) order by id"
+	sqliteDeleteQuery     = "DELETE FROM expenses WHERE id = ?"
+
+	sqliteInsertHistoryQuery = "INSERT INTO expense_history (expense_id, version, action, actor, request_id, changed_at, changes) values (?, (select coalesce(max(version), 0) + 1 from expense_history where expense_id = ?), ?, ?, ?, ?, ?)"
+	sqliteHistoryQuery       = "select version, action, actor, request_id, changed_at, changes from expense_history where expense_id = ? order by version"
+	sqliteHistoryAsOfQuery   = "select version, action, actor, request_id, changed_at, changes from expense_history where expense_id = ?1 and version <= (select max(version) from expense_history where expense_id = ?1 and changed_at <= ?2) order by version"
+)
+
+// sqliteTimeFormat is the layout of changed_at, fixed width so that the text
+// sorts by time.
+const sqliteTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// SqliteMgmt is the Storage of single-user and offline deployments.
+type SqliteMgmt struct {
+	dataMgmt *sql.DB
+	timeout  time.Duration
+}
+
+func NewSqlite(d *sql.DB, timeout time.Duration) *SqliteMgmt {
+	return &SqliteMgmt{dataMgmt: d, timeout: timeout}
+}
+
+func (mgmt SqliteMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+// jsonTags encodes tags for the tags column, nil tags are stored as NULL.
+func jsonTags(tags []string) (sql.NullString, error) {
+	if tags == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func scanSqlite(row scanner) (*ExpensesResponse, error) {
+	result := &ExpensesResponse{}
+	var names sql.NullString
+	if err := row.Scan(&result.Id, &result.Title, &result.Amount, &result.Note, &names); err != nil {
+		return nil, err
+	}
+	if names.Valid {
+		if err := json.Unmarshal([]byte(names.String), &result.Tags); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (mgmt SqliteMgmt) Insert(ctx context.Context, req ExpensesRequest) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("Insert", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteMgmt.Insert", sqliteInsertQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	if req.CategoryId != nil {
+		return nil, common.ErrUnsupported
+	}
+	names, err := jsonTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ExpensesResponse
+	err = mgmt.inTx(ctx, func(tx *sql.Tx) (err error) {
+		row := tx.QueryRowContext(ctx, sqliteInsertQuery, req.Title, req.Amount, req.Note, names)
+		if result, err = scanSqlite(row); err != nil {
+			return err
+		}
+		return sqliteAddHistory(ctx, tx, result.Id, ActionInsert, nil, result)
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// inTx runs fn in a transaction committed only when fn succeeds. The single
+// connection of SQLite serializes the transactions.
+func (mgmt SqliteMgmt) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := mgmt.dataMgmt.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqliteAddHistory records the change of the expense id from before to after
+// with the actor and request id of ctx, and adds its event to the outbox.
+func sqliteAddHistory(ctx context.Context, tx *sql.Tx, id int64, action string, before *ExpensesResponse, after *ExpensesResponse) error {
+	changes, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	changedAt := time.Now().UTC().Format(sqliteTimeFormat)
+	if _, err := tx.ExecContext(ctx, sqliteInsertHistoryQuery, id, id, action, nullString(common.Actor(ctx)), nullString(common.RequestId(ctx)), changedAt, string(encoded)); err != nil {
+		return err
+	}
+	event, err := newEvent(ctx, id, action, before, after, changes)
+	if err != nil {
+		return err
+	}
+	return outbox.AddSqlite(ctx, tx, event)
+}
+
+func scanSqliteHistory(row scanner) (*HistoryResponse, error) {
+	result := &HistoryResponse{}
+	var actor, requestId sql.NullString
+	var changedAt, changes string
+	if err := row.Scan(&result.Version, &result.Action, &actor, &requestId, &changedAt, &changes); err != nil {
+		return nil, err
+	}
+	result.Actor, result.RequestId = actor.String, requestId.String
+	var err error
+	if result.ChangedAt, err = time.Parse(sqliteTimeFormat, changedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(changes), &result.Changes); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (mgmt SqliteMgmt) SearchById(ctx context.Context, id int64) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("SearchById", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteMgmt.SearchById", sqliteSearchByIdQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	row := mgmt.dataMgmt.QueryRowContext(ctx, sqliteSearchByIdQuery, id)
+	result, err := scanSqlite(row)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt SqliteMgmt) Update(ctx context.Context, id int64, req ExpensesRequest) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("Update", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteMgmt.Update", sqliteUpdateQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	if req.CategoryId != nil {
+		return nil, common.ErrUnsupported
+	}
+	names, err := jsonTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ExpensesResponse
+	err = mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := scanSqlite(tx.QueryRowContext(ctx, sqliteSearchByIdQuery, id))
+		if err != nil {
+			return err
+		}
+		row := tx.QueryRowContext(ctx, sqliteUpdateQuery, req.Title, req.Amount, req.Note, names, id)
+		if result, err = scanSqlite(row); err != nil {
+			return err
+		}
+		return sqliteAddHistory(ctx, tx, id, ActionUpdate, before, result)
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Delete removes the expense, its history is kept.
+func (mgmt SqliteMgmt) Delete(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("Delete", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteMgmt.Delete", sqliteDeleteQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := scanSqlite(tx.QueryRowContext(ctx, sqliteSearchByIdQuery, id))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, sqliteDeleteQuery, id); err != nil {
+			return err
+		}
+		return sqliteAddHistory(ctx, tx, id, ActionDelete, before, nil)
+	})
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	return nil
+}
+
+// History returns the versions of the expense, the oldest first, also after
+// the expense was deleted. common.ErrNotFound is returned when the expense
+// never existed.
+func (mgmt SqliteMgmt) History(ctx context.Context, id int64) ([]HistoryResponse, error) {
+	defer metrics.ObserveQuery("History", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteMgmt.History", sqliteHistoryQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := sqliteQueryHistory(ctx, mgmt.dataMgmt, sqliteHistoryQuery, id)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// sqliteQueryHistory returns the versions selected by query,
+// common.ErrNotFound when there is none.
+func sqliteQueryHistory(ctx context.Context, q querier, query string, args ...any) ([]HistoryResponse, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []HistoryResponse{}
+	for rows.Next() {
+		version, err := scanSqliteHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *version)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, common.ErrNotFound
+	}
+	return result, nil
+}
+
+// SearchAsOf returns the expense as it was at the moment at, replayed from
+// its history. common.ErrNotFound is returned when it did not exist then.
+func (mgmt SqliteMgmt) SearchAsOf(ctx context.Context, id int64, at time.Time) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("SearchAsOf", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteMgmt.SearchAsOf", sqliteHistoryAsOfQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	history, err := sqliteQueryHistory(ctx, mgmt.dataMgmt, sqliteHistoryAsOfQuery, id, at.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	result, err := replay(id, history)
+	if err == nil && result == nil {
+		err = common.ErrNotFound
+	}
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Revert restores the expense to its state at version and records it as a
+// new version. common.ErrInvalid is returned for an unknown version or a
+// version that deleted the expense.
+func (mgmt SqliteMgmt) Revert(ctx context.Context, id int64, version int64) (*ExpensesResponse, error) {
+	defer metrics.ObserveQuery("Revert", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteMgmt.Revert", sqliteUpdateQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result *ExpensesResponse
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := scanSqlite(tx.QueryRowContext(ctx, sqliteSearchByIdQuery, id))
+		if err != nil {
+			return err
+		}
+		history, err := sqliteQueryHistory(ctx, tx, sqliteHistoryQuery, id)
+		if err != nil {
+			return err
+		}
+		req, err := revertTo(id, history, version)
+		if err != nil {
+			return err
+		}
+		names, err := jsonTags(req.Tags)
+		if err != nil {
+			return err
+		}
+		row := tx.QueryRowContext(ctx, sqliteUpdateQuery, req.Title, req.Amount, req.Note, names, id)
+		if result, err = scanSqlite(row); err != nil {
+			return err
+		}
+		return sqliteAddHistory(ctx, tx, id, ActionRevert, before, result)
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt SqliteMgmt) SearchAll(ctx context.Context, filter Filter) ([]ExpensesResponse, error) {
+	defer metrics.ObserveQuery("SearchAll", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteMgmt.SearchAll", sqliteSearchAllQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	want, err := json.Marshal(append([]string{}, tags.Keys(filter.Tags)...))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, sqliteSearchAllQuery, string(want))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []ExpensesResponse{}
+	for rows.Next() {
+		exp, err := scanSqlite(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		result = append(result, *exp)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
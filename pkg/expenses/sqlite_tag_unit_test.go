@@ -0,0 +1,109 @@
+//go:build unit
+
+package expenses
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/migration"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/outbox"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func newSqliteDb(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection of :memory: is a different database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migration.New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSqliteConformance(t *testing.T) {
+	runStorageConformance(t, func(t *testing.T) Storage {
+		return NewSqlite(newSqliteDb(t), time.Second)
+	})
+}
+
+func TestSqliteTags(t *testing.T) {
+	t.Run("should keep nil tags as nil and match only the empty filter", func(t *testing.T) {
+		storage := NewSqlite(newSqliteDb(t), time.Second)
+		inserted, err := storage.Insert(context.Background(), ExpensesRequest{Title: "no tags"})
+		assert.NoError(t, err)
+
+		all, _ := storage.SearchAll(context.Background(), Filter{})
+		foods, _ := storage.SearchAll(context.Background(), Filter{Tags: []string{"food"}})
+
+		assert.Nil(t, inserted.Tags)
+		assert.Len(t, all, 1)
+		assert.Len(t, foods, 0)
+	})
+}
+
+func TestSqliteOutbox(t *testing.T) {
+	t.Run("should add an event of every committed write to the outbox", func(t *testing.T) {
+		db := newSqliteDb(t)
+		storage := NewSqlite(db, time.Second)
+		ctx := common.WithRequestId(common.WithActor(context.Background(), "alice"), "req-1")
+		inserted, _ := storage.Insert(ctx, ExpensesRequest{Title: "taxi", Amount: 100})
+		storage.Update(ctx, inserted.Id, ExpensesRequest{Title: "taxi", Amount: 120})
+		storage.Update(ctx, 404, ExpensesRequest{Title: "unknown"})
+		storage.Delete(ctx, inserted.Id)
+
+		var events []outbox.Event
+		n, err := outbox.NewSqlite(db, time.Second).Relay(context.Background(), 10, 3, func(ctx context.Context, e outbox.Event) error {
+			events = append(events, e)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+		if assert.Len(t, events, 3) {
+			assert.Equal(t, []string{EventCreated, EventUpdated, EventDeleted}, []string{events[0].Type, events[1].Type, events[2].Type})
+			assert.Equal(t, Aggregate, events[1].Aggregate)
+			assert.Equal(t, inserted.Id, events[1].AggregateId)
+			assert.Equal(t, "alice", events[1].Actor)
+			assert.Equal(t, "req-1", events[1].RequestId)
+			assert.JSONEq(t, `{"expense":{"id":1,"title":"taxi","amount":120,"note":"","tags":null},"changes":{"amount":{"from":100,"to":120}}}`, string(events[1].Payload))
+		}
+	})
+}
+
+func TestSqliteSearchAsOf(t *testing.T) {
+	t.Run("should replay every version up to the last one changed by then", func(t *testing.T) {
+		db := newSqliteDb(t)
+		storage := NewSqlite(db, time.Second)
+		inserted, _ := storage.Insert(context.Background(), ExpensesRequest{Title: "taxi", Amount: 100})
+		storage.Update(context.Background(), inserted.Id, ExpensesRequest{Title: "taxi", Amount: 100, Note: "airport"})
+		storage.Update(context.Background(), inserted.Id, ExpensesRequest{Title: "taxi", Amount: 150, Note: "airport"})
+		// version 2 written by a transaction started before version 3
+		at := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+		for version, changedAt := range map[int]time.Time{1: at.Add(-time.Hour), 2: at.Add(time.Minute), 3: at} {
+			if _, err := db.Exec("update expense_history set changed_at = ? where expense_id = ? and version = ?", changedAt.Format(sqliteTimeFormat), inserted.Id, version); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := storage.SearchAsOf(context.Background(), inserted.Id, at)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, float64(150), got.Amount)
+			assert.Equal(t, "airport", got.Note)
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package expenses
+
+import (
+	"fmt"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+)
+
+// NewStorage creates the Storage of the configured backend.
+func NewStorage(ins *config.Instance) (Storage, error) {
+	cf := ins.Config.Get()
+	switch cf.StorageBackend() {
+	case config.BackendPostgres:
+		return New(ins.DB, cf.DbTimeout(), cf.DbReadRetries()), nil
+	case config.BackendSqlite:
+		return NewSqlite(ins.DB, cf.DbTimeout()), nil
+	case config.BackendMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cf.StorageBackend())
+	}
+}
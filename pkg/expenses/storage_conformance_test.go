@@ -0,0 +1,306 @@
+//go:build unit || integration
+
+package expenses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// runStorageConformance is the behavior every Storage backend must have.
+// newStorage must return an empty storage.
+func runStorageConformance(t *testing.T, newStorage func(t *testing.T) Storage) {
+	ctx := context.Background()
+	food := ExpensesRequest{Title: "strawberry smoothie", Amount: 79, Note: "night market", Tags: []string{"food", "beverage"}}
+	taxi := ExpensesRequest{Title: "taxi", Amount: 120.5, Note: "chiang mai", Tags: []string{"travel"}}
+	snack := ExpensesRequest{Title: "snack", Amount: 20, Note: "", Tags: []string{"food"}}
+
+	t.Run("Insert should assign a new increasing id and return the stored fields", func(t *testing.T) {
+		storage := newStorage(t)
+
+		first, err := storage.Insert(ctx, food)
+		assert.NoError(t, err)
+		second, err := storage.Insert(ctx, taxi)
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, int64(0), first.Id)
+		assert.Greater(t, second.Id, first.Id)
+		assert.Equal(t, ExpensesResponse{Id: first.Id, Title: food.Title, Amount: food.Amount, Note: food.Note, Tags: food.Tags}, *first)
+	})
+
+	t.Run("SearchById should return the inserted expense", func(t *testing.T) {
+		storage := newStorage(t)
+		inserted, _ := storage.Insert(ctx, food)
+
+		got, err := storage.SearchById(ctx, inserted.Id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, inserted, got)
+	})
+
+	t.Run("SearchById should return common.ErrNotFound for an unknown id", func(t *testing.T) {
+		storage := newStorage(t)
+
+		got, err := storage.SearchById(ctx, 404)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Update should replace every field and keep the id", func(t *testing.T) {
+		storage := newStorage(t)
+		inserted, _ := storage.Insert(ctx, food)
+
+		updated, err := storage.Update(ctx, inserted.Id, taxi)
+		assert.NoError(t, err)
+		got, _ := storage.SearchById(ctx, inserted.Id)
+
+		want := &ExpensesResponse{Id: inserted.Id, Title: taxi.Title, Amount: taxi.Amount, Note: taxi.Note, Tags: taxi.Tags}
+		assert.Equal(t, want, updated)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("Update should return common.ErrNotFound for an unknown id", func(t *testing.T) {
+		storage := newStorage(t)
+
+		got, err := storage.Update(ctx, 404, taxi)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("SearchAll should return an empty list when there is no expense", func(t *testing.T) {
+		storage := newStorage(t)
+
+		got, err := storage.SearchAll(ctx, Filter{})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, got)
+		assert.Len(t, got, 0)
+	})
+
+	t.Run("SearchAll should return every expense ordered by id", func(t *testing.T) {
+		storage := newStorage(t)
+		first, _ := storage.Insert(ctx, food)
+		second, _ := storage.Insert(ctx, taxi)
+		third, _ := storage.Insert(ctx, snack)
+		storage.Update(ctx, first.Id, food)
+
+		got, err := storage.SearchAll(ctx, Filter{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []ExpensesResponse{*first, *second, *third}, got)
+	})
+
+	t.Run("SearchAll should return only expenses having every tag of the filter whatever its spelling", func(t *testing.T) {
+		storage := newStorage(t)
+		first, _ := storage.Insert(ctx, food)
+		storage.Insert(ctx, taxi)
+		third, _ := storage.Insert(ctx, snack)
+
+		foods, err := storage.SearchAll(ctx, Filter{Tags: []string{"food"}})
+		assert.NoError(t, err)
+		beverages, err := storage.SearchAll(ctx, Filter{Tags: []string{"food", "beverage"}})
+		assert.NoError(t, err)
+		spelled, err := storage.SearchAll(ctx, Filter{Tags: []string{"FOOD", "Beverage"}})
+		assert.NoError(t, err)
+		none, err := storage.SearchAll(ctx, Filter{Tags: []string{"drink"}})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []ExpensesResponse{*first, *third}, foods)
+		assert.Equal(t, []ExpensesResponse{*first}, beverages)
+		assert.Equal(t, []ExpensesResponse{*first}, spelled)
+		assert.Len(t, none, 0)
+	})
+
+	t.Run("Delete should remove the expense", func(t *testing.T) {
+		storage := newStorage(t)
+		inserted, _ := storage.Insert(ctx, food)
+		kept, _ := storage.Insert(ctx, taxi)
+
+		err := storage.Delete(ctx, inserted.Id)
+		assert.NoError(t, err)
+		_, err = storage.SearchById(ctx, inserted.Id)
+		all, _ := storage.SearchAll(ctx, Filter{})
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Equal(t, []ExpensesResponse{*kept}, all)
+	})
+
+	t.Run("Delete should return common.ErrNotFound for an unknown id", func(t *testing.T) {
+		storage := newStorage(t)
+
+		err := storage.Delete(ctx, 404)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+	})
+
+	t.Run("History should record every write with its actor, request id and changed fields", func(t *testing.T) {
+		storage := newStorage(t)
+		alice := common.WithRequestId(common.WithActor(ctx, "alice"), "req-1")
+		bob := common.WithRequestId(common.WithActor(ctx, "bob"), "req-2")
+		inserted, _ := storage.Insert(alice, food)
+		storage.Update(bob, inserted.Id, ExpensesRequest{Title: food.Title, Amount: 99, Note: food.Note, Tags: food.Tags})
+		storage.Delete(ctx, inserted.Id)
+
+		got, err := storage.History(ctx, inserted.Id)
+
+		assert.NoError(t, err)
+		if assert.Len(t, got, 3) {
+			assert.Equal(t, []int64{1, 2, 3}, []int64{got[0].Version, got[1].Version, got[2].Version})
+			assert.Equal(t, []string{ActionInsert, ActionUpdate, ActionDelete}, []string{got[0].Action, got[1].Action, got[2].Action})
+			assert.Equal(t, []string{"alice", "bob", ""}, []string{got[0].Actor, got[1].Actor, got[2].Actor})
+			assert.Equal(t, []string{"req-1", "req-2", ""}, []string{got[0].RequestId, got[1].RequestId, got[2].RequestId})
+			assert.False(t, got[0].ChangedAt.IsZero())
+			assert.False(t, got[2].ChangedAt.Before(got[0].ChangedAt))
+
+			assert.Equal(t, map[string]Change{
+				"title":  {From: json.RawMessage(`null`), To: json.RawMessage(`"strawberry smoothie"`)},
+				"amount": {From: json.RawMessage(`null`), To: json.RawMessage(`79`)},
+				"note":   {From: json.RawMessage(`null`), To: json.RawMessage(`"night market"`)},
+				"tags":   {From: json.RawMessage(`null`), To: json.RawMessage(`["food","beverage"]`)},
+			}, compactChanges(t, got[0].Changes))
+			assert.Equal(t, map[string]Change{
+				"amount": {From: json.RawMessage(`79`), To: json.RawMessage(`99`)},
+			}, compactChanges(t, got[1].Changes))
+			assert.Equal(t, []string{"amount", "note", "tags", "title"}, changedFields(got[2].Changes))
+		}
+	})
+
+	t.Run("History should return common.ErrNotFound for an expense that never existed", func(t *testing.T) {
+		storage := newStorage(t)
+
+		got, err := storage.History(ctx, 404)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("SearchAsOf should return the expense as it was at the given time", func(t *testing.T) {
+		storage := newStorage(t)
+		inserted, _ := storage.Insert(ctx, food)
+		// the wait keeps the versions apart on the millisecond clock of sqlite.
+		time.Sleep(5 * time.Millisecond)
+		updated, _ := storage.Update(ctx, inserted.Id, taxi)
+		history, _ := storage.History(ctx, inserted.Id)
+		if !assert.Len(t, history, 2) {
+			return
+		}
+
+		before, beforeErr := storage.SearchAsOf(ctx, inserted.Id, history[0].ChangedAt.Add(-time.Hour))
+		first, firstErr := storage.SearchAsOf(ctx, inserted.Id, history[0].ChangedAt)
+		latest, latestErr := storage.SearchAsOf(ctx, inserted.Id, history[1].ChangedAt.Add(time.Hour))
+
+		assert.ErrorIs(t, beforeErr, common.ErrNotFound)
+		assert.Nil(t, before)
+		assert.NoError(t, firstErr)
+		assert.Equal(t, inserted, first)
+		assert.NoError(t, latestErr)
+		assert.Equal(t, updated, latest)
+	})
+
+	t.Run("SearchAsOf should return common.ErrNotFound once the expense is deleted", func(t *testing.T) {
+		storage := newStorage(t)
+		inserted, _ := storage.Insert(ctx, food)
+		storage.Delete(ctx, inserted.Id)
+
+		got, err := storage.SearchAsOf(ctx, inserted.Id, time.Now().Add(time.Hour))
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Revert should restore the fields of the version as a new revert version", func(t *testing.T) {
+		storage := newStorage(t)
+		inserted, _ := storage.Insert(ctx, food)
+		storage.Update(ctx, inserted.Id, taxi)
+
+		reverted, err := storage.Revert(common.WithActor(ctx, "alice"), inserted.Id, 1)
+		assert.NoError(t, err)
+		got, _ := storage.SearchById(ctx, inserted.Id)
+		history, _ := storage.History(ctx, inserted.Id)
+
+		assert.Equal(t, inserted, reverted)
+		assert.Equal(t, inserted, got)
+		if assert.Len(t, history, 3) {
+			assert.Equal(t, int64(3), history[2].Version)
+			assert.Equal(t, ActionRevert, history[2].Action)
+			assert.Equal(t, "alice", history[2].Actor)
+			assert.Equal(t, []string{"amount", "note", "tags", "title"}, changedFields(history[2].Changes))
+		}
+	})
+
+	t.Run("Revert should return common.ErrInvalid for an unknown version", func(t *testing.T) {
+		storage := newStorage(t)
+		inserted, _ := storage.Insert(ctx, food)
+
+		got, err := storage.Revert(ctx, inserted.Id, 9)
+
+		assert.ErrorIs(t, err, common.ErrInvalid)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Revert should return common.ErrNotFound for an unknown id", func(t *testing.T) {
+		storage := newStorage(t)
+
+		got, err := storage.Revert(ctx, 404, 1)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("returned expenses should not share state with the storage", func(t *testing.T) {
+		storage := newStorage(t)
+		req := ExpensesRequest{Title: "shared", Tags: []string{"food"}}
+		inserted, _ := storage.Insert(ctx, req)
+
+		req.Tags[0] = "changed"
+		inserted.Tags[0] = "changed"
+		got, _ := storage.SearchById(ctx, inserted.Id)
+
+		assert.Equal(t, []string{"food"}, got.Tags)
+	})
+
+	t.Run("should return the context error when the context is done", func(t *testing.T) {
+		storage := newStorage(t)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := storage.Insert(cancelled, food)
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// compactChanges drops the insignificant whitespace a database may add to the
+// json of the changes.
+func compactChanges(t *testing.T, changes map[string]Change) map[string]Change {
+	compact := func(raw json.RawMessage) json.RawMessage {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	result := map[string]Change{}
+	for field, c := range changes {
+		result[field] = Change{From: compact(c.From), To: compact(c.To)}
+	}
+	return result
+}
+
+func changedFields(changes map[string]Change) []string {
+	fields := []string{}
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,136 @@
+package expenses
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// The v1 api writes the ExpensesResponse of the Services as is. The v2 api
+// maps them to its own types, the ids are strings as the README specifies and
+// the bodies are in a "data" envelope.
+
+// ExpenseV2 is an expense of the v2 api.
+type ExpenseV2 struct {
+	Id         string   `json:"id"`
+	Title      string   `json:"title"`
+	Amount     float64  `json:"amount"`
+	Note       string   `json:"note"`
+	Tags       []string `json:"tags"`
+	CategoryId *string  `json:"category_id,omitempty"`
+}
+
+// ExpenseRequestV2 is the ExpensesRequest of the v2 api.
+type ExpenseRequestV2 struct {
+	Title      string   `json:"title"`
+	Amount     float64  `json:"amount"`
+	Note       string   `json:"note"`
+	Tags       []string `json:"tags"`
+	CategoryId *string  `json:"category_id"`
+}
+
+type ExpenseEnvelopeV2 struct {
+	Data ExpenseV2 `json:"data"`
+}
+
+type ExpenseListV2 struct {
+	Data []ExpenseV2 `json:"data"`
+}
+
+// HistoryV2 is a version of an expense of the v2 api, the category ids of
+// the changes are strings.
+type HistoryV2 struct {
+	Version   int64             `json:"version"`
+	Action    string            `json:"action"`
+	Actor     string            `json:"actor,omitempty"`
+	RequestId string            `json:"request_id,omitempty"`
+	ChangedAt time.Time         `json:"changed_at"`
+	Changes   map[string]Change `json:"changes"`
+}
+
+type HistoryListV2 struct {
+	Data []HistoryV2 `json:"data"`
+}
+
+// toV2 maps an expense of the Services to v2, the tags are never null.
+func toV2(e ExpensesResponse) ExpenseV2 {
+	v2 := ExpenseV2{
+		Id:     strconv.FormatInt(e.Id, 10),
+		Title:  e.Title,
+		Amount: e.Amount,
+		Note:   e.Note,
+		Tags:   e.Tags,
+	}
+	if v2.Tags == nil {
+		v2.Tags = []string{}
+	}
+	if e.CategoryId != nil {
+		id := strconv.FormatInt(*e.CategoryId, 10)
+		v2.CategoryId = &id
+	}
+	return v2
+}
+
+func toListV2(expenses []ExpensesResponse) ExpenseListV2 {
+	list := ExpenseListV2{Data: make([]ExpenseV2, 0, len(expenses))}
+	for _, e := range expenses {
+		list.Data = append(list.Data, toV2(e))
+	}
+	return list
+}
+
+func toHistoryListV2(history []HistoryResponse) (HistoryListV2, error) {
+	list := HistoryListV2{Data: make([]HistoryV2, 0, len(history))}
+	for _, h := range history {
+		v2 := HistoryV2{
+			Version:   h.Version,
+			Action:    h.Action,
+			Actor:     h.Actor,
+			RequestId: h.RequestId,
+			ChangedAt: h.ChangedAt,
+			Changes:   map[string]Change{},
+		}
+		for field, change := range h.Changes {
+			if field == "category_id" {
+				var err error
+				if change.From, err = idV2(change.From); err != nil {
+					return list, err
+				}
+				if change.To, err = idV2(change.To); err != nil {
+					return list, err
+				}
+			}
+			v2.Changes[field] = change
+		}
+		list.Data = append(list.Data, v2)
+	}
+	return list, nil
+}
+
+// idV2 maps a json id to a json string, null stays null.
+func idV2(raw json.RawMessage) (json.RawMessage, error) {
+	var id *int64
+	if err := json.Unmarshal(raw, &id); err != nil || id == nil {
+		return raw, err
+	}
+	return json.Marshal(strconv.FormatInt(*id, 10))
+}
+
+// request maps the request to the ExpensesRequest of the Services, the
+// category id must be a number.
+func (r ExpenseRequestV2) request() (ExpensesRequest, error) {
+	req := ExpensesRequest{
+		Title:  r.Title,
+		Amount: r.Amount,
+		Note:   r.Note,
+		Tags:   r.Tags,
+	}
+	if r.CategoryId != nil {
+		id, err := strconv.ParseInt(*r.CategoryId, 10, 64)
+		if err != nil {
+			return req, err
+		}
+		req.CategoryId = &id
+	}
+	return req, nil
+}
@@ -0,0 +1,131 @@
+package graphapi
+
+import (
+	"github.com/graph-gophers/graphql-go/types"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// listFactor is the number of items a list field is assumed to return.
+const listFactor = 10
+
+// Complexity estimates the cost of the operation of query: each field costs
+// one, the fields selected under a list are counted listFactor times. The
+// estimate stops once it exceeds limit, a cost above limit is returned then.
+// It returns the syntax error of a query that does not parse.
+func Complexity(schema *types.Schema, query string, operationName string, limit int) (int, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return 0, err
+	}
+	var op *ast.OperationDefinition
+	if operationName == "" && len(doc.Operations) == 1 {
+		op = doc.Operations[0]
+	} else {
+		op = doc.Operations.ForName(operationName)
+	}
+	if op == nil {
+		return 0, nil
+	}
+	c := complexity{schema: schema, fragments: doc.Fragments, limit: limit, costs: map[string]int{}, visiting: map[string]bool{}}
+	return c.selections(op.SelectionSet, schema.EntryPoints[string(op.Operation)]), nil
+}
+
+type complexity struct {
+	schema    *types.Schema
+	fragments ast.FragmentDefinitionList
+	// limit saturates the costs, a cost above it is limit + 1.
+	limit int
+	// costs holds the cost of every fragment counted, a fragment spread many
+	// times is walked once.
+	costs map[string]int
+	// visiting stops on a fragment spreading itself, which the validation
+	// rejects.
+	visiting map[string]bool
+}
+
+// add returns a + b, limit + 1 once above limit.
+func (c complexity) add(a int, b int) int {
+	if a > c.limit-b {
+		return c.limit + 1
+	}
+	return a + b
+}
+
+func (c complexity) selections(set ast.SelectionSet, parent types.NamedType) int {
+	total := 0
+	for _, sel := range set {
+		if total > c.limit {
+			return total
+		}
+		switch sel := sel.(type) {
+		case *ast.Field:
+			fieldType, list := c.fieldType(parent, sel.Name)
+			cost := c.selections(sel.SelectionSet, fieldType)
+			if list {
+				if cost > c.limit/listFactor {
+					cost = c.limit + 1
+				} else {
+					cost *= listFactor
+				}
+			}
+			total = c.add(total, c.add(1, cost))
+		case *ast.InlineFragment:
+			typ := parent
+			if sel.TypeCondition != "" {
+				typ = c.schema.Types[sel.TypeCondition]
+			}
+			total = c.add(total, c.selections(sel.SelectionSet, typ))
+		case *ast.FragmentSpread:
+			total = c.add(total, c.fragment(sel.Name))
+		}
+	}
+	return total
+}
+
+// fragment returns the cost of the fragment name, nothing when it is unknown
+// or spreads itself.
+func (c complexity) fragment(name string) int {
+	if cost, ok := c.costs[name]; ok {
+		return cost
+	}
+	fragment := c.fragments.ForName(name)
+	if fragment == nil || c.visiting[name] {
+		return 0
+	}
+	c.visiting[name] = true
+	cost := c.selections(fragment.SelectionSet, c.schema.Types[fragment.TypeCondition])
+	delete(c.visiting, name)
+	c.costs[name] = cost
+	return cost
+}
+
+// fieldType returns the named type of the field name of parent and whether the
+// field is a list, nil when the field is unknown.
+func (c complexity) fieldType(parent types.NamedType, name string) (types.NamedType, bool) {
+	var fields types.FieldsDefinition
+	switch parent := parent.(type) {
+	case *types.ObjectTypeDefinition:
+		fields = parent.Fields
+	case *types.InterfaceTypeDefinition:
+		fields = parent.Fields
+	}
+	field := fields.Get(name)
+	if field == nil {
+		return nil, false
+	}
+	list := false
+	typ := field.Type
+	for {
+		switch t := typ.(type) {
+		case *types.NonNull:
+			typ = t.OfType
+		case *types.List:
+			list, typ = true, t.OfType
+		case types.NamedType:
+			return t, list
+		default:
+			return nil, list
+		}
+	}
+}
@@ -0,0 +1,79 @@
+//go:build unit
+
+package graphapi
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplexity(t *testing.T) {
+	schema := graphql.MustParseSchema(Schema, &Resolver{}).ASTSchema()
+
+	t.Run("should count the fields under a list for every item", func(t *testing.T) {
+		// expenses, then id and category for 10 items, and name under category
+		cost, err := Complexity(schema, `{ expenses { id category { name } } }`, "", 1000)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1+10*(1+1+1), cost)
+	})
+
+	t.Run("should multiply the nested lists", func(t *testing.T) {
+		cost, err := Complexity(schema, `{ tags { expenses { id } } }`, "", 1000)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1+10*(1+10*1), cost)
+	})
+
+	t.Run("should count the fields of the fragments", func(t *testing.T) {
+		cost, err := Complexity(schema, `
+			query One { expense(id: 1) { ...fields } }
+			query Two { summary { count } }
+			fragment fields on Expense { id title }
+		`, "One", 1000)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1+2, cost)
+	})
+
+	t.Run("should stop on a fragment spreading itself", func(t *testing.T) {
+		cost, err := Complexity(schema, `{ expense(id: 1) { ...loop } } fragment loop on Expense { id ...loop }`, "", 1000)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1+1, cost)
+	})
+
+	t.Run("should count a fragment spread many times once", func(t *testing.T) {
+		// every fragment spreads the next twice, 2^40 fields when expanded
+		var fragments strings.Builder
+		for i := 0; i < 40; i++ {
+			fmt.Fprintf(&fragments, "fragment f%d on Expense { ...f%d ...f%d }\n", i, i+1, i+1)
+		}
+		fragments.WriteString("fragment f40 on Expense { id }\n")
+		start := time.Now()
+
+		cost, err := Complexity(schema, `{ expense(id: 1) { ...f0 } }`+fragments.String(), "", 1000)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1001, cost)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("should stop once the cost exceeds the limit", func(t *testing.T) {
+		cost, err := Complexity(schema, `{ tags { expenses { id } } }`, "", 20)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 21, cost)
+	})
+
+	t.Run("should return the error of a query that does not parse", func(t *testing.T) {
+		_, err := Complexity(schema, `{ expenses {`, "", 1000)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,34 @@
+package graphapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+)
+
+var errInvalidId = &Error{Status: http.StatusBadRequest, Message: "invalid id"}
+
+// Error is a GraphQL error telling the http status of the failure in its
+// extensions, like the status of the REST api.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{"status": e.Status}
+}
+
+// resolverError reports the error of a service with its status.
+func resolverError(err error) error {
+	var cmErr *common.Error
+	if errors.As(err, &cmErr) {
+		return &Error{Status: cmErr.Code, Message: cmErr.Desc}
+	}
+	return &Error{Status: common.StatusFromError(err), Message: err.Error()}
+}
@@ -0,0 +1,98 @@
+package graphapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	log           common.Log
+	schema        *graphql.Schema
+	resolver      *Resolver
+	persisted     *Persisted
+	maxComplexity int
+}
+
+func NewHandler(schema *graphql.Schema, resolver *Resolver, persisted *Persisted, maxComplexity int, l common.Log) *Handler {
+	return &Handler{schema: schema, resolver: resolver, persisted: persisted, maxComplexity: maxComplexity, log: l}
+}
+
+// Query runs a GraphQL query. The errors of the query are reported in the
+// body with 200, like the GraphQL servers do, a malformed request is 400.
+func (h Handler) Query(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.Query")
+	defer span.End()
+
+	req := Request{}
+	if c.Request().Method == http.MethodGet {
+		if err := bindQueryParams(c, &req); err != nil {
+			return c.NoContent(http.StatusBadRequest)
+		}
+	} else if err := c.Bind(&req); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if pq := req.Extensions.PersistedQuery; pq != nil {
+		if req.Query == "" {
+			query, ok := h.persisted.Get(pq.Sha256Hash)
+			if !ok {
+				return c.JSON(http.StatusOK, errorResponse("PersistedQueryNotFound", "PERSISTED_QUERY_NOT_FOUND"))
+			}
+			req.Query = query
+		} else if !h.persisted.Add(pq.Sha256Hash, req.Query) {
+			return c.JSON(http.StatusBadRequest, errorResponse("provided sha does not match query", "INVALID_PERSISTED_QUERY"))
+		}
+	}
+	if req.Query == "" {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	// the validation checks the max depth and the fragments before the
+	// complexity walks the query
+	if errs := h.schema.ValidateWithVariables(req.Query, req.Variables); len(errs) > 0 {
+		return c.JSON(http.StatusOK, &graphql.Response{Errors: errs})
+	}
+	cost, err := Complexity(h.schema.ASTSchema(), req.Query, req.OperationName, h.maxComplexity)
+	if err != nil {
+		return c.JSON(http.StatusOK, errorResponse(err.Error(), "GRAPHQL_PARSE_FAILED"))
+	}
+	if cost > h.maxComplexity {
+		return c.JSON(http.StatusOK, errorResponse(fmt.Sprintf("query complexity exceeds %d", h.maxComplexity), "COMPLEXITY_LIMIT_EXCEEDED"))
+	}
+
+	resp := h.schema.Exec(h.resolver.withLoaders(ctx), req.Query, req.OperationName, req.Variables)
+	return c.JSON(http.StatusOK, resp)
+}
+
+// bindQueryParams reads a GET request, variables and extensions are json.
+func bindQueryParams(c echo.Context, req *Request) error {
+	req.Query = c.QueryParam("query")
+	req.OperationName = c.QueryParam("operationName")
+	if variables := c.QueryParam("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			return err
+		}
+	}
+	if extensions := c.QueryParam("extensions"); extensions != "" {
+		if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errorResponse is a response failing before the execution, code tells the
+// clients what failed.
+func errorResponse(message string, code string) *graphql.Response {
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{{
+		Message:    message,
+		Extensions: map[string]any{"code": code},
+	}}}
+}
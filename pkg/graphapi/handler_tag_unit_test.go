@@ -0,0 +1,271 @@
+//go:build unit
+
+package graphapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/categories"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tags"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// ExpensesStub serves fixed expenses and counts the reads of them.
+type ExpensesStub struct {
+	expenses.Services
+	mu      sync.Mutex
+	all     []expenses.ExpensesResponse
+	filters []expenses.Filter
+}
+
+func (s *ExpensesStub) SearchExpensesById(ctx context.Context, id int64) (*expenses.ExpensesResponse, error) {
+	for _, exp := range s.all {
+		if exp.Id == id {
+			return &exp, nil
+		}
+	}
+	return nil, &common.Error{Code: http.StatusNotFound, Desc: "Search Expenses By Id Error"}
+}
+
+func (s *ExpensesStub) SearchExpensesAll(ctx context.Context, filter expenses.Filter) ([]expenses.ExpensesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filters = append(s.filters, filter)
+	result := []expenses.ExpensesResponse{}
+	for _, exp := range s.all {
+		if len(filter.Tags) == 0 || contains(exp.Tags, filter.Tags[0]) {
+			result = append(result, exp)
+		}
+	}
+	return result, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if tags.Key(n) == tags.Key(name) {
+			return true
+		}
+	}
+	return false
+}
+
+type TagsStub struct{}
+
+func (TagsStub) ListTags(ctx context.Context) ([]tags.TagResponse, error) {
+	return []tags.TagResponse{{Id: 1, Name: "food", Count: 2}, {Id: 2, Name: "travel", Count: 1}}, nil
+}
+
+// CategoriesStub counts the batches of ids read.
+type CategoriesStub struct {
+	mu      sync.Mutex
+	batches [][]int64
+}
+
+func (s *CategoriesStub) ListCategories(ctx context.Context) ([]categories.CategoryResponse, error) {
+	return []categories.CategoryResponse{{Id: 1, Name: "Travel"}}, nil
+}
+
+func (s *CategoriesStub) SearchCategoriesByIds(ctx context.Context, ids []int64) ([]categories.CategoryResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, ids)
+	result := []categories.CategoryResponse{}
+	for _, id := range ids {
+		result = append(result, categories.CategoryResponse{Id: id, Name: "category"})
+	}
+	return result, nil
+}
+
+func (s *CategoriesStub) SummaryCategories(ctx context.Context) ([]categories.SummaryResponse, error) {
+	return []categories.SummaryResponse{{Id: 1, Name: "Travel", Amount: 100, Count: 1, TotalAmount: 300, TotalCount: 3}}, nil
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func newHandler(t Tags, c Categories, maxComplexity int) (*Handler, *ExpensesStub) {
+	stub := &ExpensesStub{all: []expenses.ExpensesResponse{
+		{Id: 1, Title: "Taxi", Amount: 250, Tags: []string{"travel"}, CategoryId: int64Ptr(1)},
+		{Id: 2, Title: "Lunch", Amount: 80, Tags: []string{"food"}, CategoryId: int64Ptr(2)},
+		{Id: 3, Title: "Dinner", Amount: 120, Tags: []string{"food", "Travel"}, CategoryId: int64Ptr(2)},
+	}}
+	resolver := NewResolver(stub, t, c)
+	schema := graphql.MustParseSchema(Schema, resolver, graphql.MaxDepth(4), graphql.MaxParallelism(maxParallelism))
+	return NewHandler(schema, resolver, NewPersisted(10), maxComplexity, logrus.New()), stub
+}
+
+func post(h *Handler, body string) (*httptest.ResponseRecorder, map[string]any) {
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	h.Query(echo.New().NewContext(req, rec))
+	resp := map[string]any{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func query(q string) string {
+	body, _ := json.Marshal(Request{Query: q})
+	return string(body)
+}
+
+func TestQuery(t *testing.T) {
+	t.Run("should resolve the expenses with their categories in one batch", func(t *testing.T) {
+		categoryStub := &CategoriesStub{}
+		h, _ := newHandler(TagsStub{}, categoryStub, 1000)
+
+		rec, resp := post(h, query(`{ expenses { id title category { id } } }`))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, resp["errors"])
+		assert.Len(t, resp["data"].(map[string]any)["expenses"], 3)
+		assert.Len(t, categoryStub.batches, 1)
+		assert.ElementsMatch(t, []int64{1, 2}, categoryStub.batches[0])
+	})
+
+	t.Run("should read the expenses of every tag at once", func(t *testing.T) {
+		h, stub := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+
+		_, resp := post(h, query(`{ tags { name expenses { title } } }`))
+
+		assert.Nil(t, resp["errors"])
+		assert.Equal(t, map[string]any{"tags": []any{
+			map[string]any{"name": "food", "expenses": []any{map[string]any{"title": "Lunch"}, map[string]any{"title": "Dinner"}}},
+			map[string]any{"name": "travel", "expenses": []any{map[string]any{"title": "Taxi"}, map[string]any{"title": "Dinner"}}},
+		}}, resp["data"])
+		assert.Len(t, stub.filters, 1)
+	})
+
+	t.Run("should total the expenses by tag", func(t *testing.T) {
+		h, _ := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+
+		_, resp := post(h, query(`{ summary { count amount tags { tag count amount } } categories { name totalAmount totalCount } }`))
+
+		assert.Nil(t, resp["errors"])
+		assert.Equal(t, map[string]any{
+			"summary": map[string]any{"count": 3.0, "amount": 450.0, "tags": []any{
+				map[string]any{"tag": "food", "count": 2.0, "amount": 200.0},
+				map[string]any{"tag": "travel", "count": 2.0, "amount": 370.0},
+			}},
+			"categories": []any{map[string]any{"name": "Travel", "totalAmount": 300.0, "totalCount": 3.0}},
+		}, resp["data"])
+	})
+
+	t.Run("should return null for an expense that does not exist", func(t *testing.T) {
+		h, _ := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+
+		_, resp := post(h, query(`{ expense(id: 404) { id } }`))
+
+		assert.Nil(t, resp["errors"])
+		assert.Equal(t, map[string]any{"expense": nil}, resp["data"])
+	})
+
+	t.Run("should report the tags unsupported by the backend with status 501", func(t *testing.T) {
+		h, _ := newHandler(nil, nil, 1000)
+
+		_, resp := post(h, query(`{ tags { name } }`))
+
+		errors := resp["errors"].([]any)
+		assert.Len(t, errors, 1)
+		assert.Equal(t, map[string]any{"status": 501.0}, errors[0].(map[string]any)["extensions"])
+	})
+
+	t.Run("should reject a query deeper than the max depth", func(t *testing.T) {
+		h, _ := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+
+		_, resp := post(h, query(`{ tags { expenses { category { parent { parent { id } } } } } }`))
+
+		assert.NotNil(t, resp["errors"])
+		assert.Nil(t, resp["data"])
+	})
+
+	t.Run("should reject a query more complex than the max complexity", func(t *testing.T) {
+		h, stub := newHandler(TagsStub{}, &CategoriesStub{}, 20)
+
+		_, resp := post(h, query(`{ tags { expenses { id } } }`))
+
+		errors := resp["errors"].([]any)
+		assert.Equal(t, "query complexity exceeds 20", errors[0].(map[string]any)["message"])
+		assert.Empty(t, stub.filters)
+	})
+
+	t.Run("should reject a query that does not validate before its complexity", func(t *testing.T) {
+		h, stub := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+
+		_, resp := post(h, query(`{ tags { unknown } }`))
+
+		errors := resp["errors"].([]any)
+		assert.Len(t, errors, 1)
+		assert.Nil(t, errors[0].(map[string]any)["extensions"])
+		assert.Empty(t, stub.filters)
+	})
+
+	t.Run("should return 400 for a request without query", func(t *testing.T) {
+		h, _ := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+
+		rec, _ := post(h, `{}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestPersistedQuery(t *testing.T) {
+	q := `{ expense(id: 1) { title } }`
+	sum := sha256.Sum256([]byte(q))
+	hash := hex.EncodeToString(sum[:])
+	extensions := `{"persistedQuery":{"version":1,"sha256Hash":"` + hash + `"}}`
+
+	get := func(h *Handler, params url.Values) map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/graphql?"+params.Encode(), nil)
+		rec := httptest.NewRecorder()
+		h.Query(echo.New().NewContext(req, rec))
+		resp := map[string]any{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	t.Run("should ask for the query of an unknown hash", func(t *testing.T) {
+		h, _ := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+
+		resp := get(h, url.Values{"extensions": {extensions}})
+
+		errors := resp["errors"].([]any)
+		assert.Equal(t, "PersistedQueryNotFound", errors[0].(map[string]any)["message"])
+	})
+
+	t.Run("should run the query of a hash sent with it before", func(t *testing.T) {
+		h, _ := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+
+		get(h, url.Values{"query": {q}, "extensions": {extensions}})
+		resp := get(h, url.Values{"extensions": {extensions}})
+
+		assert.Nil(t, resp["errors"])
+		assert.Equal(t, map[string]any{"expense": map[string]any{"title": "Taxi"}}, resp["data"])
+	})
+
+	t.Run("should reject a hash which is not the hash of the query", func(t *testing.T) {
+		h, _ := newHandler(TagsStub{}, &CategoriesStub{}, 1000)
+		body, _ := json.Marshal(Request{Query: `{ tags { id } }`, Extensions: Extensions{PersistedQuery: &PersistedQuery{Version: 1, Sha256Hash: hash}}})
+
+		rec, _ := post(h, string(body))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		_, ok := h.persisted.Get(hash)
+		assert.False(t, ok)
+	})
+}
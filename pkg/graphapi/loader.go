@@ -0,0 +1,85 @@
+package graphapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Loader batches the keys loaded within wait of each other into one fetch, so
+// resolving a field of every item of a list makes one query instead of one per
+// item. The results are kept by key, a Loader serves a single request.
+type Loader[K comparable, V any] struct {
+	fetch   func(ctx context.Context, keys []K) (map[K]V, error)
+	wait    time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	batches map[K]*batch[K, V]
+	pending *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	full   chan struct{}
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+// NewLoader returns a Loader fetching up to maxSize keys at once. fetch
+// leaves out the keys without value.
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error), wait time.Duration, maxSize int) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, wait: wait, maxSize: maxSize, batches: map[K]*batch[K, V]{}}
+}
+
+// Load returns the value of key and whether it has one.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &batch[K, V]{full: make(chan struct{}), done: make(chan struct{})}
+			go l.run(ctx, l.pending)
+		}
+		b = l.pending
+		b.keys = append(b.keys, key)
+		l.batches[key] = b
+		if len(b.keys) >= l.maxSize {
+			l.pending = nil
+			close(b.full)
+		}
+	}
+	l.mu.Unlock()
+
+	var value V
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+	if b.err != nil {
+		return value, false, b.err
+	}
+	value, ok = b.values[key]
+	return value, ok, nil
+}
+
+func (l *Loader[K, V]) run(ctx context.Context, b *batch[K, V]) {
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-b.full:
+	}
+
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	keys := b.keys
+	l.mu.Unlock()
+
+	b.values, b.err = l.fetch(ctx, keys)
+	close(b.done)
+}
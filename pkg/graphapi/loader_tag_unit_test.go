@@ -0,0 +1,102 @@
+//go:build unit
+
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fetchStub doubles the keys and records the batches it was called with.
+type fetchStub struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (f *fetchStub) fetch(ctx context.Context, keys []int) (map[int]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, keys)
+	if f.err != nil {
+		return nil, f.err
+	}
+	result := map[int]int{}
+	for _, k := range keys {
+		if k >= 0 {
+			result[k] = 2 * k
+		}
+	}
+	return result, nil
+}
+
+func loadAll(l *Loader[int, int], keys ...int) []int {
+	values := make([]int, len(keys))
+	var wg sync.WaitGroup
+	for i, k := range keys {
+		wg.Add(1)
+		go func(i, k int) {
+			defer wg.Done()
+			values[i], _, _ = l.Load(context.Background(), k)
+		}(i, k)
+	}
+	wg.Wait()
+	return values
+}
+
+func TestLoader(t *testing.T) {
+	t.Run("should fetch the keys loaded together at once", func(t *testing.T) {
+		stub := &fetchStub{}
+		l := NewLoader(stub.fetch, 20*time.Millisecond, 100)
+
+		values := loadAll(l, 1, 2, 3)
+
+		assert.Equal(t, []int{2, 4, 6}, values)
+		assert.Len(t, stub.batches, 1)
+		assert.ElementsMatch(t, []int{1, 2, 3}, stub.batches[0])
+	})
+
+	t.Run("should not fetch a key twice", func(t *testing.T) {
+		stub := &fetchStub{}
+		l := NewLoader(stub.fetch, time.Millisecond, 100)
+
+		loadAll(l, 1)
+		value, ok, err := l.Load(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 2, value)
+		assert.Len(t, stub.batches, 1)
+	})
+
+	t.Run("should fetch a full batch without waiting", func(t *testing.T) {
+		stub := &fetchStub{}
+		l := NewLoader(stub.fetch, time.Hour, 2)
+
+		values := loadAll(l, 1, 2)
+
+		assert.Equal(t, []int{2, 4}, values)
+	})
+
+	t.Run("should tell a key without value", func(t *testing.T) {
+		l := NewLoader((&fetchStub{}).fetch, time.Millisecond, 100)
+
+		_, ok, err := l.Load(context.Background(), -1)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("should return the error of the fetch to every key", func(t *testing.T) {
+		l := NewLoader((&fetchStub{err: errors.New("down")}).fetch, time.Millisecond, 100)
+
+		_, _, err := l.Load(context.Background(), 1)
+
+		assert.EqualError(t, err, "down")
+	})
+}
@@ -0,0 +1,37 @@
+package graphapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/cache"
+)
+
+// persistedTtl is how long a persisted query is kept after it was sent.
+const persistedTtl = 24 * time.Hour
+
+// Persisted keeps the queries by their sha256 hash, a client sends the hash
+// alone once the query was sent with it (automatic persisted queries).
+type Persisted struct {
+	queries *cache.LRU[string, string]
+}
+
+func NewPersisted(size int) *Persisted {
+	return &Persisted{queries: cache.NewLRU[string, string](size, persistedTtl)}
+}
+
+// Get returns the query of hash.
+func (p *Persisted) Get(hash string) (string, bool) {
+	return p.queries.Get(hash)
+}
+
+// Add keeps query under hash and reports whether hash is the hash of query.
+func (p *Persisted) Add(hash string, query string) bool {
+	sum := sha256.Sum256([]byte(query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return false
+	}
+	p.queries.Add(hash, query, p.queries.Generation())
+	return true
+}
@@ -0,0 +1,21 @@
+package graphapi
+
+// Request is a GraphQL request, the json body of a POST or the query
+// parameters of a GET.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    Extensions     `json:"extensions"`
+}
+
+type Extensions struct {
+	// PersistedQuery holds the sha256 hash of the query, sent with the query
+	// to persist it or alone to run the persisted query.
+	PersistedQuery *PersistedQuery `json:"persistedQuery"`
+}
+
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
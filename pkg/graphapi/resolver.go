@@ -0,0 +1,389 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/categories"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tags"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// loaderWait is how long a Loader waits for more keys before it fetches.
+	loaderWait = 2 * time.Millisecond
+	// loaderMaxSize is the number of keys fetched at once.
+	loaderMaxSize = 100
+)
+
+// Tags lists the tags with the number of their expenses, like tags.Service.
+type Tags interface {
+	ListTags(ctx context.Context) ([]tags.TagResponse, error)
+}
+
+// Categories reads the categories and their spending, like
+// categories.Service.
+type Categories interface {
+	ListCategories(ctx context.Context) ([]categories.CategoryResponse, error)
+	SearchCategoriesByIds(ctx context.Context, ids []int64) ([]categories.CategoryResponse, error)
+	SummaryCategories(ctx context.Context) ([]categories.SummaryResponse, error)
+}
+
+// Resolver resolves the queries of schema.graphql with the services.
+type Resolver struct {
+	expenseService  expenses.Services
+	tagService      Tags
+	categoryService Categories
+}
+
+// NewResolver returns the Resolver of the services, t and c are nil when the
+// backend does not support them.
+func NewResolver(e expenses.Services, t Tags, c Categories) *Resolver {
+	return &Resolver{expenseService: e, tagService: t, categoryService: c}
+}
+
+// loaders are the Loaders of a request.
+type loaders struct {
+	categories *Loader[int64, categories.CategoryResponse]
+	tagged     *Loader[string, []expenses.ExpensesResponse]
+
+	summaryOnce sync.Once
+	summary     map[int64]categories.SummaryResponse
+	summaryErr  error
+}
+
+type loadersKey struct{}
+
+// withLoaders returns ctx carrying new loaders of r.
+func (r *Resolver) withLoaders(ctx context.Context) context.Context {
+	l := &loaders{
+		tagged: NewLoader(r.fetchTagged, loaderWait, loaderMaxSize),
+	}
+	if r.categoryService != nil {
+		l.categories = NewLoader(r.fetchCategories, loaderWait, loaderMaxSize)
+	}
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersOf(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func (r *Resolver) fetchCategories(ctx context.Context, ids []int64) (map[int64]categories.CategoryResponse, error) {
+	resp, err := r.categoryService.SearchCategoriesByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := map[int64]categories.CategoryResponse{}
+	for _, c := range resp {
+		result[c.Id] = c
+	}
+	return result, nil
+}
+
+// fetchTagged reads the expenses of each tag. A single tag is filtered by the
+// storage, several tags read every expense once and group them.
+func (r *Resolver) fetchTagged(ctx context.Context, names []string) (map[string][]expenses.ExpensesResponse, error) {
+	if len(names) == 1 {
+		resp, err := r.expenseService.SearchExpensesAll(ctx, expenses.Filter{Tags: names})
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]expenses.ExpensesResponse{names[0]: resp}, nil
+	}
+
+	resp, err := r.expenseService.SearchExpensesAll(ctx, expenses.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string][]expenses.ExpensesResponse{}
+	for _, exp := range resp {
+		for _, key := range tags.Keys(exp.Tags) {
+			byKey[key] = append(byKey[key], exp)
+		}
+	}
+	result := map[string][]expenses.ExpensesResponse{}
+	for _, name := range names {
+		result[name] = byKey[tags.Key(name)]
+	}
+	return result, nil
+}
+
+// categorySummary returns the spending of the category, read once for every
+// category of the request.
+func (r *Resolver) categorySummary(ctx context.Context, id int64) (categories.SummaryResponse, error) {
+	l := loadersOf(ctx)
+	l.summaryOnce.Do(func() {
+		var resp []categories.SummaryResponse
+		resp, l.summaryErr = r.categoryService.SummaryCategories(ctx)
+		l.summary = map[int64]categories.SummaryResponse{}
+		for _, s := range resp {
+			l.summary[s.Id] = s
+		}
+	})
+	return l.summary[id], l.summaryErr
+}
+
+func (r *Resolver) Expense(ctx context.Context, args struct{ ID graphql.ID }) (*expenseResolver, error) {
+	id, err := strconv.ParseInt(string(args.ID), 10, 64)
+	if err != nil {
+		return nil, errInvalidId
+	}
+	resp, err := r.expenseService.SearchExpensesById(ctx, id)
+	var cmErr *common.Error
+	if errors.As(err, &cmErr) && cmErr.Code == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return &expenseResolver{r, *resp}, nil
+}
+
+func (r *Resolver) Expenses(ctx context.Context, args struct{ Tags *[]string }) ([]*expenseResolver, error) {
+	filter := expenses.Filter{}
+	if args.Tags != nil {
+		filter.Tags = *args.Tags
+	}
+	resp, err := r.expenseService.SearchExpensesAll(ctx, filter)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return r.expenseResolvers(resp), nil
+}
+
+func (r *Resolver) expenseResolvers(resp []expenses.ExpensesResponse) []*expenseResolver {
+	result := make([]*expenseResolver, len(resp))
+	for i := range resp {
+		result[i] = &expenseResolver{r, resp[i]}
+	}
+	return result
+}
+
+func (r *Resolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	if r.tagService == nil {
+		return nil, resolverError(common.ErrUnsupported)
+	}
+	resp, err := r.tagService.ListTags(ctx)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	result := make([]*tagResolver, len(resp))
+	for i := range resp {
+		result[i] = &tagResolver{r, resp[i]}
+	}
+	return result, nil
+}
+
+func (r *Resolver) Categories(ctx context.Context) ([]*categoryResolver, error) {
+	if r.categoryService == nil {
+		return nil, resolverError(common.ErrUnsupported)
+	}
+	resp, err := r.categoryService.ListCategories(ctx)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	result := make([]*categoryResolver, len(resp))
+	for i := range resp {
+		result[i] = &categoryResolver{r, resp[i]}
+	}
+	return result, nil
+}
+
+func (r *Resolver) Summary(ctx context.Context, args struct{ Tags *[]string }) (*summaryResolver, error) {
+	filter := expenses.Filter{}
+	if args.Tags != nil {
+		filter.Tags = *args.Tags
+	}
+	resp, err := r.expenseService.SearchExpensesAll(ctx, filter)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+
+	summary := &summaryResolver{}
+	byKey := map[string]*tagTotalResolver{}
+	for _, exp := range resp {
+		summary.count++
+		summary.amount += exp.Amount
+		for _, name := range exp.Tags {
+			total, ok := byKey[tags.Key(name)]
+			if !ok {
+				total = &tagTotalResolver{tag: name}
+				byKey[tags.Key(name)] = total
+				summary.tags = append(summary.tags, total)
+			}
+			total.count++
+			total.amount += exp.Amount
+		}
+	}
+	sort.Slice(summary.tags, func(i, j int) bool { return summary.tags[i].tag < summary.tags[j].tag })
+	return summary, nil
+}
+
+type expenseResolver struct {
+	root *Resolver
+	exp  expenses.ExpensesResponse
+}
+
+func (e *expenseResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(e.exp.Id, 10))
+}
+
+func (e *expenseResolver) Title() string {
+	return e.exp.Title
+}
+
+func (e *expenseResolver) Amount() float64 {
+	return e.exp.Amount
+}
+
+func (e *expenseResolver) Note() string {
+	return e.exp.Note
+}
+
+func (e *expenseResolver) Tags() []string {
+	if e.exp.Tags == nil {
+		return []string{}
+	}
+	return e.exp.Tags
+}
+
+func (e *expenseResolver) Category(ctx context.Context) (*categoryResolver, error) {
+	return e.root.category(ctx, e.exp.CategoryId)
+}
+
+// category loads the category of id, nil when there is none.
+func (r *Resolver) category(ctx context.Context, id *int64) (*categoryResolver, error) {
+	l := loadersOf(ctx)
+	if id == nil || l.categories == nil {
+		return nil, nil
+	}
+	c, ok, err := l.categories.Load(ctx, *id)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &categoryResolver{r, c}, nil
+}
+
+type tagResolver struct {
+	root *Resolver
+	tag  tags.TagResponse
+}
+
+func (t *tagResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(t.tag.Id, 10))
+}
+
+func (t *tagResolver) Name() string {
+	return t.tag.Name
+}
+
+func (t *tagResolver) Count() int32 {
+	return int32(t.tag.Count)
+}
+
+func (t *tagResolver) Expenses(ctx context.Context) ([]*expenseResolver, error) {
+	resp, _, err := loadersOf(ctx).tagged.Load(ctx, t.tag.Name)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return t.root.expenseResolvers(resp), nil
+}
+
+type categoryResolver struct {
+	root     *Resolver
+	category categories.CategoryResponse
+}
+
+func (c *categoryResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(c.category.Id, 10))
+}
+
+func (c *categoryResolver) Name() string {
+	return c.category.Name
+}
+
+func (c *categoryResolver) Parent(ctx context.Context) (*categoryResolver, error) {
+	return c.root.category(ctx, c.category.ParentId)
+}
+
+func (c *categoryResolver) Amount(ctx context.Context) (float64, error) {
+	s, err := c.root.categorySummary(ctx, c.category.Id)
+	if err != nil {
+		return 0, resolverError(err)
+	}
+	return s.Amount, nil
+}
+
+func (c *categoryResolver) Count(ctx context.Context) (int32, error) {
+	s, err := c.root.categorySummary(ctx, c.category.Id)
+	if err != nil {
+		return 0, resolverError(err)
+	}
+	return int32(s.Count), nil
+}
+
+func (c *categoryResolver) TotalAmount(ctx context.Context) (float64, error) {
+	s, err := c.root.categorySummary(ctx, c.category.Id)
+	if err != nil {
+		return 0, resolverError(err)
+	}
+	return s.TotalAmount, nil
+}
+
+func (c *categoryResolver) TotalCount(ctx context.Context) (int32, error) {
+	s, err := c.root.categorySummary(ctx, c.category.Id)
+	if err != nil {
+		return 0, resolverError(err)
+	}
+	return int32(s.TotalCount), nil
+}
+
+type summaryResolver struct {
+	count  int32
+	amount float64
+	tags   []*tagTotalResolver
+}
+
+func (s *summaryResolver) Count() int32 {
+	return s.count
+}
+
+func (s *summaryResolver) Amount() float64 {
+	return s.amount
+}
+
+func (s *summaryResolver) Tags() []*tagTotalResolver {
+	if s.tags == nil {
+		return []*tagTotalResolver{}
+	}
+	return s.tags
+}
+
+type tagTotalResolver struct {
+	tag    string
+	count  int32
+	amount float64
+}
+
+func (t *tagTotalResolver) Tag() string {
+	return t.tag
+}
+
+func (t *tagTotalResolver) Count() int32 {
+	return t.count
+}
+
+func (t *tagTotalResolver) Amount() float64 {
+	return t.amount
+}
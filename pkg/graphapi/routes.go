@@ -0,0 +1,67 @@
+package graphapi
+
+import (
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+)
+
+// maxParallelism is the number of fields of a query resolved at once, it
+// bounds the keys a Loader batches.
+const maxParallelism = 100
+
+// requestSchema is the schema of a query, Query is left out by the persisted
+// ones.
+var requestSchema = &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+	"query":         {Type: "string"},
+	"operationName": {Type: "string"},
+	"variables":     {Type: []string{"object", "null"}},
+	"extensions":    {Type: []string{"object", "null"}},
+}}
+
+// responseSchema is the schema of graphql.Response, inline as health.Response
+// has the same name.
+var responseSchema = &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+	"data":       {},
+	"errors":     {Type: "array", Items: &openapi.Schema{Type: "object"}},
+	"extensions": {Type: "object"},
+}}
+
+// Operations describe the routes of Routes in the OpenAPI document, the
+// schema of the queries is Schema.
+var Operations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/graphql", Id: "QueryGet", Summary: "Run a GraphQL query, variables and extensions are json", Tag: "graphql",
+		Params: []openapi.Param{
+			{Name: "query", In: "query", Schema: ""},
+			{Name: "operationName", In: "query", Schema: ""},
+			{Name: "variables", In: "query", Schema: ""},
+			{Name: "extensions", In: "query", Schema: ""},
+		}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the result", Body: responseSchema},
+			{Status: http.StatusBadRequest, Description: "invalid request", Body: responseSchema},
+		}},
+	{Method: http.MethodPost, Path: "/graphql", Id: "Query", Summary: "Run a GraphQL query", Tag: "graphql",
+		Body: requestSchema, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the result", Body: responseSchema},
+			{Status: http.StatusBadRequest, Description: "invalid request", Body: responseSchema},
+		}},
+}
+
+// Routes serves the GraphQL api of the services on /graphql, t and c are nil
+// when the backend does not support them.
+func Routes(echo *echo.Echo, ins *config.Instance, e expenses.Services, t Tags, c Categories) {
+	cf := ins.Config.Get()
+	resolver := NewResolver(e, t, c)
+	schema := graphql.MustParseSchema(Schema, resolver,
+		graphql.MaxDepth(cf.GraphqlMaxDepth()),
+		graphql.MaxParallelism(maxParallelism),
+	)
+	handler := NewHandler(schema, resolver, NewPersisted(cf.GraphqlPersistedSize()), cf.GraphqlMaxComplexity(), ins.Log)
+
+	echo.GET("/graphql", handler.Query)
+	echo.POST("/graphql", handler.Query)
+}
@@ -0,0 +1,8 @@
+// Package graphapi serves the expenses, their tags and their aggregates as a
+// GraphQL api, the schema is schema.graphql.
+package graphapi
+
+import _ "embed"
+
+//go:embed schema.graphql
+var Schema string
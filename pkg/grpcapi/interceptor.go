@@ -0,0 +1,92 @@
+package grpcapi
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator returns the name of the api key key, like
+// config.Config.Authenticate.
+type Authenticator func(key string) (string, bool)
+
+// authenticate checks the api key of the authorization metadata and returns
+// ctx carrying the name of the key as the actor, and the request id of the
+// x-request-id metadata.
+func authenticate(ctx context.Context, auth Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get("x-request-id"); len(ids) > 0 {
+		ctx = common.WithRequestId(ctx, ids[0])
+	}
+	if keys := md.Get("authorization"); len(keys) > 0 {
+		if name, ok := auth(keys[0]); ok {
+			return common.WithActor(ctx, name), nil
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "Please provide valid credentials")
+}
+
+func UnaryAuth(auth Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamAuth(auth Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), auth)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// UnaryRecover logs the panic of a call and answers it Internal, like the
+// Recover middleware of the http api.
+func UnaryRecover(log common.Log) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				resp, err = nil, recovered(log, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecover logs the panic of a stream and answers it Internal.
+func StreamRecover(log common.Log) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(log, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(log common.Log, method string, r any) error {
+	log.Errorf("gRPC %s Panic : %v\n%s", method, r, debug.Stack())
+	return status.Error(codes.Internal, "internal error")
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
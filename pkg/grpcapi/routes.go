@@ -0,0 +1,22 @@
+package grpcapi
+
+import (
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/grpcapi/expensespb"
+	"google.golang.org/grpc"
+)
+
+// New returns a gRPC server serving the expenses of service to the callers
+// holding an api key of the configuration, a panic is answered Internal.
+func New(ins *config.Instance, service expenses.Services) *grpc.Server {
+	auth := func(key string) (string, bool) {
+		return ins.Config.Get().Authenticate(key)
+	}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryRecover(ins.Log), UnaryAuth(auth)),
+		grpc.ChainStreamInterceptor(StreamRecover(ins.Log), StreamAuth(auth)),
+	)
+	expensespb.RegisterExpensesServiceServer(srv, NewServer(service, ins.Log))
+	return srv
+}
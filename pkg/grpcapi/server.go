@@ -0,0 +1,129 @@
+// Package grpcapi serves the expenses over gRPC with the Service of the REST
+// api, the definition of the api is expensespb/expenses.proto.
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/grpcapi/expensespb"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	expensespb.UnimplementedExpensesServiceServer
+	log     common.Log
+	service expenses.Services
+}
+
+func NewServer(s expenses.Services, l common.Log) *Server {
+	return &Server{service: s, log: l}
+}
+
+func (s *Server) CreateExpense(ctx context.Context, req *expensespb.CreateExpenseRequest) (*expensespb.Expense, error) {
+	ctx, span := tracing.Start(ctx, "Grpc.CreateExpense")
+	defer span.End()
+
+	if req.GetExpense() == nil {
+		return nil, status.Error(codes.InvalidArgument, "expense is required")
+	}
+
+	resp, err := s.service.AddExpenses(ctx, toRequest(req.GetExpense()))
+	if err != nil {
+		return nil, s.statusError("CreateExpense", err)
+	}
+
+	return toExpense(resp), nil
+}
+
+func (s *Server) GetExpense(ctx context.Context, req *expensespb.GetExpenseRequest) (*expensespb.Expense, error) {
+	ctx, span := tracing.Start(ctx, "Grpc.GetExpense")
+	defer span.End()
+
+	resp, err := s.service.SearchExpensesById(ctx, req.GetId())
+	if err != nil {
+		return nil, s.statusError("GetExpense", err)
+	}
+
+	return toExpense(resp), nil
+}
+
+func (s *Server) UpdateExpense(ctx context.Context, req *expensespb.UpdateExpenseRequest) (*expensespb.Expense, error) {
+	ctx, span := tracing.Start(ctx, "Grpc.UpdateExpense")
+	defer span.End()
+
+	if req.GetExpense() == nil {
+		return nil, status.Error(codes.InvalidArgument, "expense is required")
+	}
+
+	resp, err := s.service.UpdateExpenses(ctx, req.GetId(), toRequest(req.GetExpense()))
+	if err != nil {
+		return nil, s.statusError("UpdateExpense", err)
+	}
+
+	return toExpense(resp), nil
+}
+
+// ListExpenses sends the expenses one message each, the stream ends early when
+// the client goes away.
+func (s *Server) ListExpenses(req *expensespb.ListExpensesRequest, stream expensespb.ExpensesService_ListExpensesServer) error {
+	ctx, span := tracing.Start(stream.Context(), "Grpc.ListExpenses")
+	defer span.End()
+
+	resp, err := s.service.SearchExpensesAll(ctx, expenses.Filter{Tags: req.GetTags()})
+	if err != nil {
+		return s.statusError("ListExpenses", err)
+	}
+
+	for i := range resp {
+		if err := stream.Send(toExpense(&resp[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) DeleteExpense(ctx context.Context, req *expensespb.DeleteExpenseRequest) (*expensespb.DeleteExpenseResponse, error) {
+	ctx, span := tracing.Start(ctx, "Grpc.DeleteExpense")
+	defer span.End()
+
+	if err := s.service.DeleteExpenses(ctx, req.GetId()); err != nil {
+		return nil, s.statusError("DeleteExpense", err)
+	}
+
+	return &expensespb.DeleteExpenseResponse{}, nil
+}
+
+// statusError reports a *common.Error with the code of its http status, the
+// other errors are internal and only logged.
+func (s *Server) statusError(method string, err error) error {
+	if cmErr, ok := err.(*common.Error); ok {
+		return status.Error(Code(cmErr.Code), cmErr.Desc)
+	}
+	s.log.Errorf("Grpc %s Error : %s", method, err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func toRequest(in *expensespb.ExpenseInput) expenses.ExpensesRequest {
+	return expenses.ExpensesRequest{
+		Title:      in.GetTitle(),
+		Amount:     in.GetAmount(),
+		Note:       in.GetNote(),
+		Tags:       in.GetTags(),
+		CategoryId: in.CategoryId,
+	}
+}
+
+func toExpense(resp *expenses.ExpensesResponse) *expensespb.Expense {
+	return &expensespb.Expense{
+		Id:         resp.Id,
+		Title:      resp.Title,
+		Amount:     resp.Amount,
+		Note:       resp.Note,
+		Tags:       resp.Tags,
+		CategoryId: resp.CategoryId,
+	}
+}
@@ -0,0 +1,270 @@
+//go:build unit
+
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/grpcapi/expensespb"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// ServiceStub keeps the expenses in memory and fails every call with err.
+type ServiceStub struct {
+	expenses []expenses.ExpensesResponse
+	err      error
+	actor    string
+	filter   expenses.Filter
+}
+
+func (s *ServiceStub) AddExpenses(ctx context.Context, req expenses.ExpensesRequest) (*expenses.ExpensesResponse, error) {
+	s.actor = common.Actor(ctx)
+	if s.err != nil {
+		return nil, s.err
+	}
+	exp := expenses.ExpensesResponse{Id: int64(len(s.expenses) + 1), Title: req.Title, Amount: req.Amount, Note: req.Note, Tags: req.Tags, CategoryId: req.CategoryId}
+	s.expenses = append(s.expenses, exp)
+	return &exp, nil
+}
+
+func (s *ServiceStub) SearchExpensesById(ctx context.Context, id int64) (*expenses.ExpensesResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, exp := range s.expenses {
+		if exp.Id == id {
+			return &exp, nil
+		}
+	}
+	return nil, &common.Error{Code: http.StatusNotFound, Desc: "Expenses not found"}
+}
+
+func (s *ServiceStub) UpdateExpenses(ctx context.Context, id int64, req expenses.ExpensesRequest) (*expenses.ExpensesResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	exp := expenses.ExpensesResponse{Id: id, Title: req.Title, Amount: req.Amount, Note: req.Note, Tags: req.Tags}
+	return &exp, nil
+}
+
+func (s *ServiceStub) SearchExpensesAll(ctx context.Context, filter expenses.Filter) ([]expenses.ExpensesResponse, error) {
+	s.filter = filter
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.expenses, nil
+}
+
+func (s *ServiceStub) DeleteExpenses(ctx context.Context, id int64) error {
+	return s.err
+}
+
+func (s *ServiceStub) ExpensesHistory(ctx context.Context, id int64) ([]expenses.HistoryResponse, error) {
+	return nil, s.err
+}
+
+func (s *ServiceStub) SearchExpensesAsOf(ctx context.Context, id int64, at time.Time) (*expenses.ExpensesResponse, error) {
+	return nil, s.err
+}
+
+func (s *ServiceStub) RevertExpenses(ctx context.Context, id int64, req expenses.RevertRequest) (*expenses.ExpensesResponse, error) {
+	return nil, s.err
+}
+
+// dial serves service in memory with the api key "secret" of alice and
+// returns a client of it.
+func dial(t *testing.T, service expenses.Services) expensespb.ExpensesServiceClient {
+	auth := func(key string) (string, bool) {
+		return "alice", key == "secret"
+	}
+	log := logrus.New()
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryRecover(log), UnaryAuth(auth)),
+		grpc.ChainStreamInterceptor(StreamRecover(log), StreamAuth(auth)),
+	)
+	expensespb.RegisterExpensesServiceServer(srv, NewServer(service, log))
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return expensespb.NewExpensesServiceClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", key)
+}
+
+func TestServer(t *testing.T) {
+	t.Run("should create the expense as the actor of the api key", func(t *testing.T) {
+		service := &ServiceStub{}
+		client := dial(t, service)
+		categoryId := int64(3)
+
+		resp, err := client.CreateExpense(withKey("secret"), &expensespb.CreateExpenseRequest{Expense: &expensespb.ExpenseInput{
+			Title: "Coffee", Amount: 60, Note: "morning", Tags: []string{"food"}, CategoryId: &categoryId,
+		}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), resp.Id)
+		assert.Equal(t, "Coffee", resp.Title)
+		assert.Equal(t, 60.0, resp.Amount)
+		assert.Equal(t, []string{"food"}, resp.Tags)
+		assert.Equal(t, categoryId, resp.GetCategoryId())
+		assert.Equal(t, "alice", service.actor)
+	})
+
+	t.Run("should reject a create without expense", func(t *testing.T) {
+		_, err := dial(t, &ServiceStub{}).CreateExpense(withKey("secret"), &expensespb.CreateExpenseRequest{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should return NotFound for an expense that does not exist", func(t *testing.T) {
+		_, err := dial(t, &ServiceStub{}).GetExpense(withKey("secret"), &expensespb.GetExpenseRequest{Id: 7})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("should update the expense", func(t *testing.T) {
+		resp, err := dial(t, &ServiceStub{}).UpdateExpense(withKey("secret"), &expensespb.UpdateExpenseRequest{Id: 2, Expense: &expensespb.ExpenseInput{Title: "Tea", Amount: 40}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), resp.Id)
+		assert.Equal(t, "Tea", resp.Title)
+	})
+
+	t.Run("should stream every expense of the tags", func(t *testing.T) {
+		service := &ServiceStub{expenses: []expenses.ExpensesResponse{{Id: 1, Title: "Coffee"}, {Id: 2, Title: "Tea"}}}
+
+		stream, err := dial(t, service).ListExpenses(withKey("secret"), &expensespb.ListExpensesRequest{Tags: []string{"food"}})
+		assert.NoError(t, err)
+		ids := []int64{}
+		for {
+			exp, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			ids = append(ids, exp.Id)
+		}
+
+		assert.Equal(t, []int64{1, 2}, ids)
+		assert.Equal(t, []string{"food"}, service.filter.Tags)
+	})
+
+	t.Run("should delete the expense", func(t *testing.T) {
+		_, err := dial(t, &ServiceStub{}).DeleteExpense(withKey("secret"), &expensespb.DeleteExpenseRequest{Id: 1})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("should map the http status of the error to its code", func(t *testing.T) {
+		service := &ServiceStub{err: &common.Error{Code: http.StatusNotFound, Desc: "not found"}}
+
+		_, err := dial(t, service).DeleteExpense(withKey("secret"), &expensespb.DeleteExpenseRequest{Id: 1})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, "not found", status.Convert(err).Message())
+	})
+
+	t.Run("should return FailedPrecondition for the delete of an expense with attachments", func(t *testing.T) {
+		service := &ServiceStub{err: &common.Error{Code: common.StatusFromError(common.ErrConflict), Desc: "Delete Expenses Error", OriginalError: common.ErrConflict}}
+
+		_, err := dial(t, service).DeleteExpense(withKey("secret"), &expensespb.DeleteExpenseRequest{Id: 1})
+
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("should hide an unexpected error as Internal", func(t *testing.T) {
+		service := &ServiceStub{err: errors.New("connection refused")}
+
+		_, err := dial(t, service).GetExpense(withKey("secret"), &expensespb.GetExpenseRequest{Id: 1})
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.NotContains(t, status.Convert(err).Message(), "connection refused")
+	})
+}
+
+func TestAuth(t *testing.T) {
+	t.Run("should reject a call without api key", func(t *testing.T) {
+		_, err := dial(t, &ServiceStub{}).GetExpense(context.Background(), &expensespb.GetExpenseRequest{Id: 1})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("should reject a stream with a wrong api key", func(t *testing.T) {
+		service := &ServiceStub{}
+
+		stream, err := dial(t, service).ListExpenses(withKey("guess"), &expensespb.ListExpensesRequest{})
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Nil(t, service.filter.Tags)
+	})
+}
+
+// PanicStub panics in every call.
+type PanicStub struct {
+	ServiceStub
+}
+
+func (s *PanicStub) SearchExpensesById(ctx context.Context, id int64) (*expenses.ExpensesResponse, error) {
+	panic("nil map")
+}
+
+func (s *PanicStub) SearchExpensesAll(ctx context.Context, filter expenses.Filter) ([]expenses.ExpensesResponse, error) {
+	panic("nil map")
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("should answer a panic of a call as Internal", func(t *testing.T) {
+		_, err := dial(t, &PanicStub{}).GetExpense(withKey("secret"), &expensespb.GetExpenseRequest{Id: 1})
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.NotContains(t, status.Convert(err).Message(), "nil map")
+	})
+
+	t.Run("should answer a panic of a stream as Internal", func(t *testing.T) {
+		stream, err := dial(t, &PanicStub{}).ListExpenses(withKey("secret"), &expensespb.ListExpensesRequest{})
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestCode(t *testing.T) {
+	cases := map[int]codes.Code{
+		http.StatusBadRequest:            codes.InvalidArgument,
+		http.StatusNotFound:              codes.NotFound,
+		http.StatusConflict:              codes.FailedPrecondition,
+		http.StatusNotImplemented:        codes.Unimplemented,
+		http.StatusGatewayTimeout:        codes.DeadlineExceeded,
+		common.StatusClientClosedRequest: codes.Canceled,
+		http.StatusInternalServerError:   codes.Internal,
+	}
+	for httpStatus, want := range cases {
+		assert.Equal(t, want, Code(httpStatus), "status %d", httpStatus)
+	}
+}
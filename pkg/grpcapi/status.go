@@ -0,0 +1,38 @@
+package grpcapi
+
+import (
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"google.golang.org/grpc/codes"
+)
+
+// Code maps the http status of a common.Error to the gRPC code reporting the
+// same failure. A conflict is FailedPrecondition: the expenses conflict while
+// they have attachments, not on a duplicate.
+func Code(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case common.StatusClientClosedRequest:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check reports an error when the dependency it probes is unusable.
+type Check func(ctx context.Context) error
+
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type Health struct {
+	timeout    time.Duration
+	drainDelay time.Duration
+	checks     []namedCheck
+	draining   atomic.Bool
+}
+
+// New creates a Health whose readiness checks share timeout. drainDelay is
+// how long Drain keeps the process serving after readiness starts failing.
+func New(timeout time.Duration, drainDelay time.Duration) *Health {
+	return &Health{timeout: timeout, drainDelay: drainDelay}
+}
+
+// AddCheck registers a readiness check. It is not safe to call once the
+// routes are served.
+func (h *Health) AddCheck(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Drain makes readiness fail and waits for the drain delay so load balancers
+// stop routing new traffic before the server is shut down.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+	time.Sleep(h.drainDelay)
+}
+
+func (h *Health) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, Response{Status: StatusUp})
+}
+
+func (h *Health) Readiness(c echo.Context) error {
+	if h.draining.Load() {
+		return c.JSON(http.StatusServiceUnavailable, Response{Status: StatusDown, Checks: map[string]string{"draining": StatusDown}})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	resp := Response{Status: StatusUp, Checks: map[string]string{}}
+	for _, nc := range h.checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			err := nc.check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				resp.Status = StatusDown
+				resp.Checks[nc.name] = err.Error()
+				return
+			}
+			resp.Checks[nc.name] = StatusUp
+		}(nc)
+	}
+	wg.Wait()
+
+	if resp.Status != StatusUp {
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// IsProbe reports whether path is one of the probe routes, which are served
+// without authentication.
+func IsProbe(path string) bool {
+	return path == LivenessPath || path == ReadinessPath
+}
+
+// Operations describe the probes in the OpenAPI document.
+var Operations = []openapi.Operation{
+	{Method: http.MethodGet, Path: LivenessPath, Id: "Liveness", Summary: "Liveness probe", Tag: "health", Public: true,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the process is up", Body: Response{}},
+		}},
+	{Method: http.MethodGet, Path: ReadinessPath, Id: "Readiness", Summary: "Readiness probe", Tag: "health", Public: true,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "every check is up", Body: Response{}},
+			{Status: http.StatusServiceUnavailable, Description: "a check is down or the instance is draining", Body: Response{}},
+		}},
+}
+
+func Routes(echo *echo.Echo, h *Health) {
+	echo.GET(LivenessPath, h.Liveness)
+	echo.GET(ReadinessPath, h.Readiness)
+}
@@ -0,0 +1,87 @@
+//go:build unit
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(h *Health, path string) (*httptest.ResponseRecorder, Response) {
+	e := echo.New()
+	Routes(e, h)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	resp := Response{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func TestLiveness(t *testing.T) {
+	t.Run("should return http status code = 200 even when a readiness check fails", func(t *testing.T) {
+		h := New(time.Second, 0)
+		h.AddCheck("database", func(ctx context.Context) error { return errors.New("connection refused") })
+
+		rec, resp := serve(h, LivenessPath)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, StatusUp, resp.Status)
+	})
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("should return http status code = 200 when every check passes", func(t *testing.T) {
+		h := New(time.Second, 0)
+		h.AddCheck("database", func(ctx context.Context) error { return nil })
+		h.AddCheck("migrations", func(ctx context.Context) error { return nil })
+
+		rec, resp := serve(h, ReadinessPath)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, map[string]string{"database": StatusUp, "migrations": StatusUp}, resp.Checks)
+	})
+
+	t.Run("should return http status code = 503 when a check fails", func(t *testing.T) {
+		h := New(time.Second, 0)
+		h.AddCheck("database", func(ctx context.Context) error { return errors.New("connection refused") })
+		h.AddCheck("migrations", func(ctx context.Context) error { return nil })
+
+		rec, resp := serve(h, ReadinessPath)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, StatusDown, resp.Status)
+		assert.Equal(t, "connection refused", resp.Checks["database"])
+	})
+
+	t.Run("should return http status code = 503 when a check exceeds the timeout", func(t *testing.T) {
+		h := New(10*time.Millisecond, 0)
+		h.AddCheck("database", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		rec, _ := serve(h, ReadinessPath)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("should return http status code = 503 after Drain", func(t *testing.T) {
+		h := New(time.Second, 0)
+		h.AddCheck("database", func(ctx context.Context) error { return nil })
+
+		h.Drain()
+		rec, resp := serve(h, ReadinessPath)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, StatusDown, resp.Checks["draining"])
+	})
+}
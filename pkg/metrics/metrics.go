@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "expenses"
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of storage queries by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	expensesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "created_total",
+		Help:      "Number of expenses created.",
+	})
+
+	// amountRecorded is a gauge rather than a counter because an expense
+	// amount may be negative (refunds) and counters cannot decrease.
+	amountRecorded = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "amount_recorded",
+		Help:      "Sum of the amount of expenses created since the process started.",
+	})
+
+	outboxPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_published_total",
+		Help:      "Number of domain events published by the outbox relay.",
+	})
+
+	outboxFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_failures_total",
+		Help:      "Number of outbox relay runs that failed to publish an event.",
+	})
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of expense cache lookups by result, hit or miss.",
+	}, []string{"result"})
+	cacheInvalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_invalidations_total",
+		Help:      "Number of expense cache entries invalidated by a write.",
+	})
+)
+
+// Handler serves the metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDBStats exports the sql.DB.Stats() pool gauges of db.
+func RegisterDBStats(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Middleware records the duration of every request under its route pattern,
+// so /expenses/1 and /expenses/2 share the /expenses/:id series.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				if httpErr, ok := err.(*echo.HTTPError); ok {
+					status = httpErr.Code
+				} else {
+					status = http.StatusInternalServerError
+				}
+			}
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			httpRequestDuration.WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// ObserveQuery records the duration of a storage query started at start.
+// It is meant to be deferred: defer metrics.ObserveQuery("Insert", time.Now()).
+func ObserveQuery(method string, start time.Time) {
+	dbQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// ExpenseCreated updates the business counters for a newly created expense.
+func ExpenseCreated(amount float64) {
+	expensesCreated.Inc()
+	amountRecorded.Add(amount)
+}
+
+// OutboxPublished counts n events published by the outbox relay.
+func OutboxPublished(n int) {
+	outboxPublished.Add(float64(n))
+}
+
+// OutboxFailed counts a failed run of the outbox relay.
+func OutboxFailed() {
+	outboxFailures.Inc()
+}
+
+// CacheHit counts an expense read served by the cache.
+func CacheHit() {
+	cacheRequests.WithLabelValues("hit").Inc()
+}
+
+// CacheMiss counts an expense read the cache sent to the database.
+func CacheMiss() {
+	cacheRequests.WithLabelValues("miss").Inc()
+}
+
+// CacheInvalidated counts an expense removed from the cache by a write.
+func CacheInvalidated() {
+	cacheInvalidations.Inc()
+}
@@ -0,0 +1,67 @@
+//go:build unit
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleCount(t *testing.T, o prometheus.Observer) uint64 {
+	m := &dto.Metric{}
+	err := o.(prometheus.Metric).Write(m)
+	assert.NoError(t, err)
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("should observe request under route pattern and status", func(t *testing.T) {
+		e := echo.New()
+		e.Use(Middleware())
+		e.GET("/expenses/:id", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+		e.GET("/fail", func(c echo.Context) error {
+			return echo.NewHTTPError(http.StatusUnauthorized)
+		})
+
+		for _, path := range []string{"/expenses/1", "/expenses/2", "/fail"} {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		assert.Equal(t, uint64(2), sampleCount(t, httpRequestDuration.WithLabelValues(http.MethodGet, "/expenses/:id", "200")))
+		assert.Equal(t, uint64(1), sampleCount(t, httpRequestDuration.WithLabelValues(http.MethodGet, "/fail", "401")))
+	})
+}
+
+func TestBusinessCounters(t *testing.T) {
+	t.Run("should count created expenses and sum amount", func(t *testing.T) {
+		created := testutil.ToFloat64(expensesCreated)
+		amount := testutil.ToFloat64(amountRecorded)
+
+		ExpenseCreated(79)
+		ExpenseCreated(-10)
+
+		assert.Equal(t, created+2, testutil.ToFloat64(expensesCreated))
+		assert.Equal(t, amount+69, testutil.ToFloat64(amountRecorded))
+	})
+}
+
+func TestObserveQuery(t *testing.T) {
+	t.Run("should observe query duration per method", func(t *testing.T) {
+		before := sampleCount(t, dbQueryDuration.WithLabelValues("SearchById"))
+
+		ObserveQuery("SearchById", time.Now())
+
+		assert.Equal(t, before+1, sampleCount(t, dbQueryDuration.WithLabelValues("SearchById")))
+	})
+}
@@ -0,0 +1,84 @@
+//go:build unit
+
+package migration
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("should load migrations ordered by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"pg/0002_add_index.sql":     {Data: []byte("CREATE INDEX a ON b (c)")},
+			"pg/0001_create_table.sql":  {Data: []byte("CREATE TABLE b (c INT)")},
+			"pg/0010_add_other_tab.sql": {Data: []byte("CREATE TABLE d (e INT)")},
+		}
+
+		migrations, err := load(fsys, "pg")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 10}, []int{migrations[0].Version, migrations[1].Version, migrations[2].Version})
+		assert.Equal(t, "create_table", migrations[0].Name)
+	})
+
+	t.Run("should return error when file name has no version", func(t *testing.T) {
+		fsys := fstest.MapFS{"pg/create_table.sql": {Data: []byte("")}}
+
+		_, err := load(fsys, "pg")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should load embedded migrations of every dialect", func(t *testing.T) {
+		for _, dialect := range []string{"postgres", "sqlite"} {
+			migrations, err := load(files, dialect)
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, migrations)
+		}
+	})
+}
+
+func TestUp(t *testing.T) {
+	t.Run("should apply only migrations not recorded yet", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		m := &Migrator{db: db, migrations: []Migration{
+			{Version: 1, Name: "create_table", Sql: "CREATE TABLE b (c INT)"},
+			{Version: 2, Name: "add_index", Sql: "CREATE INDEX a ON b (c)"},
+		}}
+		mock.ExpectExec(regexp.QuoteMeta(createVersionTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM schema_migrations")).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX a ON b (c)")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name) VALUES (2, 'add_index')")).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = m.Up(context.Background())
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPending(t *testing.T) {
+	t.Run("should count migrations not recorded yet", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		m := &Migrator{db: db, migrations: []Migration{{Version: 1}, {Version: 2}, {Version: 3}}}
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM schema_migrations")).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+
+		pending, err := m.Pending(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, pending)
+	})
+}
@@ -0,0 +1,32 @@
+-- Tags are unique by key, the lower-cased name with whitespace collapsed, so
+-- "Food", "food" and "food " are the same tag. expenses.tags keeps the ordered
+-- display names as a copy of expense_tags for reads.
+CREATE TABLE IF NOT EXISTS tags (id SERIAL PRIMARY KEY, name TEXT NOT NULL, key TEXT NOT NULL UNIQUE);
+CREATE TABLE IF NOT EXISTS expense_tags (
+	expense_id INTEGER NOT NULL REFERENCES expenses (id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (expense_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS expense_tags_tag_id_idx ON expense_tags (tag_id);
+
+CREATE TEMPORARY TABLE expense_tag_keys ON COMMIT DROP AS
+	SELECT e.id AS expense_id, regexp_replace(btrim(t.name), '\s+', ' ', 'g') AS name,
+		lower(regexp_replace(btrim(t.name), '\s+', ' ', 'g')) AS key, t.pos
+	FROM expenses e, unnest(e.tags) WITH ORDINALITY AS t(name, pos)
+	WHERE btrim(t.name) <> '';
+
+-- the first spelling by expense id becomes the display name
+INSERT INTO tags (name, key)
+	SELECT DISTINCT ON (key) name, key FROM expense_tag_keys ORDER BY key, expense_id, pos
+	ON CONFLICT (key) DO NOTHING;
+
+INSERT INTO expense_tags (expense_id, tag_id)
+	SELECT DISTINCT k.expense_id, t.id FROM expense_tag_keys k JOIN tags t ON t.key = k.key
+	ON CONFLICT DO NOTHING;
+
+UPDATE expenses e SET tags = d.tags FROM (
+	SELECT f.expense_id, array_agg(t.name ORDER BY f.pos) AS tags
+	FROM (SELECT expense_id, key, min(pos) AS pos FROM expense_tag_keys GROUP BY expense_id, key) f
+	JOIN tags t ON t.key = f.key
+	GROUP BY f.expense_id
+) d WHERE e.id = d.expense_id;
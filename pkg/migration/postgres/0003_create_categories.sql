@@ -0,0 +1,11 @@
+-- Categories form a tree, a category cannot be deleted while it has children.
+-- Sibling names are unique whatever their case.
+CREATE TABLE IF NOT EXISTS categories (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	parent_id INTEGER REFERENCES categories (id) ON DELETE RESTRICT
+);
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_name_idx ON categories (coalesce(parent_id, 0), lower(name));
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS expenses_category_id_idx ON expenses (category_id);
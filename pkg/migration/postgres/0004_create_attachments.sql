@@ -0,0 +1,13 @@
+-- Files are kept in the blob store under their sha256, an expense has a file
+-- at most once and expenses attaching the same file share its blob.
+CREATE TABLE IF NOT EXISTS attachments (
+	id SERIAL PRIMARY KEY,
+	expense_id INTEGER NOT NULL REFERENCES expenses (id) ON DELETE CASCADE,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	sha256 TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (expense_id, sha256)
+);
+CREATE INDEX IF NOT EXISTS attachments_sha256_idx ON attachments (sha256);
@@ -0,0 +1,24 @@
+-- expenses_search is the text search configuration of the expense search, the
+-- application maps its words to the dictionary of search.dictionary. It starts
+-- as a copy of simple, which neither stems nor drops stop words and so keeps
+-- Thai and English words as they are written.
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'expenses_search') THEN
+		CREATE TEXT SEARCH CONFIGURATION expenses_search (COPY = simple);
+	END IF;
+END
+$$;
+
+-- expenses_search_document ranks the title over the tags over the note. It is
+-- declared immutable for the generated column, the application rewrites the
+-- column whenever it changes the configuration.
+CREATE OR REPLACE FUNCTION expenses_search_document(title TEXT, note TEXT, tags TEXT[]) RETURNS tsvector
+LANGUAGE sql IMMUTABLE AS $$
+	SELECT setweight(to_tsvector('expenses_search', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('expenses_search', coalesce(array_to_string(tags, ' '), '')), 'B') ||
+		setweight(to_tsvector('expenses_search', coalesce(note, '')), 'C')
+$$;
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (expenses_search_document(title, note, tags)) STORED;
+CREATE INDEX IF NOT EXISTS expenses_search_idx ON expenses USING GIN (search);
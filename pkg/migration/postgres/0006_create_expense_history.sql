@@ -0,0 +1,25 @@
+-- expense_history is append only, every insert, update and delete of an
+-- expense adds the next version of the expense in the same transaction. changes
+-- maps each changed field to {"from": ..., "to": ...}, an insert changes its
+-- fields from null and a delete to null. The history outlives the expense.
+CREATE TABLE IF NOT EXISTS expense_history (
+	id BIGSERIAL PRIMARY KEY,
+	expense_id INTEGER NOT NULL,
+	version INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	actor TEXT,
+	request_id TEXT,
+	changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	changes JSONB NOT NULL,
+	UNIQUE (expense_id, version)
+);
+
+-- The expenses created before the history start it with an insert of an
+-- unknown actor. Their creation time is not stored, the insert is changed at
+-- the time of this migration, so they are not found as of an earlier time.
+INSERT INTO expense_history (expense_id, version, action, changes)
+select e.id, 1, 'insert', coalesce((select jsonb_object_agg(f.key, jsonb_build_object('from', null, 'to', f.value))
+	from jsonb_each(jsonb_build_object('title', e.title, 'amount', e.amount, 'note', e.note, 'tags', e.tags, 'category_id', e.category_id)) f
+	where f.value <> 'null'::jsonb), '{}'::jsonb)
+from expenses e
+where not exists (select 1 from expense_history h where h.expense_id = e.id);
@@ -0,0 +1,19 @@
+-- outbox holds the domain events of the writes, added in the transaction of
+-- the write and published at least once by the relay. An event is published
+-- when published_at is set, attempts and last_error tell about the failed
+-- publications.
+CREATE TABLE IF NOT EXISTS outbox (
+	id BIGSERIAL PRIMARY KEY,
+	aggregate TEXT NOT NULL,
+	aggregate_id BIGINT NOT NULL,
+	type TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	actor TEXT,
+	request_id TEXT,
+	occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	published_at TIMESTAMPTZ,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1,17 @@
+-- notify_expenses tells the listening instances the id of every expense
+-- written, whatever the statement, so they drop it from their cache. The
+-- notifications are sent when the transaction commits.
+CREATE OR REPLACE FUNCTION notify_expenses() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		PERFORM pg_notify('expenses', OLD.id::text);
+	ELSE
+		PERFORM pg_notify('expenses', NEW.id::text);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS expenses_notify ON expenses;
+CREATE TRIGGER expenses_notify AFTER INSERT OR UPDATE OR DELETE ON expenses
+	FOR EACH ROW EXECUTE FUNCTION notify_expenses();
@@ -0,0 +1,17 @@
+-- enqueue_webhook_deliveries adds a delivery of every expense event to the
+-- active webhooks subscribed to it, in the transaction of the write adding the
+-- event to the outbox. A committed write is so always delivered, a rolled back
+-- one never is. The payload is the webhooks.Payload of the event.
+CREATE OR REPLACE FUNCTION enqueue_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+	INSERT INTO webhook_deliveries (webhook_id, event, payload)
+	SELECT id, NEW.type, jsonb_build_object('event', NEW.type, 'occurred_at', NEW.occurred_at, 'expense', NEW.payload->'expense')
+	FROM webhooks
+	WHERE active AND (cardinality(events) = 0 OR NEW.type = any(events));
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS outbox_enqueue_webhook_deliveries ON outbox;
+CREATE TRIGGER outbox_enqueue_webhook_deliveries AFTER INSERT ON outbox
+	FOR EACH ROW WHEN (NEW.aggregate = 'expense') EXECUTE FUNCTION enqueue_webhook_deliveries();
@@ -0,0 +1,5 @@
+-- now() is the start of the transaction, while the version of a change is
+-- numbered when it is written, under the lock of the expense. A transaction
+-- started earlier could write a later version with an earlier changed_at, so
+-- changed_at is the time of the write instead.
+ALTER TABLE expense_history ALTER COLUMN changed_at SET DEFAULT clock_timestamp();
@@ -0,0 +1,4 @@
+-- The blobs of the attachments are removed with them, not by the database, so
+-- an expense is deleted after its attachments rather than cascading to them.
+ALTER TABLE attachments DROP CONSTRAINT IF EXISTS attachments_expense_id_fkey;
+ALTER TABLE attachments ADD CONSTRAINT attachments_expense_id_fkey FOREIGN KEY (expense_id) REFERENCES expenses (id);
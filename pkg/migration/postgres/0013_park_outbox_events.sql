@@ -0,0 +1,7 @@
+-- An event the sink keeps rejecting is parked once its attempts run out, so
+-- the events after it are published. A parked event has parked_at set and is
+-- published again only when parked_at is cleared.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS parked_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE published_at IS NULL AND parked_at IS NULL;
@@ -0,0 +1,30 @@
+-- expenses_search_document reads the expenses_search configuration, which
+-- the application changes, so it is stable and not immutable. A generated
+-- column needs an immutable expression, the search column is kept by a
+-- trigger instead and rewritten by the application with the configuration.
+CREATE OR REPLACE FUNCTION expenses_search_document(title TEXT, note TEXT, tags TEXT[]) RETURNS tsvector
+LANGUAGE sql STABLE AS $$
+	SELECT setweight(to_tsvector('expenses_search', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('expenses_search', coalesce(array_to_string(tags, ' '), '')), 'B') ||
+		setweight(to_tsvector('expenses_search', coalesce(note, '')), 'C')
+$$;
+
+CREATE OR REPLACE FUNCTION expenses_search_refresh() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	NEW.search := expenses_search_document(NEW.title, NEW.note, NEW.tags);
+	RETURN NEW;
+END
+$$;
+
+-- postgres 12 cannot turn a generated column into a plain one, the column
+-- and its index are created again.
+ALTER TABLE expenses DROP COLUMN IF EXISTS search;
+ALTER TABLE expenses ADD COLUMN search tsvector;
+
+DROP TRIGGER IF EXISTS expenses_search_refresh ON expenses;
+CREATE TRIGGER expenses_search_refresh BEFORE INSERT OR UPDATE OF title, note, tags ON expenses
+	FOR EACH ROW EXECUTE FUNCTION expenses_search_refresh();
+
+UPDATE expenses SET title = title;
+CREATE INDEX IF NOT EXISTS expenses_search_idx ON expenses USING GIN (search);
@@ -0,0 +1,2 @@
+-- tags is a JSON array of strings as SQLite has no array type.
+CREATE TABLE IF NOT EXISTS expenses (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT, amount REAL, note TEXT, tags TEXT);
@@ -0,0 +1,10 @@
+-- See the postgres migration, changed_at is an RFC 3339 UTC text with
+-- milliseconds so that it sorts by time.
+CREATE TABLE IF NOT EXISTS expense_history (id INTEGER PRIMARY KEY AUTOINCREMENT, expense_id INTEGER NOT NULL, version INTEGER NOT NULL, action TEXT NOT NULL, actor TEXT, request_id TEXT, changed_at TEXT NOT NULL, changes TEXT NOT NULL, UNIQUE (expense_id, version));
+
+INSERT INTO expense_history (expense_id, version, action, changed_at, changes)
+select e.id, 1, 'insert', strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), (select json_group_object(f.key, json_object('from', NULL, 'to', CASE WHEN f.type IN ('array', 'object') THEN json(f.value) ELSE f.value END))
+	from json_each(json_object('title', e.title, 'amount', e.amount, 'note', e.note, 'tags', json(e.tags))) f
+	where f.type <> 'null')
+from expenses e
+where not exists (select 1 from expense_history h where h.expense_id = e.id);
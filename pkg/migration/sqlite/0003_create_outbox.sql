@@ -0,0 +1,5 @@
+-- See the postgres migration, the times are RFC 3339 UTC texts with
+-- milliseconds.
+CREATE TABLE IF NOT EXISTS outbox (id INTEGER PRIMARY KEY AUTOINCREMENT, aggregate TEXT NOT NULL, aggregate_id INTEGER NOT NULL, type TEXT NOT NULL, payload TEXT NOT NULL, actor TEXT, request_id TEXT, occurred_at TEXT NOT NULL, published_at TEXT, attempts INTEGER NOT NULL DEFAULT 0, last_error TEXT);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE published_at IS NULL;
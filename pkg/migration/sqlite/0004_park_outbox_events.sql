@@ -0,0 +1,5 @@
+-- See the postgres migration.
+ALTER TABLE outbox ADD COLUMN parked_at TEXT;
+
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE published_at IS NULL AND parked_at IS NULL;
@@ -0,0 +1,243 @@
+// Package openapi describes the REST api as an OpenAPI 3.1 document, built
+// from the Operations each package declares next to its routes and from the
+// json encoding of their request and response types.
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	Version = "3.1.0"
+
+	// SecurityScheme is the api key checked by the middleware of the server.
+	SecurityScheme = "apiKey"
+
+	MIMEApplicationJSON = "application/json"
+)
+
+// The error responses shared by the operations, the handlers answer them
+// without body.
+var (
+	BadRequest = Response{Status: http.StatusBadRequest, Description: "invalid request"}
+	NotFound   = Response{Status: http.StatusNotFound, Description: "not found"}
+	Conflict   = Response{Status: http.StatusConflict, Description: "conflicts with the stored records"}
+
+	NotAcceptable        = Response{Status: http.StatusNotAcceptable, Description: "accepts none of the media types"}
+	UnsupportedMediaType = Response{Status: http.StatusUnsupportedMediaType, Description: "the body is of another media type"}
+)
+
+// Operation describes a route. Path is the echo path of the route, its :params
+// are the int64 ids of the path. Body and the Body of the Responses are values
+// of the Go types encoded to json, or a *Schema.
+type Operation struct {
+	Method  string
+	Path    string
+	Id      string
+	Summary string
+	Tag     string
+	// Public operations are served without api key.
+	Public    bool
+	Params    []Param
+	Body      any
+	BodyType  string
+	Responses []Response
+	// Formats are the media types the operation also reads and writes its
+	// json bodies in, chosen by the Content-Type and Accept headers.
+	Formats []string
+	// Deprecated operations are still served until their sunset.
+	Deprecated bool
+}
+
+// Param is a query or header parameter, Schema is a value of its Go type.
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Schema      any
+}
+
+// Response is a status of an Operation, ContentType defaults to json when
+// Body is set.
+type Response struct {
+	Status      int
+	Description string
+	Body        any
+	ContentType string
+}
+
+// Document is the OpenAPI document of operations.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Security   []map[string][]string            `json:"security"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in"`
+	Name string `json:"name"`
+}
+
+type operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// New builds the document of operations. The operations needing the api key
+// also answer 401, every operation may answer 500.
+func New(info Info, operations ...[]Operation) *Document {
+	s := newSchemas()
+	doc := &Document{
+		OpenAPI:  Version,
+		Info:     info,
+		Security: []map[string][]string{{SecurityScheme: {}}},
+		Paths:    map[string]map[string]*operation{},
+		Components: components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]securityScheme{
+				SecurityScheme: {Type: "apiKey", In: "header", Name: "Authorization"},
+			},
+		},
+	}
+	for _, ops := range operations {
+		for _, op := range ops {
+			path, params := PathOf(op.Path)
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]*operation{}
+			}
+			doc.Paths[path][strings.ToLower(op.Method)] = s.operation(op, params)
+		}
+	}
+	return doc
+}
+
+func (s *schemas) operation(op Operation, pathParams []string) *operation {
+	item := &operation{OperationId: op.Id, Summary: op.Summary, Deprecated: op.Deprecated, Responses: map[string]response{}}
+	if op.Tag != "" {
+		item.Tags = []string{op.Tag}
+	}
+	if op.Public {
+		// an empty requirement overrides the api key of the document
+		item.Security = []map[string][]string{{}}
+	}
+	for _, name := range pathParams {
+		item.Parameters = append(item.Parameters, parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}})
+	}
+	for _, p := range op.Params {
+		item.Parameters = append(item.Parameters, parameter{Name: p.Name, In: p.In, Description: p.Description, Schema: s.of(p.Schema, false)})
+	}
+	if op.Body != nil {
+		schema := s.of(op.Body, false)
+		item.RequestBody = &requestBody{Required: true, Content: map[string]mediaType{
+			contentType(op.BodyType): {Schema: schema},
+		}}
+		for _, format := range op.Formats {
+			item.RequestBody.Content[format] = mediaType{Schema: schema}
+		}
+	}
+
+	responses := append([]Response{}, op.Responses...)
+	if len(op.Formats) > 0 {
+		responses = append(responses, NotAcceptable)
+		if op.Body != nil {
+			responses = append(responses, UnsupportedMediaType)
+		}
+	}
+	if !op.Public {
+		responses = append(responses, Response{Status: http.StatusUnauthorized, Description: "missing or unknown api key"})
+	}
+	responses = append(responses, Response{Status: http.StatusInternalServerError, Description: "unexpected error"})
+	for _, r := range responses {
+		resp := response{Description: r.Description}
+		if r.Body != nil {
+			schema := s.of(r.Body, true)
+			resp.Content = map[string]mediaType{contentType(r.ContentType): {Schema: schema}}
+			for _, format := range op.Formats {
+				resp.Content[format] = mediaType{Schema: schema}
+			}
+		} else if r.ContentType != "" {
+			resp.Content = map[string]mediaType{r.ContentType: {}}
+		}
+		item.Responses[strconv.Itoa(r.Status)] = resp
+	}
+	return item
+}
+
+func contentType(t string) string {
+	if t == "" {
+		return MIMEApplicationJSON
+	}
+	return t
+}
+
+// PathOf turns the echo path into the path of the document and returns the
+// names of its parameters.
+func PathOf(echoPath string) (string, []string) {
+	var params []string
+	parts := strings.Split(echoPath, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// Operations returns the method and document path of every operation of doc,
+// sorted.
+func (d *Document) Operations() []string {
+	result := []string{}
+	for path, item := range d.Paths {
+		for method := range item {
+			result = append(result, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,178 @@
+//go:build unit
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type item struct {
+	Id        int64     `json:"id"`
+	Note      string    `json:"note,omitempty"`
+	ParentId  *int64    `json:"parent_id"`
+	CreatedAt time.Time `json:"created_at"`
+	secret    string
+}
+
+type ranked struct {
+	item
+	Rank float32 `json:"rank"`
+}
+
+func toJSON(t *testing.T, v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should refer to the component of a named struct", func(t *testing.T) {
+		doc := New(Info{Title: "test"}, []Operation{
+			{Method: http.MethodPost, Path: "/items", Id: "AddItem", Responses: []Response{
+				{Status: http.StatusCreated, Description: "created", Body: item{}},
+			}},
+			{Method: http.MethodGet, Path: "/items", Id: "ListItems", Responses: []Response{
+				{Status: http.StatusOK, Description: "items", Body: []item{}},
+			}},
+		})
+
+		op := doc.Paths["/items"]["get"]
+		want := `{"type":["array","null"],"items":{"$ref":"#/components/schemas/item"}}`
+		if got := toJSON(t, op.Responses["200"].Content[MIMEApplicationJSON].Schema); got != want {
+			t.Errorf("schema=%s; want %s", got, want)
+		}
+		want = `{"type":"object","properties":{"created_at":{"type":"string","format":"date-time"},"id":{"type":"integer","format":"int64"},"note":{"type":"string"},"parent_id":{"type":["integer","null"],"format":"int64"}},"required":["id","parent_id","created_at"]}`
+		if got := toJSON(t, doc.Components.Schemas["item"]); got != want {
+			t.Errorf("component=%s; want %s", got, want)
+		}
+	})
+
+	t.Run("should flatten the fields of an embedded struct", func(t *testing.T) {
+		doc := New(Info{}, []Operation{
+			{Method: http.MethodGet, Path: "/search", Id: "Search", Responses: []Response{
+				{Status: http.StatusOK, Description: "results", Body: ranked{}},
+			}},
+		})
+
+		got := doc.Components.Schemas["ranked"]
+		for _, name := range []string{"id", "note", "parent_id", "created_at", "rank"} {
+			if got.Properties[name] == nil {
+				t.Errorf("property %s is missing", name)
+			}
+		}
+		if _, ok := doc.Components.Schemas["item"]; ok {
+			t.Errorf("embedded struct has a component")
+		}
+	})
+
+	t.Run("should take the path parameters and require the api key unless public", func(t *testing.T) {
+		doc := New(Info{}, []Operation{
+			{Method: http.MethodDelete, Path: "/items/:id/notes/:noteId", Id: "DeleteNote", Responses: []Response{
+				{Status: http.StatusNoContent, Description: "deleted"},
+			}},
+			{Method: http.MethodGet, Path: "/ping", Id: "Ping", Public: true, Responses: []Response{
+				{Status: http.StatusOK, Description: "pong"},
+			}},
+		})
+
+		op := doc.Paths["/items/{id}/notes/{noteId}"]["delete"]
+		if op == nil {
+			t.Fatalf("paths=%v; want /items/{id}/notes/{noteId}", doc.Paths)
+		}
+		names := []string{}
+		for _, p := range op.Parameters {
+			names = append(names, p.In+":"+p.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"path:id", "path:noteId"}) {
+			t.Errorf("parameters=%v; want the path parameters", names)
+		}
+		if _, ok := op.Responses["401"]; !ok || op.Security != nil {
+			t.Errorf("operation does not need the api key")
+		}
+		ping := doc.Paths["/ping"]["get"]
+		if _, ok := ping.Responses["401"]; ok || toJSON(t, ping.Security) != `[{}]` {
+			t.Errorf("public operation needs the api key")
+		}
+	})
+
+	t.Run("should describe the bodies in every format and answer 406 and 415", func(t *testing.T) {
+		doc := New(Info{}, []Operation{
+			{Method: http.MethodPut, Path: "/items/:id", Id: "UpdateItem", Formats: []string{"application/xml"},
+				Body: struct{ Note string }{}, Responses: []Response{
+					{Status: http.StatusOK, Description: "updated", Body: item{}},
+				}},
+		})
+
+		op := doc.Paths["/items/{id}"]["put"]
+		for _, contentType := range []string{MIMEApplicationJSON, "application/xml"} {
+			if _, ok := op.RequestBody.Content[contentType]; !ok {
+				t.Errorf("request body has no %s", contentType)
+			}
+			if _, ok := op.Responses["200"].Content[contentType]; !ok {
+				t.Errorf("response has no %s", contentType)
+			}
+		}
+		for _, status := range []string{"406", "415"} {
+			if _, ok := op.Responses[status]; !ok {
+				t.Errorf("responses have no %s", status)
+			}
+		}
+	})
+
+	t.Run("should describe any of the bodies of a deprecated operation", func(t *testing.T) {
+		doc := New(Info{}, []Operation{
+			{Method: http.MethodGet, Path: "/items/:id", Id: "GetItem", Deprecated: true, Responses: []Response{
+				{Status: http.StatusOK, Description: "the item", Body: AnyOf(item{}, struct {
+					Data item `json:"data"`
+				}{})},
+			}},
+		})
+
+		op := doc.Paths["/items/{id}"]["get"]
+		want := `{"anyOf":[{"$ref":"#/components/schemas/item"},{"type":"object","properties":{"data":{"$ref":"#/components/schemas/item"}},"required":["data"]}]}`
+		if got := toJSON(t, op.Responses["200"].Content[MIMEApplicationJSON].Schema); got != want {
+			t.Errorf("schema=%s; want %s", got, want)
+		}
+		if !op.Deprecated {
+			t.Errorf("operation is not deprecated")
+		}
+	})
+
+	t.Run("should panic when two structs share a name", func(t *testing.T) {
+		outer := item{}
+		type item struct {
+			Name string `json:"name"`
+		}
+		defer func() {
+			if recover() == nil {
+				t.Errorf("no panic")
+			}
+		}()
+
+		New(Info{}, []Operation{
+			{Method: http.MethodGet, Path: "/a", Id: "A", Responses: []Response{{Status: http.StatusOK, Body: item{}}}},
+		}, []Operation{
+			{Method: http.MethodGet, Path: "/b", Id: "B", Responses: []Response{{Status: http.StatusOK, Body: outer}}},
+		})
+	})
+
+	t.Run("should panic when a struct is both a request and a response", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("no panic")
+			}
+		}()
+
+		New(Info{}, []Operation{
+			{Method: http.MethodPut, Path: "/items/:id", Id: "UpdateItem", Body: item{}, Responses: []Response{
+				{Status: http.StatusOK, Description: "updated", Body: item{}},
+			}},
+		})
+	})
+}
@@ -0,0 +1,51 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// initializer starts the Swagger UI on the document of the server instead of
+// the petstore of the distribution.
+const initializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "` + DocumentPath + `",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+type Handler struct {
+	doc    *Document
+	assets http.Handler
+}
+
+func NewHandler(doc *Document) *Handler {
+	return &Handler{
+		doc:    doc,
+		assets: http.StripPrefix(DocsPath+"/", http.FileServer(http.FS(swaggerfiles.FS))),
+	}
+}
+
+func (h Handler) Document(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.doc)
+}
+
+func (h Handler) Docs(c echo.Context) error {
+	return c.Redirect(http.StatusMovedPermanently, DocsPath+"/")
+}
+
+func (h Handler) Initializer(c echo.Context) error {
+	return c.Blob(http.StatusOK, "application/javascript", []byte(initializer))
+}
+
+// Assets serves the files of the Swagger UI distribution.
+func (h Handler) Assets(c echo.Context) error {
+	h.assets.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
@@ -0,0 +1,38 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DocumentPath = "/openapi.json"
+	// DocsPath serves the Swagger UI of the document.
+	DocsPath = "/docs"
+)
+
+// Operations are the routes of the document itself, the Swagger UI is left
+// out.
+var Operations = []Operation{
+	{Method: http.MethodGet, Path: DocumentPath, Id: "OpenAPIDocument", Summary: "OpenAPI document of the api", Tag: "docs", Public: true, Responses: []Response{
+		{Status: http.StatusOK, Description: "the document", Body: &Schema{Type: "object"}},
+	}},
+}
+
+// IsDocs reports whether path is the document or its Swagger UI, served
+// without api key as the browsers do not send it.
+func IsDocs(path string) bool {
+	return path == DocumentPath || path == DocsPath || strings.HasPrefix(path, DocsPath+"/")
+}
+
+// Routes serves doc and its Swagger UI.
+func Routes(echo *echo.Echo, doc *Document) {
+	handler := NewHandler(doc)
+
+	echo.GET(DocumentPath, handler.Document)
+	echo.GET(DocsPath, handler.Docs)
+	echo.GET(DocsPath+"/swagger-initializer.js", handler.Initializer)
+	echo.GET(DocsPath+"/*", handler.Assets)
+}
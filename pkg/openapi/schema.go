@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON schema of the OpenAPI document. Type is a string, or a list
+// of strings for a nullable value. AdditionalProperties is a *Schema, or false
+// for the objects of the requests, which reject unknown fields.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentMediaType     string             `json:"contentMediaType,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// anyOf is a value of one of the Go types, see AnyOf.
+type anyOf []any
+
+// AnyOf is a body of any of the types of values, for the operations which
+// read or write the bodies of several versions.
+func AnyOf(values ...any) any {
+	return anyOf(values)
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas generates the schemas of the Go types from their json encoding. A
+// named struct becomes a component referenced by name.
+type schemas struct {
+	components map[string]*Schema
+	types      map[string]component
+}
+
+// component is the struct of a component, its required fields depend on
+// whether it is a response.
+type component struct {
+	t        reflect.Type
+	response bool
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]*Schema{}, types: map[string]component{}}
+}
+
+// of returns the schema of v, v itself when it is a *Schema. The fields
+// without omitempty of a response are required, the fields of a request are
+// all optional, the handlers tell the missing ones.
+func (s *schemas) of(v any, response bool) *Schema {
+	switch v := v.(type) {
+	case *Schema:
+		return v
+	case anyOf:
+		schema := &Schema{}
+		for _, value := range v {
+			schema.AnyOf = append(schema.AnyOf, s.of(value, response))
+		}
+		return schema
+	}
+	return s.typeOf(reflect.TypeOf(v), response)
+}
+
+func (s *schemas) typeOf(t reflect.Type, response bool) *Schema {
+	if t.Kind() == reflect.Pointer {
+		return nullable(s.typeOf(t.Elem(), response))
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		// a nil slice is encoded as null
+		return nullable(&Schema{Type: "array", Items: s.typeOf(t.Elem(), response)})
+	case reflect.Array:
+		return &Schema{Type: "array", Items: s.typeOf(t.Elem(), response)}
+	case reflect.Map:
+		return nullable(&Schema{Type: "object", AdditionalProperties: s.typeOf(t.Elem(), response)})
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t, response)
+		}
+		return s.component(t, response)
+	}
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+// component adds the struct t to the components once and refers to it. Two
+// structs of the same name would overwrite each other and a struct of both a
+// request and a response would have the required fields of one of them, which
+// are mistakes.
+func (s *schemas) component(t reflect.Type, response bool) *Schema {
+	name := t.Name()
+	if known, ok := s.types[name]; ok {
+		if known.t != t {
+			panic(fmt.Sprintf("openapi: %s and %s share the schema name %s", known.t, t, name))
+		}
+		if known.response != response {
+			panic(fmt.Sprintf("openapi: %s is both a request and a response", t))
+		}
+	} else {
+		s.types[name] = component{t: t, response: response}
+		s.components[name] = s.object(t, response)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (s *schemas) object(t reflect.Type, response bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	if !response {
+		schema.AdditionalProperties = false
+	}
+	s.fields(schema, t, response)
+	return schema
+}
+
+// fields adds the exported fields of t to schema, the fields of an embedded
+// struct, exported or not, are encoded as fields of t.
+func (s *schemas) fields(schema *Schema, t reflect.Type, response bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			s.fields(schema, f.Type, response)
+			continue
+		}
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		schema.Properties[name] = s.typeOf(f.Type, response)
+		if response && !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// nullable allows null besides the values of schema.
+func nullable(schema *Schema) *Schema {
+	if typ, ok := schema.Type.(string); ok {
+		schema.Type = []string{typ, "null"}
+		return schema
+	}
+	return &Schema{OneOf: []*Schema{schema, {Type: "null"}}}
+}
@@ -0,0 +1,430 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/labstack/echo/v4"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	MIMEApplicationProblemJSON = "application/problem+json"
+
+	// documentURL is the url of the document among the schemas of the
+	// Validator, never fetched.
+	documentURL = "mem://openapi.json"
+)
+
+// Problem is an RFC 9457 problem details response.
+type Problem struct {
+	Type   string         `json:"type"`
+	Title  string         `json:"title"`
+	Status int            `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	Errors []ProblemError `json:"errors,omitempty"`
+}
+
+// ProblemError is a value not matching its schema. In is path, query, header
+// or body, Name the name of a parameter and Pointer the JSON pointer of the
+// value in a body.
+type ProblemError struct {
+	In      string `json:"in"`
+	Name    string `json:"name,omitempty"`
+	Pointer string `json:"pointer,omitempty"`
+	Message string `json:"message"`
+}
+
+// Validator checks the requests and responses of the operations of a Document.
+type Validator struct {
+	log        common.Log
+	operations map[string]*validation
+}
+
+// validation holds the compiled schemas of an operation.
+type validation struct {
+	params []paramValidation
+	body   *jsonschema.Schema
+	// bodyTypes are the media types of the body, the json one is checked
+	// against body.
+	bodyTypes map[string]bool
+	// responses maps the statuses to their json schema, nil for the statuses
+	// without json body.
+	responses map[int]*jsonschema.Schema
+	// buffered operations answer bodies of a schema or nothing, the others
+	// stream their responses which are not checked.
+	buffered bool
+}
+
+type paramValidation struct {
+	name   string
+	in     string
+	typ    string
+	schema *jsonschema.Schema
+}
+
+// NewValidator compiles the schemas of doc.
+func NewValidator(doc *Document, l common.Log) (*Validator, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+	if err := compiler.AddResource(documentURL, bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+
+	v := &Validator{log: l, operations: map[string]*validation{}}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			ptr := "#/paths/" + escape(path) + "/" + method
+			val, err := compile(compiler, ptr, op)
+			if err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", strings.ToUpper(method), path, err)
+			}
+			v.operations[strings.ToUpper(method)+" "+path] = val
+		}
+	}
+	return v, nil
+}
+
+func compile(compiler *jsonschema.Compiler, ptr string, op *operation) (*validation, error) {
+	val := &validation{bodyTypes: map[string]bool{}, responses: map[int]*jsonschema.Schema{}, buffered: true}
+	for i, p := range op.Parameters {
+		schema, err := compiler.Compile(fmt.Sprintf("%s%s/parameters/%d/schema", documentURL, ptr, i))
+		if err != nil {
+			return nil, err
+		}
+		val.params = append(val.params, paramValidation{name: p.Name, in: p.In, typ: primaryType(p.Schema), schema: schema})
+	}
+	if op.RequestBody != nil {
+		for contentType := range op.RequestBody.Content {
+			val.bodyTypes[contentType] = true
+			if contentType != MIMEApplicationJSON {
+				continue
+			}
+			schema, err := compiler.Compile(documentURL + ptr + "/requestBody/content/" + escape(contentType) + "/schema")
+			if err != nil {
+				return nil, err
+			}
+			val.body = schema
+		}
+	}
+	for status, resp := range op.Responses {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return nil, err
+		}
+		val.responses[code] = nil
+		for contentType, media := range resp.Content {
+			if media.Schema == nil {
+				val.buffered = false
+				continue
+			}
+			if contentType != MIMEApplicationJSON {
+				continue
+			}
+			schema, err := compiler.Compile(documentURL + ptr + "/responses/" + status + "/content/" + escape(contentType) + "/schema")
+			if err != nil {
+				return nil, err
+			}
+			val.responses[code] = schema
+		}
+	}
+	return val, nil
+}
+
+// escape turns s into a token of a JSON pointer.
+func escape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// primaryType is the type of the values of a parameter, without null.
+func primaryType(schema *Schema) string {
+	switch typ := schema.Type.(type) {
+	case string:
+		return typ
+	case []string:
+		return typ[0]
+	}
+	return ""
+}
+
+// operation returns the validation of the route of c, nil for the routes
+// outside of the document.
+func (v *Validator) operation(c echo.Context) *validation {
+	path, _ := PathOf(c.Path())
+	return v.operations[c.Request().Method+" "+path]
+}
+
+// Requests rejects the requests which do not match their operation with a
+// problem before the handlers run, 415 for a body of another content type and
+// 400 for the rest.
+func (v *Validator) Requests() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			op := v.operation(c)
+			if op == nil {
+				return next(c)
+			}
+
+			problems := op.checkParams(c)
+			mediaType := ""
+			if len(op.bodyTypes) > 0 {
+				mediaType, _, _ = mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+				if !op.bodyTypes[mediaType] {
+					return problem(c, http.StatusUnsupportedMediaType, "the body must be one of "+strings.Join(sortedKeys(op.bodyTypes), ", "), nil)
+				}
+			}
+			if mediaType == MIMEApplicationJSON && op.body != nil {
+				errs, err := op.checkBody(c)
+				if err != nil {
+					return err
+				}
+				problems = append(problems, errs...)
+			}
+			if len(problems) > 0 {
+				return problem(c, http.StatusBadRequest, "the request does not match the OpenAPI document", problems)
+			}
+			return next(c)
+		}
+	}
+}
+
+func (op *validation) checkParams(c echo.Context) []ProblemError {
+	var problems []ProblemError
+	for _, p := range op.params {
+		var raw string
+		switch p.in {
+		case "path":
+			raw = c.Param(p.name)
+		case "query":
+			if _, ok := c.QueryParams()[p.name]; !ok {
+				continue
+			}
+			raw = c.QueryParam(p.name)
+		case "header":
+			if raw = c.Request().Header.Get(p.name); raw == "" {
+				continue
+			}
+		}
+		if err := p.schema.Validate(paramValue(p.typ, raw)); err != nil {
+			for _, message := range messages(err) {
+				problems = append(problems, ProblemError{In: p.in, Name: p.name, Message: message.Message})
+			}
+		}
+	}
+	return problems
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// paramValue is the json value of the raw parameter, raw itself when it is
+// not of typ for the schema to tell.
+func paramValue(typ string, raw string) any {
+	switch typ {
+	case "integer":
+		if _, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return json.Number(raw)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+// checkBody validates the json body, which is kept for the handler.
+func (op *validation) checkBody(c echo.Context) ([]ProblemError, error) {
+	req := c.Request()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	value, err := decode(body)
+	if err != nil {
+		return []ProblemError{{In: "body", Message: "invalid json: " + err.Error()}}, nil
+	}
+	var problems []ProblemError
+	if err := op.body.Validate(value); err != nil {
+		for _, message := range messages(err) {
+			problems = append(problems, ProblemError{In: "body", Pointer: message.Pointer, Message: message.Message})
+		}
+	}
+	return problems, nil
+}
+
+// decode returns the json value of body for the schemas, its numbers kept as
+// written.
+func decode(body []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var value any
+	if err := d.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the json value")
+	}
+	return value, nil
+}
+
+type message struct {
+	Pointer string
+	Message string
+}
+
+// messages returns the causes of the validation error err, the errors of the
+// schemas of a oneOf are left to the oneOf.
+func messages(err error) []message {
+	var vErr *jsonschema.ValidationError
+	if !errors.As(err, &vErr) {
+		return []message{{Message: err.Error()}}
+	}
+	var result []message
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 || strings.HasSuffix(e.KeywordLocation, "/oneOf") {
+			result = append(result, message{Pointer: e.InstanceLocation, Message: e.Message})
+			return
+		}
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+	}
+	walk(vErr)
+	return result
+}
+
+func problem(c echo.Context, status int, detail string, errs []ProblemError) error {
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.JSON(status, Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Errors: errs,
+	})
+}
+
+// bufferWriter keeps a response until it is checked.
+type bufferWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// Flush keeps the response, it is written once checked.
+func (w *bufferWriter) Flush() {}
+
+// Responses answers a 500 problem in place of the responses which do not match
+// their operation. The responses are buffered for it, the streamed ones are
+// not checked.
+func (v *Validator) Responses() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			op := v.operation(c)
+			if op == nil || !op.buffered {
+				return next(c)
+			}
+
+			res := c.Response()
+			writer := res.Writer
+			buffer := &bufferWriter{ResponseWriter: writer}
+			res.Writer = buffer
+			err := func() error {
+				// a panic is answered by the recover middleware
+				defer func() { res.Writer = writer }()
+				return next(c)
+			}()
+			if buffer.status == 0 {
+				return err
+			}
+
+			problems := op.checkResponse(buffer.status, writer.Header().Get(echo.HeaderContentType), buffer.body.Bytes())
+			if len(problems) == 0 {
+				writer.WriteHeader(buffer.status)
+				_, werr := writer.Write(buffer.body.Bytes())
+				if err == nil {
+					err = werr
+				}
+				return err
+			}
+
+			v.log.Errorf("Response of %s %s does not match the OpenAPI document : %v", c.Request().Method, c.Path(), problems)
+			res.Status = http.StatusInternalServerError
+			writer.Header().Del(echo.HeaderContentLength)
+			writer.Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return json.NewEncoder(writer).Encode(Problem{
+				Type:   "about:blank",
+				Title:  http.StatusText(http.StatusInternalServerError),
+				Status: http.StatusInternalServerError,
+				Detail: fmt.Sprintf("the %d response does not match the OpenAPI document", buffer.status),
+				Errors: problems,
+			})
+		}
+	}
+}
+
+// checkResponse checks the status of the response, and its body against the
+// schema when it is json.
+func (op *validation) checkResponse(status int, contentType string, body []byte) []ProblemError {
+	schema, ok := op.responses[status]
+	if !ok {
+		return []ProblemError{{In: "status", Message: fmt.Sprintf("%d is not a documented status", status)}}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); schema != nil && mediaType != MIMEApplicationJSON {
+		return nil
+	}
+	if schema == nil {
+		if len(body) > 0 {
+			return []ProblemError{{In: "body", Message: "no body is documented"}}
+		}
+		return nil
+	}
+	value, err := decode(body)
+	if err != nil {
+		return []ProblemError{{In: "body", Message: "invalid json: " + err.Error()}}
+	}
+	var problems []ProblemError
+	if err := schema.Validate(value); err != nil {
+		for _, message := range messages(err) {
+			problems = append(problems, ProblemError{In: "body", Pointer: message.Pointer, Message: message.Message})
+		}
+	}
+	return problems
+}
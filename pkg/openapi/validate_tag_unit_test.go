@@ -0,0 +1,173 @@
+//go:build unit
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+type noteRequest struct {
+	Text  string   `json:"text"`
+	Stars *int     `json:"stars"`
+	Tags  []string `json:"tags"`
+}
+
+type noteResponse struct {
+	Id   int64  `json:"id"`
+	Text string `json:"text"`
+}
+
+var noteOperations = []Operation{
+	{Method: http.MethodPut, Path: "/notes/:id", Id: "UpdateNote", Body: noteRequest{}, Params: []Param{
+		{Name: "dry_run", In: "query", Schema: false},
+	}, Responses: []Response{
+		{Status: http.StatusOK, Description: "updated", Body: noteResponse{}},
+		BadRequest,
+	}},
+	{Method: http.MethodGet, Path: "/notes/:id/file", Id: "DownloadNote", Responses: []Response{
+		{Status: http.StatusOK, Description: "the file", ContentType: "text/plain"},
+	}},
+}
+
+// setupValidator serves handler on the operations of noteOperations.
+func setupValidator(t *testing.T, handler echo.HandlerFunc) *echo.Echo {
+	v, err := NewValidator(New(Info{}, noteOperations), logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.Use(v.Requests(), v.Responses())
+	e.PUT("/notes/:id", handler)
+	e.GET("/notes/:id/file", handler)
+	return e
+}
+
+func serve(e *echo.Echo, method string, path string, contentType string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func problemOf(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	if ct := rec.Header().Get(echo.HeaderContentType); ct != MIMEApplicationProblemJSON {
+		t.Fatalf("content type=%s; want %s\n%s", ct, MIMEApplicationProblemJSON, rec.Body)
+	}
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRequests(t *testing.T) {
+	handled := false
+	e := setupValidator(t, func(c echo.Context) error {
+		handled = true
+		var req noteRequest
+		if err := c.Bind(&req); err != nil {
+			return c.NoContent(http.StatusBadRequest)
+		}
+		return c.JSON(http.StatusOK, noteResponse{Id: 1, Text: req.Text})
+	})
+
+	t.Run("should pass the matching request with its body to the handler", func(t *testing.T) {
+		rec := serve(e, http.MethodPut, "/notes/1?dry_run=true", echo.MIMEApplicationJSON, `{"text":"paid","stars":null,"tags":null}`)
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"text":"paid"`) {
+			t.Errorf("status=%d body=%s; want the note", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("should reject every mismatch of the request at once", func(t *testing.T) {
+		handled = false
+
+		rec := serve(e, http.MethodPut, "/notes/x?dry_run=maybe", echo.MIMEApplicationJSON, `{"text":1,"color":"red"}`)
+
+		p := problemOf(t, rec)
+		if rec.Code != http.StatusBadRequest || p.Status != http.StatusBadRequest || handled {
+			t.Fatalf("status=%d handled=%v; want %d before the handler", rec.Code, handled, http.StatusBadRequest)
+		}
+		got := map[string]bool{}
+		for _, e := range p.Errors {
+			got[e.In+":"+e.Name+e.Pointer] = true
+		}
+		for _, want := range []string{"path:id", "query:dry_run", "body:/text", "body:"} {
+			if !got[want] {
+				t.Errorf("errors=%+v; want one of %s", p.Errors, want)
+			}
+		}
+	})
+
+	t.Run("should reject a body that is not json", func(t *testing.T) {
+		rec := serve(e, http.MethodPut, "/notes/1", echo.MIMEApplicationJSON, `{"text":`)
+
+		if p := problemOf(t, rec); rec.Code != http.StatusBadRequest || len(p.Errors) != 1 {
+			t.Errorf("status=%d errors=%+v; want %d with the json error", rec.Code, p.Errors, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("should reject a body of another content type", func(t *testing.T) {
+		rec := serve(e, http.MethodPut, "/notes/1", echo.MIMETextPlain, `text=paid`)
+
+		if p := problemOf(t, rec); rec.Code != http.StatusUnsupportedMediaType || p.Status != http.StatusUnsupportedMediaType {
+			t.Errorf("status=%d; want %d", rec.Code, http.StatusUnsupportedMediaType)
+		}
+	})
+}
+
+func TestResponses(t *testing.T) {
+	t.Run("should write the matching response", func(t *testing.T) {
+		e := setupValidator(t, func(c echo.Context) error {
+			return c.JSON(http.StatusOK, noteResponse{Id: 1, Text: "paid"})
+		})
+
+		rec := serve(e, http.MethodPut, "/notes/1", echo.MIMEApplicationJSON, `{}`)
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":1`) {
+			t.Errorf("status=%d body=%s; want the note", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("should answer a problem in place of the mismatching response", func(t *testing.T) {
+		for name, handler := range map[string]echo.HandlerFunc{
+			"missing field": func(c echo.Context) error {
+				return c.JSON(http.StatusOK, map[string]any{"id": 1})
+			},
+			"undocumented status": func(c echo.Context) error {
+				return c.NoContent(http.StatusConflict)
+			},
+			"body of a status without": func(c echo.Context) error {
+				return c.String(http.StatusBadRequest, "bad")
+			},
+		} {
+			e := setupValidator(t, handler)
+
+			rec := serve(e, http.MethodPut, "/notes/1", echo.MIMEApplicationJSON, `{}`)
+
+			if p := problemOf(t, rec); rec.Code != http.StatusInternalServerError || len(p.Errors) == 0 {
+				t.Errorf("%s: status=%d errors=%+v; want %d with the mismatch", name, rec.Code, p.Errors, http.StatusInternalServerError)
+			}
+		}
+	})
+
+	t.Run("should not buffer the responses which are not json", func(t *testing.T) {
+		e := setupValidator(t, func(c echo.Context) error {
+			return c.String(http.StatusOK, "paid")
+		})
+
+		rec := serve(e, http.MethodGet, "/notes/1/file", "", "")
+
+		if rec.Code != http.StatusOK || rec.Body.String() != "paid" {
+			t.Errorf("status=%d body=%s; want the file", rec.Code, rec.Body)
+		}
+	})
+}
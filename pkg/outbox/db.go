@@ -0,0 +1,212 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+)
+
+const (
+	insertQuery = "INSERT INTO outbox (aggregate, aggregate_id, type, payload, actor, request_id) values ($1, $2, $3, $4::jsonb, $5, $6)"
+	// lockQuery lets a single instance relay at a time, so an event is not
+	// published by two instances at once. The ids are taken when the events
+	// are added, not when their transactions commit, so the events are
+	// published in the order of their ids, which is not the commit order.
+	lockQuery      = "select pg_try_advisory_lock(hashtext('outbox'))"
+	unlockQuery    = "select pg_advisory_unlock(hashtext('outbox'))"
+	pendingQuery   = "select id, aggregate, aggregate_id, type, payload, actor, request_id, occurred_at from outbox where published_at is null and parked_at is null order by id limit $1"
+	publishedQuery = "UPDATE outbox SET published_at = now(), attempts = attempts + 1, last_error = NULL WHERE id = $1"
+	failedQuery    = "UPDATE outbox SET attempts = attempts + 1, last_error = $2, parked_at = CASE WHEN attempts + 1 >= $3 THEN now() END WHERE id = $1"
+	sinceQuery     = "select id, aggregate, aggregate_id, type, payload, actor, request_id, occurred_at from outbox where id > $1 order by id limit $2"
+	lastQuery      = "select coalesce(max(id), 0) from outbox where occurred_at < $1"
+)
+
+// Add adds e to the outbox of postgres in tx, so e is published only when tx
+// commits.
+func Add(ctx context.Context, tx *sql.Tx, e Event) error {
+	_, err := tx.ExecContext(ctx, insertQuery, e.Aggregate, e.AggregateId, e.Type, string(e.Payload), nullString(e.Actor), nullString(e.RequestId))
+	return err
+}
+
+// nullString stores an empty s as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+type DataMgmt struct {
+	dataMgmt *sql.DB
+	timeout  time.Duration
+}
+
+func New(d *sql.DB, timeout time.Duration) *DataMgmt {
+	return &DataMgmt{dataMgmt: d, timeout: timeout}
+}
+
+func (mgmt DataMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+// queryError prefers the context error and records the error on the query
+// span.
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	tracing.RecordError(ctx, err)
+	return err
+}
+
+func scanEvent(row scanner) (*Event, error) {
+	e := &Event{}
+	var actor, requestId sql.NullString
+	var payload []byte
+	if err := row.Scan(&e.Id, &e.Aggregate, &e.AggregateId, &e.Type, &payload, &actor, &requestId, &e.OccurredAt); err != nil {
+		return nil, err
+	}
+	e.Payload, e.Actor, e.RequestId = payload, actor.String, requestId.String
+	return e, nil
+}
+
+// Relay publishes the pending events while its connection holds the relay
+// lock, an instance finding the outbox locked by another one publishes
+// nothing. Every event is marked in a statement of its own, so no transaction
+// stays open while the events are published. The publications are not bound
+// by the query timeout.
+func (mgmt DataMgmt) Relay(ctx context.Context, limit int, maxAttempts int, publish func(context.Context, Event) error) (int, error) {
+	defer metrics.ObserveQuery("RelayOutbox", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "OutboxMgmt.Relay", pendingQuery)
+	defer span.End()
+
+	conn, err := mgmt.dataMgmt.Conn(ctx)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, lockQuery).Scan(&locked); err != nil {
+		return 0, queryError(ctx, err)
+	}
+	if !locked {
+		return 0, nil
+	}
+	defer mgmt.unlock(conn)
+
+	events, err := mgmt.pending(ctx, conn, limit)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+
+	published := 0
+	var publishErr error
+	for _, e := range events {
+		if publishErr = publish(ctx, e); publishErr != nil {
+			if err := mgmt.exec(ctx, conn, failedQuery, e.Id, publishErr.Error(), maxAttempts); err != nil {
+				return published, queryError(ctx, err)
+			}
+			break
+		}
+		if err := mgmt.exec(ctx, conn, publishedQuery, e.Id); err != nil {
+			return published, queryError(ctx, err)
+		}
+		published++
+	}
+	return published, publishErr
+}
+
+func (mgmt DataMgmt) exec(ctx context.Context, conn *sql.Conn, query string, args ...any) error {
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	_, err := conn.ExecContext(ctx, query, args...)
+	return err
+}
+
+// unlock releases the relay lock of conn, which is discarded instead of
+// going back to the pool with the lock when the release fails.
+func (mgmt DataMgmt) unlock(conn *sql.Conn) {
+	ctx, cancel := mgmt.withTimeout(context.Background())
+	defer cancel()
+
+	if _, err := conn.ExecContext(ctx, unlockQuery); err != nil {
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
+
+func (mgmt DataMgmt) pending(ctx context.Context, conn *sql.Conn, limit int) ([]Event, error) {
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := conn.QueryContext(ctx, pendingQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+// Since returns the events after the id after. An id is taken when the event
+// is added but visible only once its transaction commits, so an event with a
+// lower id than the returned ones may still show up.
+func (mgmt DataMgmt) Since(ctx context.Context, after int64, limit int) ([]Event, error) {
+	defer metrics.ObserveQuery("OutboxSince", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "OutboxMgmt.Since", sinceQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, sinceQuery, after, limit)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		events = append(events, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return events, nil
+}
+
+func (mgmt DataMgmt) LastBefore(ctx context.Context, at time.Time) (int64, error) {
+	defer metrics.ObserveQuery("OutboxLastBefore", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "OutboxMgmt.LastBefore", lastQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	if err := mgmt.dataMgmt.QueryRowContext(ctx, lastQuery, at).Scan(&id); err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,143 @@
+//go:build unit
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var eventColumns = []string{"id", "aggregate", "aggregate_id", "type", "payload", "actor", "request_id", "occurred_at"}
+
+func TestRelay(t *testing.T) {
+	occurredAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("should publish the pending events in order and mark them published", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(pendingQuery)).WithArgs(10).WillReturnRows(sqlmock.NewRows(eventColumns).
+			AddRow(1, "expense", 7, "ExpenseCreated", []byte(`{}`), "alice", "req-1", occurredAt).
+			AddRow(2, "expense", 7, "ExpenseDeleted", []byte(`{}`), nil, nil, occurredAt))
+		mock.ExpectExec(regexp.QuoteMeta(publishedQuery)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(publishedQuery)).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(unlockQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		var published []Event
+		n, err := New(db, time.Second).Relay(context.Background(), 10, 3, func(ctx context.Context, e Event) error {
+			published = append(published, e)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, 2, n)
+		assert.Equal(t, []Event{
+			{Id: 1, Aggregate: "expense", AggregateId: 7, Type: "ExpenseCreated", Payload: []byte(`{}`), Actor: "alice", RequestId: "req-1", OccurredAt: occurredAt},
+			{Id: 2, Aggregate: "expense", AggregateId: 7, Type: "ExpenseDeleted", Payload: []byte(`{}`), OccurredAt: occurredAt},
+		}, published)
+	})
+
+	t.Run("should stop at the first failed event, record its error and keep the published ones", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(pendingQuery)).WithArgs(10).WillReturnRows(sqlmock.NewRows(eventColumns).
+			AddRow(1, "expense", 7, "ExpenseCreated", []byte(`{}`), nil, nil, occurredAt).
+			AddRow(2, "expense", 7, "ExpenseUpdated", []byte(`{}`), nil, nil, occurredAt).
+			AddRow(3, "expense", 7, "ExpenseDeleted", []byte(`{}`), nil, nil, occurredAt))
+		mock.ExpectExec(regexp.QuoteMeta(publishedQuery)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(failedQuery)).WithArgs(2, "sink down", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(unlockQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		n, err := New(db, time.Second).Relay(context.Background(), 10, 3, func(ctx context.Context, e Event) error {
+			if e.Id == 2 {
+				return errors.New("sink down")
+			}
+			return nil
+		})
+
+		assert.EqualError(t, err, "sink down")
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("should publish nothing when another instance relays", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+
+		n, err := New(db, time.Second).Relay(context.Background(), 10, 3, func(ctx context.Context, e Event) error {
+			t.Fatal("unexpected publish")
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, 0, n)
+	})
+	t.Run("should keep the events published before a failed mark", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(pendingQuery)).WithArgs(10).WillReturnRows(sqlmock.NewRows(eventColumns).
+			AddRow(1, "expense", 7, "ExpenseCreated", []byte(`{}`), nil, nil, occurredAt).
+			AddRow(2, "expense", 7, "ExpenseDeleted", []byte(`{}`), nil, nil, occurredAt))
+		mock.ExpectExec(regexp.QuoteMeta(publishedQuery)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(publishedQuery)).WithArgs(2).WillReturnError(errors.New("connection reset"))
+		mock.ExpectExec(regexp.QuoteMeta(unlockQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		n, err := New(db, time.Second).Relay(context.Background(), 10, 3, func(ctx context.Context, e Event) error {
+			return nil
+		})
+
+		assert.EqualError(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, 1, n)
+	})
+}
+
+func TestSince(t *testing.T) {
+	t.Run("should return the events after the id in order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		occurredAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		mock.ExpectQuery(regexp.QuoteMeta(sinceQuery)).WithArgs(4, 2).WillReturnRows(sqlmock.NewRows(eventColumns).
+			AddRow(5, "expense", 7, "ExpenseCreated", []byte(`{}`), "alice", nil, occurredAt).
+			AddRow(7, "expense", 7, "ExpenseUpdated", []byte(`{}`), nil, nil, occurredAt))
+
+		events, err := New(db, time.Second).Since(context.Background(), 4, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Event{
+			{Id: 5, Aggregate: "expense", AggregateId: 7, Type: "ExpenseCreated", Payload: []byte(`{}`), Actor: "alice", OccurredAt: occurredAt},
+			{Id: 7, Aggregate: "expense", AggregateId: 7, Type: "ExpenseUpdated", Payload: []byte(`{}`), OccurredAt: occurredAt},
+		}, events)
+	})
+}
+
+func TestLastBefore(t *testing.T) {
+	t.Run("should return the last id occurred before the time", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		defer db.Close()
+		assert.NoError(t, err)
+		at := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		mock.ExpectQuery(regexp.QuoteMeta(lastQuery)).WithArgs(at).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+		id, err := New(db, time.Second).LastBefore(context.Background(), at)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), id)
+	})
+}
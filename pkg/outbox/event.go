@@ -0,0 +1,23 @@
+// Package outbox publishes the domain events of the writes with a
+// transactional outbox: a write adds its events to the outbox table in its own
+// transaction and the Relay publishes them to a Sink afterwards, at least
+// once. Consumers tell a repeated event by its id.
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is a change of an aggregate, an expense for instance. Id is assigned
+// by the outbox and increases with every event.
+type Event struct {
+	Id          int64           `json:"id"`
+	Aggregate   string          `json:"aggregate"`
+	AggregateId int64           `json:"aggregate_id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Actor       string          `json:"actor,omitempty"`
+	RequestId   string          `json:"request_id,omitempty"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
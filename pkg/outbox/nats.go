@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NatsSink publishes every event to the JetStream subject <subject>.<type>.
+// The event id is the message id, so JetStream drops the events published
+// again within its duplicate window.
+type NatsSink struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+// NewNatsSink connects to url and creates the stream of the subject when it
+// does not exist. The stream is named after the subject, EXPENSES_EVENTS for
+// expenses.events.
+func NewNatsSink(url string, subject string) (*NatsSink, error) {
+	conn, err := nats.Connect(url, nats.Name("expenses-outbox"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err == nil {
+		err = ensureStream(js, subject)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &NatsSink{conn: conn, js: js, subject: subject}, nil
+}
+
+func ensureStream(js nats.JetStreamContext, subject string) error {
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "*", "_", ">", "_").Replace(subject))
+	_, err := js.StreamInfo(name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{Name: name, Subjects: []string{subject + ".>"}})
+	}
+	return err
+}
+
+func (s *NatsSink) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(s.subject + "." + e.Type)
+	msg.Data = data
+	_, err = s.js.PublishMsg(msg, nats.MsgId(strconv.FormatInt(e.Id, 10)), nats.Context(ctx))
+	return err
+}
+
+func (s *NatsSink) Close() error {
+	return s.conn.Drain()
+}
@@ -0,0 +1,93 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+)
+
+// maxBackoff bounds the wait of the relay after failures.
+const maxBackoff = time.Minute
+
+type Options struct {
+	// Interval is the wait once the outbox is drained, and the first wait
+	// after a failure.
+	Interval time.Duration
+	// BatchSize is the number of events published per Storage.Relay call.
+	BatchSize int
+	// PublishTimeout bounds Sink.Publish, zero is unbounded.
+	PublishTimeout time.Duration
+	// MaxAttempts is the number of failed publications after which an event
+	// is parked, so it no longer blocks the events after it.
+	MaxAttempts int
+}
+
+// Relay moves the events of the outbox to the sink.
+type Relay struct {
+	storage Storage
+	sink    Sink
+	options Options
+	log     common.Log
+}
+
+func NewRelay(storage Storage, sink Sink, options Options, log common.Log) *Relay {
+	return &Relay{storage: storage, sink: sink, options: options, log: log}
+}
+
+// Run publishes the pending events until ctx is done. Full batches are
+// followed at once, failures double the wait up to maxBackoff.
+func (r *Relay) Run(ctx context.Context) {
+	failures := 0
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		n, err := r.storage.Relay(ctx, r.options.BatchSize, r.options.MaxAttempts, r.publish)
+		metrics.OutboxPublished(n)
+		wait := r.options.Interval
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			failures++
+			metrics.OutboxFailed()
+			wait = backoff(r.options.Interval, failures)
+			r.log.Warnf("Outbox relay fail, retry in %s : %s", wait, err)
+		case n == r.options.BatchSize:
+			failures = 0
+			wait = 0
+		default:
+			failures = 0
+		}
+		timer.Reset(wait)
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, e Event) error {
+	if r.options.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.options.PublishTimeout)
+		defer cancel()
+	}
+	return r.sink.Publish(ctx, e)
+}
+
+// backoff is interval doubled for every failure after the first one, at most
+// maxBackoff.
+func backoff(interval time.Duration, failures int) time.Duration {
+	wait := interval
+	for i := 1; i < failures && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,131 @@
+//go:build unit
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// StorageStub keeps the pending events in memory like the outbox table.
+type StorageStub struct {
+	mu      sync.Mutex
+	pending []Event
+	calls   int
+}
+
+func (s *StorageStub) Relay(ctx context.Context, limit int, maxAttempts int, publish func(context.Context, Event) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	published := 0
+	for len(s.pending) > 0 && published < limit {
+		if err := publish(ctx, s.pending[0]); err != nil {
+			return published, err
+		}
+		s.pending = s.pending[1:]
+		published++
+	}
+	return published, nil
+}
+
+func (s *StorageStub) Since(ctx context.Context, after int64, limit int) ([]Event, error) {
+	return nil, nil
+}
+
+func (s *StorageStub) LastBefore(ctx context.Context, at time.Time) (int64, error) {
+	return 0, nil
+}
+
+// SinkStub fails the first failures publications.
+type SinkStub struct {
+	mu        sync.Mutex
+	failures  int
+	published []int64
+}
+
+func (s *SinkStub) Publish(ctx context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink down")
+	}
+	s.published = append(s.published, e.Id)
+	return nil
+}
+
+func (s *SinkStub) Close() error {
+	return nil
+}
+
+func (s *SinkStub) ids() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64{}, s.published...)
+}
+
+func events(n int) []Event {
+	result := []Event{}
+	for i := 1; i <= n; i++ {
+		result = append(result, Event{Id: int64(i)})
+	}
+	return result
+}
+
+func TestRelayRun(t *testing.T) {
+	t.Run("should drain full batches without waiting for the interval", func(t *testing.T) {
+		storage := &StorageStub{pending: events(5)}
+		sink := &SinkStub{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go NewRelay(storage, sink, Options{Interval: time.Hour, BatchSize: 2}, logrus.New()).Run(ctx)
+
+		assert.Eventually(t, func() bool { return len(sink.ids()) == 5 }, time.Second, time.Millisecond)
+		assert.Equal(t, []int64{1, 2, 3, 4, 5}, sink.ids())
+	})
+
+	t.Run("should publish again the event whose publication failed", func(t *testing.T) {
+		storage := &StorageStub{pending: events(2)}
+		sink := &SinkStub{failures: 2}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go NewRelay(storage, sink, Options{Interval: time.Millisecond, BatchSize: 10}, logrus.New()).Run(ctx)
+
+		assert.Eventually(t, func() bool { return len(sink.ids()) == 2 }, time.Second, time.Millisecond)
+		assert.Equal(t, []int64{1, 2}, sink.ids())
+	})
+
+	t.Run("should return when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+
+		go func() {
+			NewRelay(&StorageStub{}, &SinkStub{}, Options{Interval: time.Hour, BatchSize: 10}, logrus.New()).Run(ctx)
+			close(done)
+		}()
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Run did not return")
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, backoff(time.Second, 1))
+	assert.Equal(t, 4*time.Second, backoff(time.Second, 3))
+	assert.Equal(t, maxBackoff, backoff(time.Second, 30))
+}
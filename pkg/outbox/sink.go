@@ -0,0 +1,91 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+)
+
+// Sink delivers the events to a downstream system. Publish returns once the
+// system has e, an event whose Publish failed is published again.
+type Sink interface {
+	Publish(ctx context.Context, e Event) error
+	Close() error
+}
+
+// NewSink creates the configured Sink, nil when outbox.sink is none.
+func NewSink(cf config.Config) (Sink, error) {
+	switch cf.OutboxSink() {
+	case config.OutboxSinkNone:
+		return nil, nil
+	case config.OutboxSinkStdout:
+		return NewWriterSink(os.Stdout), nil
+	case config.OutboxSinkFile:
+		return NewFileSink(cf.OutboxFile())
+	case config.OutboxSinkHttp:
+		return NewHttpSink(cf.OutboxUrl(), http.DefaultClient), nil
+	case config.OutboxSinkNats:
+		return NewNatsSink(cf.OutboxNatsUrl(), cf.OutboxSubject())
+	default:
+		return nil, fmt.Errorf("unknown outbox sink: %s", cf.OutboxSink())
+	}
+}
+
+// WriterSink writes the events to w as JSON lines.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Publish(ctx context.Context, e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close leaves w open, it belongs to the caller.
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// FileSink appends the events to a file as JSON lines, synced before Publish
+// returns.
+type FileSink struct {
+	file   *os.File
+	writer *WriterSink
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file, writer: NewWriterSink(file)}, nil
+}
+
+func (s *FileSink) Publish(ctx context.Context, e Event) error {
+	if err := s.writer.Publish(ctx, e); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,45 @@
+//go:build unit
+
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runSinkConformance is the behavior every Sink must have. newSink returns the
+// sink and a function returning the events the downstream system received.
+func runSinkConformance(t *testing.T, newSink func(t *testing.T) (Sink, func() []Event)) {
+	ctx := context.Background()
+	created := Event{Id: 1, Aggregate: "expense", AggregateId: 7, Type: "ExpenseCreated", Payload: json.RawMessage(`{"expense":{"id":7}}`), Actor: "alice", RequestId: "req-1", OccurredAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+	deleted := Event{Id: 2, Aggregate: "expense", AggregateId: 7, Type: "ExpenseDeleted", Payload: json.RawMessage(`{"expense":{"id":7}}`), OccurredAt: time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC)}
+
+	t.Run("Publish should deliver the events in order with every field", func(t *testing.T) {
+		sink, received := newSink(t)
+		defer sink.Close()
+
+		assert.NoError(t, sink.Publish(ctx, created))
+		assert.NoError(t, sink.Publish(ctx, deleted))
+
+		assert.Equal(t, []Event{created, deleted}, received())
+	})
+}
+
+// decodeLines decodes the JSON lines of the writer and file sinks.
+func decodeLines(t *testing.T, data []byte) []Event {
+	events := []Event{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		e := Event{}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
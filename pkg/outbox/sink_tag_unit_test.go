@@ -0,0 +1,183 @@
+//go:build unit
+
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterSinkConformance(t *testing.T) {
+	runSinkConformance(t, func(t *testing.T) (Sink, func() []Event) {
+		var buf bytes.Buffer
+		return NewWriterSink(&buf), func() []Event {
+			return decodeLines(t, buf.Bytes())
+		}
+	})
+}
+
+func TestFileSinkConformance(t *testing.T) {
+	runSinkConformance(t, func(t *testing.T) (Sink, func() []Event) {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sink, func() []Event {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return decodeLines(t, data)
+		}
+	})
+}
+
+// webhook is a stand-in of the http sink receiver, it answers status.
+type webhook struct {
+	mu      sync.Mutex
+	status  int
+	events  []Event
+	headers []http.Header
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	e := Event{}
+	json.Unmarshal(body, &e)
+	w.events = append(w.events, e)
+	w.headers = append(w.headers, r.Header.Clone())
+	rw.WriteHeader(w.status)
+}
+
+func TestHttpSinkConformance(t *testing.T) {
+	runSinkConformance(t, func(t *testing.T) (Sink, func() []Event) {
+		hook := &webhook{status: http.StatusAccepted}
+		server := httptest.NewServer(hook)
+		t.Cleanup(server.Close)
+		return NewHttpSink(server.URL, server.Client()), func() []Event {
+			hook.mu.Lock()
+			defer hook.mu.Unlock()
+			return hook.events
+		}
+	})
+}
+
+func TestHttpSink(t *testing.T) {
+	t.Run("should send the event id as idempotency key and the type", func(t *testing.T) {
+		hook := &webhook{status: http.StatusOK}
+		server := httptest.NewServer(hook)
+		defer server.Close()
+
+		err := NewHttpSink(server.URL, server.Client()).Publish(context.Background(), Event{Id: 42, Type: "ExpenseUpdated", Payload: json.RawMessage(`{}`)})
+
+		assert.NoError(t, err)
+		if assert.Len(t, hook.headers, 1) {
+			assert.Equal(t, "42", hook.headers[0].Get("Idempotency-Key"))
+			assert.Equal(t, "ExpenseUpdated", hook.headers[0].Get("X-Event-Type"))
+			assert.Equal(t, "application/json", hook.headers[0].Get("Content-Type"))
+		}
+	})
+
+	t.Run("should fail when the webhook does not answer 2xx", func(t *testing.T) {
+		server := httptest.NewServer(&webhook{status: http.StatusServiceUnavailable})
+		defer server.Close()
+
+		err := NewHttpSink(server.URL, server.Client()).Publish(context.Background(), Event{Id: 1, Payload: json.RawMessage(`{}`)})
+
+		assert.EqualError(t, err, "webhook answered 503 Service Unavailable")
+	})
+}
+
+// runNats starts an in-process NATS server with JetStream as the stand-in of
+// the broker.
+func runNats(t *testing.T) *server.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	srv := natstest.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func TestNatsSinkConformance(t *testing.T) {
+	runSinkConformance(t, func(t *testing.T) (Sink, func() []Event) {
+		srv := runNats(t)
+		sink, err := NewNatsSink(srv.ClientURL(), "expenses.events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sink, func() []Event {
+			return consumeNats(t, srv.ClientURL(), "expenses.events.>")
+		}
+	})
+}
+
+func TestNatsSink(t *testing.T) {
+	t.Run("should drop an event published again and publish under its type", func(t *testing.T) {
+		srv := runNats(t)
+		sink, err := NewNatsSink(srv.ClientURL(), "expenses.events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sink.Close()
+		e := Event{Id: 1, Type: "ExpenseCreated", Payload: json.RawMessage(`{}`), OccurredAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+		assert.NoError(t, sink.Publish(context.Background(), e))
+		assert.NoError(t, sink.Publish(context.Background(), e))
+
+		assert.Equal(t, []Event{e}, consumeNats(t, srv.ClientURL(), "expenses.events.ExpenseCreated"))
+	})
+}
+
+// consumeNats returns the events stored by JetStream on subject.
+func consumeNats(t *testing.T, url string, subject string) []Event {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := js.SubscribeSync(subject, nats.DeliverAll(), nats.AckNone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	events := []Event{}
+	for {
+		msg, err := sub.NextMsg(200 * time.Millisecond)
+		if err == nats.ErrTimeout {
+			return events
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := Event{}
+		if err := json.Unmarshal(msg.Data, &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+}
@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+)
+
+const (
+	sqliteInsertQuery    = "INSERT INTO outbox (aggregate, aggregate_id, type, payload, actor, request_id, occurred_at) values (?, ?, ?, ?, ?, ?, ?)"
+	sqlitePendingQuery   = "select id, aggregate, aggregate_id, type, payload, actor, request_id, occurred_at from outbox where published_at is null and parked_at is null order by id limit ?"
+	sqlitePublishedQuery = "UPDATE outbox SET published_at = ?, attempts = attempts + 1, last_error = NULL WHERE id = ?"
+	sqliteFailedQuery    = "UPDATE outbox SET attempts = attempts + 1, last_error = ?, parked_at = CASE WHEN attempts + 1 >= ? THEN ? END WHERE id = ?"
+	sqliteSinceQuery     = "select id, aggregate, aggregate_id, type, payload, actor, request_id, occurred_at from outbox where id > ? order by id limit ?"
+	sqliteLastQuery      = "select coalesce(max(id), 0) from outbox where occurred_at < ?"
+)
+
+// sqliteTimeFormat is the layout of the times of the outbox, fixed width so
+// that the text sorts by time.
+const sqliteTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// AddSqlite adds e to the outbox of SQLite in tx, so e is published only when
+// tx commits.
+func AddSqlite(ctx context.Context, tx *sql.Tx, e Event) error {
+	occurredAt := time.Now().UTC().Format(sqliteTimeFormat)
+	_, err := tx.ExecContext(ctx, sqliteInsertQuery, e.Aggregate, e.AggregateId, e.Type, string(e.Payload), nullString(e.Actor), nullString(e.RequestId), occurredAt)
+	return err
+}
+
+// SqliteMgmt is the outbox of the SQLite backend.
+type SqliteMgmt struct {
+	dataMgmt *sql.DB
+	timeout  time.Duration
+}
+
+func NewSqlite(d *sql.DB, timeout time.Duration) *SqliteMgmt {
+	return &SqliteMgmt{dataMgmt: d, timeout: timeout}
+}
+
+func (mgmt SqliteMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+func scanSqliteEvent(row scanner) (*Event, error) {
+	e := &Event{}
+	var actor, requestId sql.NullString
+	var payload, occurredAt string
+	if err := row.Scan(&e.Id, &e.Aggregate, &e.AggregateId, &e.Type, &payload, &actor, &requestId, &occurredAt); err != nil {
+		return nil, err
+	}
+	e.Payload, e.Actor, e.RequestId = []byte(payload), actor.String, requestId.String
+	var err error
+	if e.OccurredAt, err = time.Parse(sqliteTimeFormat, occurredAt); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Relay publishes outside of a transaction, holding the single connection of
+// SQLite during the publications would block every request. SQLite serves a
+// single instance, so the events are still published in order.
+func (mgmt SqliteMgmt) Relay(ctx context.Context, limit int, maxAttempts int, publish func(context.Context, Event) error) (int, error) {
+	defer metrics.ObserveQuery("RelayOutbox", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteOutboxMgmt.Relay", sqlitePendingQuery)
+	defer span.End()
+
+	events, err := mgmt.pending(ctx, limit)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+
+	published := 0
+	for _, e := range events {
+		if publishErr := publish(ctx, e); publishErr != nil {
+			if err := mgmt.exec(ctx, sqliteFailedQuery, publishErr.Error(), maxAttempts, time.Now().UTC().Format(sqliteTimeFormat), e.Id); err != nil {
+				return published, queryError(ctx, err)
+			}
+			return published, publishErr
+		}
+		if err := mgmt.exec(ctx, sqlitePublishedQuery, time.Now().UTC().Format(sqliteTimeFormat), e.Id); err != nil {
+			return published, queryError(ctx, err)
+		}
+		published++
+	}
+	return published, nil
+}
+
+func (mgmt SqliteMgmt) pending(ctx context.Context, limit int) ([]Event, error) {
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, sqlitePendingQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		e, err := scanSqliteEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+func (mgmt SqliteMgmt) exec(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	_, err := mgmt.dataMgmt.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Since returns the events after the id after. SQLite has a single writer,
+// the ids are visible in order.
+func (mgmt SqliteMgmt) Since(ctx context.Context, after int64, limit int) ([]Event, error) {
+	defer metrics.ObserveQuery("OutboxSince", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteOutboxMgmt.Since", sqliteSinceQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, sqliteSinceQuery, after, limit)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		e, err := scanSqliteEvent(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		events = append(events, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return events, nil
+}
+
+func (mgmt SqliteMgmt) LastBefore(ctx context.Context, at time.Time) (int64, error) {
+	defer metrics.ObserveQuery("OutboxLastBefore", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemSqlite, "SqliteOutboxMgmt.LastBefore", sqliteLastQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	if err := mgmt.dataMgmt.QueryRowContext(ctx, sqliteLastQuery, at.UTC().Format(sqliteTimeFormat)).Scan(&id); err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,181 @@
+//go:build unit
+
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func newSqliteDb(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection of :memory: is a different database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migration.New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func addSqlite(t *testing.T, db *sql.DB, events ...Event) {
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		if err := AddSqlite(context.Background(), tx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSqliteRelay(t *testing.T) {
+	t.Run("should publish an event again until its publication succeeds", func(t *testing.T) {
+		db := newSqliteDb(t)
+		addSqlite(t, db,
+			Event{Aggregate: "expense", AggregateId: 1, Type: "ExpenseCreated", Payload: []byte(`{"a":1}`), Actor: "alice"},
+			Event{Aggregate: "expense", AggregateId: 1, Type: "ExpenseUpdated", Payload: []byte(`{"a":2}`)},
+		)
+		storage := NewSqlite(db, time.Second)
+		var published []int64
+		failing := true
+		publish := func(ctx context.Context, e Event) error {
+			if e.Id == 2 && failing {
+				failing = false
+				return errors.New("sink down")
+			}
+			published = append(published, e.Id)
+			return nil
+		}
+
+		first, firstErr := storage.Relay(context.Background(), 10, 3, publish)
+		second, secondErr := storage.Relay(context.Background(), 10, 3, publish)
+		third, thirdErr := storage.Relay(context.Background(), 10, 3, publish)
+
+		assert.EqualError(t, firstErr, "sink down")
+		assert.Equal(t, 1, first)
+		assert.NoError(t, secondErr)
+		assert.Equal(t, 1, second)
+		assert.NoError(t, thirdErr)
+		assert.Equal(t, 0, third)
+		assert.Equal(t, []int64{1, 2}, published)
+
+		var attempts int
+		var lastError sql.NullString
+		db.QueryRow("select attempts, last_error from outbox where id = 2").Scan(&attempts, &lastError)
+		assert.Equal(t, 2, attempts)
+		assert.False(t, lastError.Valid)
+	})
+
+	t.Run("should park an event failing max attempts times and publish the next ones", func(t *testing.T) {
+		db := newSqliteDb(t)
+		addSqlite(t, db,
+			Event{Aggregate: "expense", AggregateId: 1, Type: "ExpenseCreated", Payload: []byte(`{}`)},
+			Event{Aggregate: "expense", AggregateId: 2, Type: "ExpenseCreated", Payload: []byte(`{}`)},
+		)
+		storage := NewSqlite(db, time.Second)
+		var published []int64
+		publish := func(ctx context.Context, e Event) error {
+			if e.Id == 1 {
+				return errors.New("rejected")
+			}
+			published = append(published, e.Id)
+			return nil
+		}
+
+		for i := 0; i < 2; i++ {
+			_, err := storage.Relay(context.Background(), 10, 2, publish)
+			assert.EqualError(t, err, "rejected")
+		}
+		n, err := storage.Relay(context.Background(), 10, 2, publish)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, []int64{2}, published)
+
+		var attempts int
+		var parkedAt sql.NullString
+		db.QueryRow("select attempts, parked_at from outbox where id = 1").Scan(&attempts, &parkedAt)
+		assert.Equal(t, 2, attempts)
+		assert.True(t, parkedAt.Valid)
+	})
+
+	t.Run("should publish at most limit events in id order", func(t *testing.T) {
+		db := newSqliteDb(t)
+		addSqlite(t, db,
+			Event{Aggregate: "expense", AggregateId: 1, Type: "ExpenseCreated", Payload: []byte(`{}`)},
+			Event{Aggregate: "expense", AggregateId: 2, Type: "ExpenseCreated", Payload: []byte(`{}`)},
+			Event{Aggregate: "expense", AggregateId: 3, Type: "ExpenseCreated", Payload: []byte(`{}`)},
+		)
+		var published []Event
+
+		n, err := NewSqlite(db, time.Second).Relay(context.Background(), 2, 3, func(ctx context.Context, e Event) error {
+			published = append(published, e)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		if assert.Len(t, published, 2) {
+			assert.Equal(t, []int64{1, 2}, []int64{published[0].AggregateId, published[1].AggregateId})
+			assert.False(t, published[0].OccurredAt.IsZero())
+		}
+	})
+}
+
+func TestSqliteSince(t *testing.T) {
+	t.Run("should return the published and pending events after the id", func(t *testing.T) {
+		db := newSqliteDb(t)
+		addSqlite(t, db,
+			Event{Aggregate: "expense", AggregateId: 1, Type: "ExpenseCreated", Payload: []byte(`{}`)},
+			Event{Aggregate: "expense", AggregateId: 2, Type: "ExpenseCreated", Payload: []byte(`{}`)},
+			Event{Aggregate: "expense", AggregateId: 3, Type: "ExpenseCreated", Payload: []byte(`{}`)},
+		)
+		storage := NewSqlite(db, time.Second)
+		_, err := storage.Relay(context.Background(), 2, 3, func(ctx context.Context, e Event) error { return nil })
+		assert.NoError(t, err)
+
+		events, err := storage.Since(context.Background(), 1, 10)
+
+		assert.NoError(t, err)
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, []int64{2, 3}, []int64{events[0].Id, events[1].Id})
+		}
+	})
+}
+
+func TestSqliteLastBefore(t *testing.T) {
+	t.Run("should return the last id occurred before the time, 0 when none", func(t *testing.T) {
+		db := newSqliteDb(t)
+		storage := NewSqlite(db, time.Second)
+		before := time.Now().Add(-time.Second)
+		addSqlite(t, db, Event{Aggregate: "expense", AggregateId: 1, Type: "ExpenseCreated", Payload: []byte(`{}`)})
+
+		none, err := storage.LastBefore(context.Background(), before)
+		assert.NoError(t, err)
+		last, err := storage.LastBefore(context.Background(), time.Now().Add(time.Second))
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(0), none)
+		assert.Equal(t, int64(1), last)
+	})
+}
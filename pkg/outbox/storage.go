@@ -0,0 +1,39 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+)
+
+// Storage keeps the events of the outbox.
+type Storage interface {
+	// Relay publishes up to limit pending events in id order and marks them
+	// published. It stops at the first event publish fails, which the next
+	// call retries until it failed maxAttempts times, the event is then
+	// parked and no longer published. It returns the number of published
+	// events.
+	Relay(ctx context.Context, limit int, maxAttempts int, publish func(context.Context, Event) error) (int, error)
+	// Since returns up to limit committed events with an id after the given
+	// one, in id order, published or not.
+	Since(ctx context.Context, after int64, limit int) ([]Event, error)
+	// LastBefore returns the id of the last event occurred before at, 0 when
+	// there is none.
+	LastBefore(ctx context.Context, at time.Time) (int64, error)
+}
+
+// NewStorage creates the outbox Storage of the configured backend. The memory
+// backend keeps no outbox and returns common.ErrUnsupported.
+func NewStorage(ins *config.Instance) (Storage, error) {
+	cf := ins.Config.Get()
+	switch cf.StorageBackend() {
+	case config.BackendPostgres:
+		return New(ins.DB, cf.DbTimeout()), nil
+	case config.BackendSqlite:
+		return NewSqlite(ins.DB, cf.DbTimeout()), nil
+	default:
+		return nil, common.ErrUnsupported
+	}
+}
@@ -0,0 +1,60 @@
+//go:build unit
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Run("should allow every request when rate is zero", func(t *testing.T) {
+		l := New(0, 0)
+
+		for i := 0; i < 100; i++ {
+			assert.True(t, l.Allow("10.0.0.1"))
+		}
+	})
+
+	t.Run("should reject requests over burst per client ip", func(t *testing.T) {
+		l := New(0.001, 2)
+
+		assert.True(t, l.Allow("10.0.0.1"))
+		assert.True(t, l.Allow("10.0.0.1"))
+		assert.False(t, l.Allow("10.0.0.1"))
+		assert.True(t, l.Allow("10.0.0.2"))
+	})
+
+	t.Run("should apply new rate to known clients on update", func(t *testing.T) {
+		l := New(0.001, 1)
+		assert.True(t, l.Allow("10.0.0.1"))
+		assert.False(t, l.Allow("10.0.0.1"))
+
+		l.Update(0, 1)
+
+		assert.True(t, l.Allow("10.0.0.1"))
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("should return http status code = 429 when over the limit", func(t *testing.T) {
+		e := echo.New()
+		e.Use(Middleware(New(0.001, 1), nil))
+		e.GET("/expenses", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		codes := []int{}
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/expenses", nil))
+			codes = append(codes, rec.Code)
+		}
+
+		assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+	})
+}
@@ -0,0 +1,200 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// column is a field of a struct in csv, named by its json name.
+type column struct {
+	name  string
+	index []int
+}
+
+// columns returns the fields of the struct t in the order of the json
+// encoding, the fields of an embedded struct are columns of t.
+func columns(t reflect.Type) []column {
+	var result []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			for _, c := range columns(f.Type) {
+				result = append(result, column{name: c.name, index: append([]int{i}, c.index...)})
+			}
+			continue
+		}
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		result = append(result, column{name: name, index: []int{i}})
+	}
+	return result
+}
+
+// encodeCSV writes the struct v, or every struct of the slice v, as a row
+// under a header of the column names.
+func encodeCSV(w io.Writer, v any) error {
+	rows := reflect.Indirect(reflect.ValueOf(v))
+	if rows.Kind() != reflect.Slice {
+		rows = reflect.Append(reflect.MakeSlice(reflect.SliceOf(rows.Type()), 0, 1), rows)
+	}
+	t := rows.Type().Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("render: no csv for %s", rows.Type())
+	}
+
+	cols := columns(t)
+	out := csv.NewWriter(w)
+	record := make([]string, len(cols))
+	for i, c := range cols {
+		record[i] = c.name
+	}
+	if err := out.Write(record); err != nil {
+		return err
+	}
+	for i := 0; i < rows.Len(); i++ {
+		row := reflect.Indirect(rows.Index(i))
+		for j, c := range cols {
+			cell, err := formatCell(row.FieldByIndex(c.index))
+			if err != nil {
+				return err
+			}
+			record[j] = cell
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// formatCell writes a nil pointer as an empty cell, a list of strings joined
+// with commas like the tags of the queries and the other structured values as
+// json. The strings are escaped from the formulas of the spreadsheets.
+func formatCell(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return escapeFormula(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			return escapeFormula(strings.Join(v.Interface().([]string), ",")), nil
+		}
+	}
+	b, err := json.Marshal(v.Interface())
+	return string(b), err
+}
+
+// escapeFormula prefixes with a quote a cell a spreadsheet would run as a
+// formula.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// decodeCSV reads a header of column names and a single row into the struct
+// v, the columns left out keep their zero value.
+func decodeCSV(r io.Reader, v any) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) != 2 {
+		return errors.New("render: csv body must have a header and a single row")
+	}
+
+	target := reflect.ValueOf(v).Elem()
+	byName := map[string]column{}
+	for _, c := range columns(target.Type()) {
+		byName[c.name] = c
+	}
+	for i, name := range records[0] {
+		c, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return fmt.Errorf("render: unknown csv column %q", name)
+		}
+		if err := parseCell(target.FieldByIndex(c.index), records[1][i]); err != nil {
+			return fmt.Errorf("render: csv column %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseCell is the reverse of formatCell.
+func parseCell(v reflect.Value, cell string) error {
+	if v.Kind() == reflect.Pointer {
+		if cell == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(cell)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(cell)
+		v.SetBool(b)
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cell, 10, v.Type().Bits())
+		v.SetInt(n)
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(cell, 10, v.Type().Bits())
+		v.SetUint(n)
+		return err
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(cell, v.Type().Bits())
+		v.SetFloat(f)
+		return err
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			var items []string
+			for _, item := range strings.Split(cell, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			v.Set(reflect.ValueOf(items).Convert(v.Type()))
+			return nil
+		}
+	}
+	return json.Unmarshal([]byte(cell), v.Addr().Interface())
+}
@@ -0,0 +1,216 @@
+// Package render writes the responses in the media type the client accepts,
+// json, xml, csv or msgpack, and reads the request bodies in the media type
+// they are sent in.
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	MIMETextCSV = "text/csv"
+
+	// formatKey holds the negotiated media type in the echo.Context.
+	formatKey = "render.format"
+	// offersKey holds the media types of the route in the echo.Context.
+	offersKey = "render.offers"
+)
+
+// Formats are the media types besides json, which is the default.
+var Formats = []string{echo.MIMEApplicationXML, MIMETextCSV, echo.MIMEApplicationMsgpack}
+
+// mediaTypes are the media types in the order of preference of the server.
+var mediaTypes = append([]string{echo.MIMEApplicationJSON}, Formats...)
+
+// aliases are the other names the clients give to the media types.
+var aliases = map[string]string{
+	echo.MIMETextXML:          echo.MIMEApplicationXML,
+	"application/x-msgpack":   echo.MIMEApplicationMsgpack,
+	"application/vnd.msgpack": echo.MIMEApplicationMsgpack,
+}
+
+// ErrUnsupportedMediaType is returned by Bind for a body of another media
+// type.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+func canonical(mediaType string) string {
+	mediaType = strings.ToLower(mediaType)
+	if name, ok := aliases[mediaType]; ok {
+		return name
+	}
+	return mediaType
+}
+
+// negotiate returns the media type of offers preferred by accept, the first
+// one when accept is empty. A media type is given the quality of the most
+// specific range matching it, the order of offers breaks the ties.
+func negotiate(accept string, offers []string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, best != ""
+}
+
+func quality(accept string, offer string) float64 {
+	q, specificity := 0.0, 0
+	offerType, _, _ := strings.Cut(offer, "/")
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		s := 0
+		switch mediaType = canonical(mediaType); {
+		case mediaType == offer:
+			s = 3
+		case mediaType == offerType+"/*":
+			s = 2
+		case mediaType == "*/*":
+			s = 1
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				q = 0
+			}
+		}
+	}
+	return q
+}
+
+// Negotiate answers 406 to the requests accepting none of the media types
+// before the handler runs, so that a write is not made for a response the
+// client cannot read. The route reads and writes only offers when given,
+// json first, every media type otherwise.
+func Negotiate(offers ...string) echo.MiddlewareFunc {
+	if len(offers) == 0 {
+		offers = mediaTypes
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			format, ok := negotiate(c.Request().Header.Get(echo.HeaderAccept), offers)
+			if !ok {
+				return c.NoContent(http.StatusNotAcceptable)
+			}
+			c.Set(formatKey, format)
+			c.Set(offersKey, offers)
+			return next(c)
+		}
+	}
+}
+
+// Render writes v in the negotiated media type.
+func Render(c echo.Context, status int, v any) error {
+	return render(c, status, "", v)
+}
+
+// List writes the slice v in the negotiated media type, the xml elements of
+// its items in an element of name.
+func List(c echo.Context, status int, name string, v any) error {
+	return render(c, status, name, v)
+}
+
+// list is a slice in an xml element, its items are named by their XMLName.
+type list struct {
+	XMLName xml.Name
+	Items   any `xml:"item"`
+}
+
+func render(c echo.Context, status int, name string, v any) error {
+	format, ok := c.Get(formatKey).(string)
+	if !ok {
+		if format, ok = negotiate(c.Request().Header.Get(echo.HeaderAccept), mediaTypes); !ok {
+			return c.NoContent(http.StatusNotAcceptable)
+		}
+	}
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+
+	switch format {
+	case echo.MIMEApplicationXML:
+		if name != "" {
+			v = list{XMLName: xml.Name{Local: name}, Items: v}
+		}
+		return c.XML(status, v)
+	case MIMETextCSV:
+		var b bytes.Buffer
+		if err := encodeCSV(&b, v); err != nil {
+			return err
+		}
+		return c.Blob(status, MIMETextCSV+"; charset=utf-8", b.Bytes())
+	case echo.MIMEApplicationMsgpack:
+		var b bytes.Buffer
+		enc := msgpack.NewEncoder(&b)
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return c.Blob(status, echo.MIMEApplicationMsgpack, b.Bytes())
+	}
+	return c.JSON(status, v)
+}
+
+// Bind reads the body of the request into v by its Content-Type. An empty
+// body without Content-Type leaves v as is, ErrUnsupportedMediaType is
+// returned for the other bodies without Content-Type or of a media type the
+// route does not read.
+func Bind(c echo.Context, v any) error {
+	req := c.Request()
+	header := req.Header.Get(echo.HeaderContentType)
+	if header == "" {
+		err := c.Bind(v)
+		if errors.Is(err, echo.ErrUnsupportedMediaType) {
+			return fmt.Errorf("%w: no %s", ErrUnsupportedMediaType, echo.HeaderContentType)
+		}
+		return err
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, header)
+	}
+
+	mediaType = canonical(mediaType)
+	if offers, ok := c.Get(offersKey).([]string); ok && !contains(offers, mediaType) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+	switch mediaType {
+	case echo.MIMEApplicationJSON:
+		return c.Bind(v)
+	case echo.MIMEApplicationXML:
+		return xml.NewDecoder(req.Body).Decode(v)
+	case MIMETextCSV:
+		return decodeCSV(req.Body, v)
+	case echo.MIMEApplicationMsgpack:
+		dec := msgpack.NewDecoder(req.Body)
+		dec.SetCustomStructTag("json")
+		return dec.Decode(v)
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
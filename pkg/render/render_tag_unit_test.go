@@ -0,0 +1,223 @@
+//go:build unit
+
+package render
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type item struct {
+	Id   int64    `json:"id" xml:"id"`
+	Name string   `json:"name" xml:"name"`
+	Tags []string `json:"tags" xml:"tags>tag"`
+	Ref  *int64   `json:"ref,omitempty" xml:"ref,omitempty"`
+}
+
+func serve(t *testing.T, req *http.Request, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	e.POST("/", handler, Negotiate())
+	e.GET("/", handler, Negotiate())
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"", echo.MIMEApplicationJSON, true},
+		{"*/*", echo.MIMEApplicationJSON, true},
+		{"application/xml", echo.MIMEApplicationXML, true},
+		{"text/xml", echo.MIMEApplicationXML, true},
+		{"text/*", MIMETextCSV, true},
+		{"application/x-msgpack", echo.MIMEApplicationMsgpack, true},
+		{"application/json;q=0.5, text/csv", MIMETextCSV, true},
+		{"application/xml;q=0.9, */*;q=0.1", echo.MIMEApplicationXML, true},
+		{"*/*, application/json;q=0", echo.MIMEApplicationXML, true},
+		{"text/html", "", false},
+		{"application/json;q=0", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := negotiate(tc.accept, mediaTypes)
+		assert.Equal(t, tc.ok, ok, tc.accept)
+		assert.Equal(t, tc.want, got, tc.accept)
+	}
+}
+
+func TestRender(t *testing.T) {
+	ref := int64(7)
+	one := item{Id: 1, Name: "a", Tags: []string{"x", "y"}, Ref: &ref}
+	render := func(c echo.Context) error { return Render(c, http.StatusCreated, one) }
+
+	t.Run("should write json without Accept", func(t *testing.T) {
+		rec := serve(t, httptest.NewRequest(http.MethodGet, "/", nil), render)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+		assert.JSONEq(t, `{"id":1,"name":"a","tags":["x","y"],"ref":7}`, rec.Body.String())
+	})
+
+	t.Run("should write xml", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationXML)
+		rec := serve(t, req, render)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, echo.MIMEApplicationXMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Body.String(), "<item><id>1</id><name>a</name><tags><tag>x</tag><tag>y</tag></tags><ref>7</ref></item>")
+	})
+
+	t.Run("should write csv with a header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, MIMETextCSV)
+		rec := serve(t, req, render)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "id,name,tags,ref\n1,a,\"x,y\",7\n", rec.Body.String())
+	})
+
+	t.Run("should escape the csv cells a spreadsheet would run as formulas", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, MIMETextCSV)
+		rec := serve(t, req, func(c echo.Context) error {
+			return List(c, http.StatusOK, "items", []item{
+				{Id: -1, Name: "=HYPERLINK(\"http://evil\")", Tags: []string{"@x", "y"}},
+				{Id: 2, Name: "+1"},
+				{Id: 3, Name: "-1"},
+				{Id: 4, Name: "a=b"},
+			})
+		})
+
+		assert.Equal(t, "id,name,tags,ref\n-1,\"'=HYPERLINK(\"\"http://evil\"\")\",\"'@x,y\",\n2,'+1,,\n3,'-1,,\n4,a=b,,\n", rec.Body.String())
+	})
+
+	t.Run("should write msgpack with the json names", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationMsgpack)
+		rec := serve(t, req, render)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, echo.MIMEApplicationMsgpack, rec.Header().Get(echo.HeaderContentType))
+		got := map[string]any{}
+		assert.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), &got))
+		assert.EqualValues(t, "a", got["name"])
+		assert.Contains(t, got, "tags")
+	})
+
+	t.Run("should return 406 when no media type is accepted", func(t *testing.T) {
+		called := false
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, "text/html")
+		rec := serve(t, req, func(c echo.Context) error {
+			called = true
+			return render(c)
+		})
+
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+		assert.False(t, called)
+	})
+}
+
+func TestList(t *testing.T) {
+	items := []item{{Id: 1, Name: "a"}, {Id: 2, Name: "b", Tags: []string{"x"}}}
+	list := func(c echo.Context) error { return List(c, http.StatusOK, "items", items) }
+
+	t.Run("should wrap the xml items in an element", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationXML)
+		rec := serve(t, req, list)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "<items><item><id>1</id><name>a</name><tags></tags></item><item><id>2</id>")
+		assert.True(t, strings.HasSuffix(rec.Body.String(), "</items>"))
+	})
+
+	t.Run("should write a csv row per item", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAccept, MIMETextCSV)
+		rec := serve(t, req, list)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "id,name,tags,ref\n1,a,,\n2,b,x,\n", rec.Body.String())
+	})
+}
+
+func TestBind(t *testing.T) {
+	bind := func(got *item) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := Bind(c, got); err != nil {
+				return c.String(http.StatusBadRequest, err.Error())
+			}
+			return c.NoContent(http.StatusNoContent)
+		}
+	}
+	ref := int64(7)
+	want := item{Id: 1, Name: "a", Tags: []string{"x", "y"}, Ref: &ref}
+
+	packed := &bytes.Buffer{}
+	enc := msgpack.NewEncoder(packed)
+	enc.SetCustomStructTag("json")
+	assert.NoError(t, enc.Encode(want))
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"json", echo.MIMEApplicationJSON, `{"id":1,"name":"a","tags":["x","y"],"ref":7}`},
+		{"xml", echo.MIMEApplicationXML, "<item><id>1</id><name>a</name><tags><tag>x</tag><tag>y</tag></tags><ref>7</ref></item>"},
+		{"text/xml", echo.MIMETextXMLCharsetUTF8, "<item><id>1</id><name>a</name><tags><tag>x</tag><tag>y</tag></tags><ref>7</ref></item>"},
+		{"csv", MIMETextCSV, "id,name,tags,ref\n1,a,\"x, y\",7\n"},
+		{"msgpack", echo.MIMEApplicationMsgpack, packed.String()},
+	}
+	for _, tc := range cases {
+		t.Run("should read "+tc.name, func(t *testing.T) {
+			got := item{}
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			rec := serve(t, req, bind(&got))
+
+			assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("should return ErrUnsupportedMediaType for another media type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=a"))
+		req.Header.Set(echo.HeaderContentType, "text/plain")
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		assert.ErrorIs(t, Bind(c, &item{}), ErrUnsupportedMediaType)
+	})
+
+	t.Run("should return ErrUnsupportedMediaType for a body without Content-Type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":1}`))
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		assert.ErrorIs(t, Bind(c, &item{}), ErrUnsupportedMediaType)
+	})
+
+	t.Run("should reject an unknown csv column", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("id,colour\n1,red\n"))
+		req.Header.Set(echo.HeaderContentType, MIMETextCSV)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		err := Bind(c, &item{})
+		assert.ErrorContains(t, err, "colour")
+		assert.NotErrorIs(t, err, ErrUnsupportedMediaType)
+	})
+}
@@ -0,0 +1,165 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/lib/pq"
+)
+
+const (
+	searchQuery = `select e.id, e.title, e.amount, e.note, e.tags, e.category_id, ts_rank(e.search, q.query) AS rank,
+ts_headline('expenses_search', e.title, q.query, $2), ts_headline('expenses_search', e.note, q.query, $3)
+from expenses e, to_tsquery('expenses_search', $1) AS q(query)
+where e.search @@ q.query
+order by rank desc, e.id
+limit $4 offset $5`
+	// dictionaryQuery returns the dictionary of the ascii words, which
+	// Configure maps together with the other words.
+	dictionaryQuery = `select d.dictname from pg_ts_config_map m
+join pg_ts_config c on c.oid = m.mapcfg
+join pg_ts_dict d on d.oid = m.mapdict
+where c.cfgname = 'expenses_search' and m.maptokentype = (select tokid from ts_token_type('default') where alias = 'asciiword')
+order by m.mapseqno limit 1`
+	lockQuery   = "select pg_advisory_xact_lock(hashtext('expenses_search'))"
+	existsQuery = "select exists (select 1 from pg_ts_dict where dictname = $1)"
+	// alterQuery is completed with the dictionaries, which cannot be bound.
+	alterQuery = "ALTER TEXT SEARCH CONFIGURATION expenses_search ALTER MAPPING FOR asciiword, asciihword, hword_asciipart, word, hword, hword_part WITH "
+	// refreshQuery recomputes the search column of every expense by its trigger.
+	refreshQuery = "UPDATE expenses SET title = title"
+)
+
+// The matches are marked with characters of the private use area, so the
+// highlights can be HTML escaped before the marks become <mark> elements.
+const (
+	startSel = "\uE000"
+	stopSel  = "\uE001"
+
+	titleOptions = `StartSel="` + startSel + `", StopSel="` + stopSel + `", HighlightAll=true`
+	noteOptions  = `StartSel="` + startSel + `", StopSel="` + stopSel + `", MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=" … "`
+)
+
+// syntaxError is the postgres error code of a tsquery it cannot parse.
+const syntaxError = "42601"
+
+type DataMgmt struct {
+	dataMgmt *sql.DB
+	timeout  time.Duration
+}
+
+func New(d *sql.DB, timeout time.Duration) *DataMgmt {
+	return &DataMgmt{dataMgmt: d, timeout: timeout}
+}
+
+func (mgmt DataMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+// queryError prefers the context error and maps a query postgres cannot parse
+// to common.ErrInvalid.
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == syntaxError {
+		err = fmt.Errorf("%w: %s", common.ErrInvalid, pqErr.Message)
+	}
+	tracing.RecordError(ctx, err)
+	return err
+}
+
+// Search returns the expenses matching query.Text, the best ranked first.
+func (mgmt DataMgmt) Search(ctx context.Context, query Query) ([]SearchResponse, error) {
+	defer metrics.ObserveQuery("SearchExpenses", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "SearchMgmt.Search", searchQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, searchQuery, ParseQuery(query.Text), titleOptions, noteOptions, query.Limit, query.Offset)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []SearchResponse{}
+	for rows.Next() {
+		found := SearchResponse{}
+		var category sql.NullInt64
+		err := rows.Scan(&found.Id, &found.Title, &found.Amount, &found.Note, pq.Array(&found.Tags), &category, &found.Rank, &found.Highlights.Title, &found.Highlights.Note)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		if category.Valid {
+			found.CategoryId = &category.Int64
+		}
+		found.Highlights.Title = highlight(found.Highlights.Title)
+		found.Highlights.Note = highlight(found.Highlights.Note)
+		result = append(result, found)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Configure maps the words of the expenses_search configuration to dictionary,
+// falling back to simple for the words dictionary does not know, and rewrites
+// the search column when the mapping changes. It is meant for startup, the
+// rewrite is not bound by the query timeout.
+func (mgmt DataMgmt) Configure(ctx context.Context, dictionary string) error {
+	defer metrics.ObserveQuery("ConfigureSearch", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "SearchMgmt.Configure", alterQuery)
+	defer span.End()
+
+	tx, err := mgmt.dataMgmt.BeginTx(ctx, nil)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, lockQuery); err != nil {
+		return queryError(ctx, err)
+	}
+	var current string
+	if err := tx.QueryRowContext(ctx, dictionaryQuery).Scan(&current); err != nil {
+		return queryError(ctx, err)
+	}
+	if current == dictionary {
+		return nil
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, existsQuery, dictionary).Scan(&exists); err != nil {
+		return queryError(ctx, err)
+	}
+	if !exists {
+		return queryError(ctx, fmt.Errorf("%w: unknown text search dictionary %q", common.ErrInvalid, dictionary))
+	}
+	dictionaries := []string{pq.QuoteIdentifier(dictionary)}
+	if dictionary != "simple" {
+		dictionaries = append(dictionaries, "simple")
+	}
+	if _, err := tx.ExecContext(ctx, alterQuery+strings.Join(dictionaries, ", ")); err != nil {
+		return queryError(ctx, err)
+	}
+	if _, err := tx.ExecContext(ctx, refreshQuery); err != nil {
+		return queryError(ctx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return queryError(ctx, err)
+	}
+	return nil
+}
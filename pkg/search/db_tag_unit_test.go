@@ -0,0 +1,108 @@
+//go:build unit
+
+package search
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var searchColumns = []string{"id", "title", "amount", "note", "tags", "category_id", "rank", "title_headline", "note_headline"}
+
+func TestSearch(t *testing.T) {
+	t.Run("should return the ranked expenses with their highlights", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		rows := sqlmock.NewRows(searchColumns).
+			AddRow(1, "Taxi", 250, "to <Chiang Mai>", pq.Array([]string{"travel"}), 3, 0.6, startSel+"Taxi"+stopSel, "to <"+startSel+"Chiang"+stopSel+" Mai>").
+			AddRow(2, "Lunch", 80, "taxi driver", pq.Array([]string{}), nil, 0.1, "Lunch", startSel+"taxi"+stopSel+" driver")
+		mock.ExpectQuery(regexp.QuoteMeta(searchQuery)).
+			WithArgs("'taxi' & 'chiang':*", titleOptions, noteOptions, 20, 0).
+			WillReturnRows(rows)
+
+		dataMgmt := New(db, time.Second)
+		result, err := dataMgmt.Search(context.Background(), Query{Text: "taxi chiang*", Limit: 20})
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.Equal(t, int64(1), result[0].Id)
+		assert.Equal(t, int64(3), *result[0].CategoryId)
+		assert.Equal(t, []string{"travel"}, result[0].Tags)
+		assert.Equal(t, float32(0.6), result[0].Rank)
+		assert.Equal(t, Highlights{Title: "<mark>Taxi</mark>", Note: "to &lt;<mark>Chiang</mark> Mai&gt;"}, result[0].Highlights)
+		assert.Nil(t, result[1].CategoryId)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return common.ErrInvalid when postgres cannot parse the query", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery(regexp.QuoteMeta(searchQuery)).WillReturnError(&pq.Error{Code: "42601", Message: "syntax error in tsquery"})
+
+		dataMgmt := New(db, time.Second)
+		result, err := dataMgmt.Search(context.Background(), Query{Text: "taxi", Limit: 20})
+
+		assert.ErrorIs(t, err, common.ErrInvalid)
+		assert.Nil(t, result)
+	})
+}
+
+func TestConfigure(t *testing.T) {
+	t.Run("should leave the configuration when it already uses the dictionary", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(dictionaryQuery)).WillReturnRows(sqlmock.NewRows([]string{"dictname"}).AddRow("simple"))
+		mock.ExpectRollback()
+
+		err = New(db, time.Second).Configure(context.Background(), "simple")
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should map the words and rewrite the search column when the dictionary changes", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(dictionaryQuery)).WillReturnRows(sqlmock.NewRows([]string{"dictname"}).AddRow("simple"))
+		mock.ExpectQuery(regexp.QuoteMeta(existsQuery)).WithArgs("english_stem").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec(regexp.QuoteMeta(alterQuery + `"english_stem", simple`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(refreshQuery)).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err = New(db, time.Second).Configure(context.Background(), "english_stem")
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return common.ErrInvalid for an unknown dictionary", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(dictionaryQuery)).WillReturnRows(sqlmock.NewRows([]string{"dictname"}).AddRow("simple"))
+		mock.ExpectQuery(regexp.QuoteMeta(existsQuery)).WithArgs("thai").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectRollback()
+
+		err = New(db, time.Second).Configure(context.Background(), "thai")
+
+		assert.ErrorIs(t, err, common.ErrInvalid)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/labstack/echo/v4"
+)
+
+type Services interface {
+	SearchExpenses(ctx context.Context, query Query) ([]SearchResponse, error)
+}
+
+type Handler struct {
+	log     common.Log
+	service Services
+}
+
+func NewHandler(s Services, l common.Log) *Handler {
+	return &Handler{service: s, log: l}
+}
+
+// queryInt parses the query parameter name, missing is zero.
+func queryInt(c echo.Context, name string) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func (h Handler) SearchExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.SearchExpenses")
+	defer span.End()
+
+	query := Query{Text: c.QueryParam("q")}
+	var err error
+	if query.Limit, err = queryInt(c, "limit"); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	if query.Offset, err = queryInt(c, "offset"); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.SearchExpenses(ctx, query)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler SearchExpenses Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
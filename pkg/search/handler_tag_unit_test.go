@@ -0,0 +1,80 @@
+//go:build unit
+
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ServiceStub struct {
+	query Query
+	code  int
+}
+
+func (s *ServiceStub) SearchExpenses(ctx context.Context, query Query) ([]SearchResponse, error) {
+	s.query = query
+	if s.code != 0 {
+		return nil, &common.Error{Code: s.code}
+	}
+	return []SearchResponse{{
+		ExpensesResponse: expenses.ExpensesResponse{Id: 1, Title: "Taxi", Amount: 250, Tags: []string{"travel"}},
+		Rank:             0.5,
+		Highlights:       Highlights{Title: "<mark>Taxi</mark>"},
+	}}, nil
+}
+
+func newContext(target string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestSearchExpensesHandler(t *testing.T) {
+	t.Run("should return http status code = 200 and the results", func(t *testing.T) {
+		c, rec := newContext(`/expenses/search?q=taxi+%22chiang+mai%22&limit=5&offset=10`)
+		service := &ServiceStub{}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.SearchExpenses(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, Query{Text: `taxi "chiang mai"`, Limit: 5, Offset: 10}, service.query)
+		var body []map[string]any
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "Taxi", body[0]["title"])
+		assert.Equal(t, 0.5, body[0]["rank"])
+		assert.Equal(t, map[string]any{"title": "<mark>Taxi</mark>", "note": ""}, body[0]["highlights"])
+	})
+
+	t.Run("should return http status code = 400 when limit is not a number", func(t *testing.T) {
+		c, rec := newContext("/expenses/search?q=taxi&limit=many")
+		handler := NewHandler(&ServiceStub{}, logrus.New())
+
+		err := handler.SearchExpenses(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("should return the status code of the service error", func(t *testing.T) {
+		c, rec := newContext("/expenses/search?q=")
+		handler := NewHandler(&ServiceStub{code: http.StatusBadRequest}, logrus.New())
+
+		err := handler.SearchExpenses(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,93 @@
+// Package search finds expenses by the words of their title, note and tags
+// with the postgres full text search.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ParseQuery turns the search box syntax into the text of a tsquery of the
+// expenses_search configuration. Words must all match, "quoted words" must
+// match in order, word* matches the words starting with word and -word
+// excludes the expenses with word. The returned text is empty when q has no
+// word.
+func ParseQuery(q string) string {
+	var terms []string
+	for _, t := range split(q) {
+		if term := t.tsquery(); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+type term struct {
+	words  []string
+	prefix bool
+	negate bool
+}
+
+func (t term) tsquery() string {
+	lexemes := make([]string, 0, len(t.words))
+	for _, w := range t.words {
+		if w = strings.TrimFunc(w, isSyntax); w != "" {
+			lexemes = append(lexemes, quote(w))
+		}
+	}
+	if len(lexemes) == 0 {
+		return ""
+	}
+	if t.prefix {
+		lexemes[len(lexemes)-1] += ":*"
+	}
+	result := strings.Join(lexemes, " <-> ")
+	if len(lexemes) > 1 {
+		result = "(" + result + ")"
+	}
+	if t.negate {
+		result = "!" + result
+	}
+	return result
+}
+
+// split cuts q into terms, an unterminated quote runs to the end of q. The
+// last word of a phrase may be a prefix too.
+func split(q string) []term {
+	var terms []term
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		t := term{}
+		if strings.HasPrefix(q, "-") {
+			t.negate = true
+			q = q[1:]
+		}
+		if strings.HasPrefix(q, `"`) {
+			phrase, rest, _ := strings.Cut(q[1:], `"`)
+			t.words, q = strings.Fields(phrase), rest
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				end = len(q)
+			}
+			t.words, q = []string{q[:end]}, q[end:]
+		}
+		if n := len(t.words); n > 0 && strings.HasSuffix(t.words[n-1], "*") {
+			t.prefix = true
+		}
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// isSyntax reports the characters of the search box syntax left around a word.
+func isSyntax(r rune) bool {
+	return r == '"' || r == '*' || r == '-'
+}
+
+// quote makes w a single quoted tsquery operand, which to_tsquery still
+// normalizes with the dictionary.
+func quote(w string) string {
+	w = strings.ReplaceAll(w, `\`, `\\`)
+	w = strings.ReplaceAll(w, `'`, `''`)
+	return "'" + w + "'"
+}
@@ -0,0 +1,41 @@
+//go:build unit
+
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want string
+	}{
+		{name: "words must all match", q: "taxi  chiang", want: "'taxi' & 'chiang'"},
+		{name: "quoted words are a phrase", q: `taxi "chiang mai"`, want: "'taxi' & ('chiang' <-> 'mai')"},
+		{name: "a star makes a prefix", q: "tax*", want: "'tax':*"},
+		{name: "a phrase may end with a prefix", q: `"chiang ma*"`, want: "('chiang' <-> 'ma':*)"},
+		{name: "a minus excludes", q: "taxi -grab", want: "'taxi' & !'grab'"},
+		{name: "thai words are kept", q: "แท็กซี่ เชียง*", want: "'แท็กซี่' & 'เชียง':*"},
+		{name: "an unterminated quote runs to the end", q: `"chiang mai`, want: "('chiang' <-> 'mai')"},
+		{name: "quotes and backslashes are escaped", q: `o'neil a\b`, want: `'o''neil' & 'a\\b'`},
+		{name: "operators are words", q: "a&b | !c", want: "'a&b' & '|' & '!c'"},
+		{name: "syntax alone has no word", q: ` "" * - `, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseQuery(tt.q))
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	t.Run("should escape the headline and mark the matches", func(t *testing.T) {
+		got := highlight("<b>taxi</b> to " + startSel + "chiang" + stopSel + " & back")
+
+		assert.Equal(t, "&lt;b&gt;taxi&lt;/b&gt; to <mark>chiang</mark> &amp; back", got)
+	})
+}
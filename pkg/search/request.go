@@ -0,0 +1,8 @@
+package search
+
+// Query is a search of the expenses, Text is in the syntax of ParseQuery.
+type Query struct {
+	Text   string
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,29 @@
+package search
+
+import (
+	"html"
+	"strings"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+)
+
+type SearchResponse struct {
+	expenses.ExpensesResponse
+	Rank       float32    `json:"rank"`
+	Highlights Highlights `json:"highlights"`
+}
+
+// Highlights are HTML escaped with the matching words in <mark> elements, the
+// note is cut to the fragments around its matches.
+type Highlights struct {
+	Title string `json:"title"`
+	Note  string `json:"note"`
+}
+
+// highlight escapes the headline of postgres and turns its marks into <mark>
+// elements.
+func highlight(headline string) string {
+	headline = html.EscapeString(headline)
+	headline = strings.ReplaceAll(headline, startSel, "<mark>")
+	return strings.ReplaceAll(headline, stopSel, "</mark>")
+}
@@ -0,0 +1,36 @@
+package search
+
+import (
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/apiversion"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/labstack/echo/v4"
+)
+
+// operations are the routes of Routes, the same in every version of the api.
+var operations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/expenses/search", Id: "SearchExpenses", Summary: "Search the title and note of the expenses", Tag: "expenses",
+		Params: []openapi.Param{
+			{Name: "q", In: "query", Description: `words to match, "quoted words" in order, word* prefixes and -word exclusions`, Schema: ""},
+			{Name: "limit", In: "query", Description: "number of results", Schema: 0},
+			{Name: "offset", In: "query", Description: "number of results skipped", Schema: 0},
+		}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the matching expenses, best first", Body: []SearchResponse{}},
+			openapi.BadRequest,
+		}},
+}
+
+// Operations describe the routes of Routes in the OpenAPI document, in every
+// version of versions.
+func Operations(versions apiversion.Versions) []openapi.Operation {
+	return versions.Same(1, operations)
+}
+
+func Routes(echo *echo.Echo, ins *config.Instance, storage Storage) {
+	searchService := NewService(storage, ins.Log)
+	searchHandler := NewHandler(searchService, ins.Log)
+
+	apiversion.Served(ins.Config.Get()).Add(echo, 1, http.MethodGet, "/expenses/search", searchHandler.SearchExpenses)
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+)
+
+type Storage interface {
+	Search(ctx context.Context, query Query) ([]SearchResponse, error)
+}
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	maxQueryLen  = 256
+)
+
+type Service struct {
+	log     common.Log
+	storage Storage
+}
+
+func NewService(s Storage, l common.Log) *Service {
+	return &Service{storage: s, log: l}
+}
+
+// SearchExpenses answers 400 for a query without words, a query longer than
+// maxQueryLen characters or a limit over maxLimit. A zero limit is
+// defaultLimit.
+func (s Service) SearchExpenses(ctx context.Context, query Query) ([]SearchResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.SearchExpenses")
+	defer span.End()
+
+	query.Text = strings.TrimSpace(query.Text)
+	if utf8.RuneCountInString(query.Text) > maxQueryLen {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "Search Expenses Error : query too long"}
+	}
+	if ParseQuery(query.Text) == "" {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "Search Expenses Error : empty query"}
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultLimit
+	}
+	if query.Limit < 0 || query.Limit > maxLimit || query.Offset < 0 {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "Search Expenses Error : invalid limit or offset"}
+	}
+
+	resp, err := s.storage.Search(ctx, query)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Search Expenses Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Search Expenses Error", OriginalError: err}
+	}
+	return resp, nil
+}
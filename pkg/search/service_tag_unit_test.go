@@ -0,0 +1,77 @@
+//go:build unit
+
+package search
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type StorageStub struct {
+	searchWasCalled bool
+	query           Query
+	err             error
+}
+
+func (s *StorageStub) Search(ctx context.Context, query Query) ([]SearchResponse, error) {
+	s.searchWasCalled = true
+	s.query = query
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []SearchResponse{}, nil
+}
+
+func TestSearchExpenses(t *testing.T) {
+	t.Run("should pass the trimmed query with the default limit to storage.Search()", func(t *testing.T) {
+		storage := &StorageStub{}
+		service := NewService(storage, logrus.New())
+
+		resp, err := service.SearchExpenses(context.Background(), Query{Text: " taxi "})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, resp)
+		assert.Equal(t, Query{Text: "taxi", Limit: defaultLimit}, storage.query)
+	})
+
+	tests := []struct {
+		name  string
+		query Query
+	}{
+		{name: "empty query", query: Query{Text: "  "}},
+		{name: "query without words", query: Query{Text: `"" *`}},
+		{name: "query too long", query: Query{Text: strings.Repeat("ก", maxQueryLen+1)}},
+		{name: "limit over the maximum", query: Query{Text: "taxi", Limit: maxLimit + 1}},
+		{name: "negative offset", query: Query{Text: "taxi", Offset: -1}},
+	}
+	for _, tt := range tests {
+		t.Run("should return http status code = 400 for "+tt.name, func(t *testing.T) {
+			storage := &StorageStub{}
+			service := NewService(storage, logrus.New())
+
+			_, err := service.SearchExpenses(context.Background(), tt.query)
+
+			cmErr, ok := err.(*common.Error)
+			assert.True(t, ok)
+			assert.Equal(t, http.StatusBadRequest, cmErr.Code)
+			assert.False(t, storage.searchWasCalled)
+		})
+	}
+
+	t.Run("should return http status code = 400 when storage rejects the query", func(t *testing.T) {
+		storage := &StorageStub{err: common.ErrInvalid}
+		service := NewService(storage, logrus.New())
+
+		_, err := service.SearchExpenses(context.Background(), Query{Text: "taxi"})
+
+		cmErr, ok := err.(*common.Error)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusBadRequest, cmErr.Code)
+	})
+}
@@ -0,0 +1,23 @@
+package search
+
+import (
+	"context"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+)
+
+// NewStorage creates the Storage of the configured backend and maps the
+// search to the configured dictionary. Only postgres has a full text search,
+// the other backends return common.ErrUnsupported.
+func NewStorage(ins *config.Instance) (Storage, error) {
+	cf := ins.Config.Get()
+	if cf.StorageBackend() != config.BackendPostgres {
+		return nil, common.ErrUnsupported
+	}
+	mgmt := New(ins.DB, cf.DbTimeout())
+	if err := mgmt.Configure(context.Background(), cf.SearchDictionary()); err != nil {
+		return nil, err
+	}
+	return mgmt, nil
+}
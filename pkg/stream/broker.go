@@ -0,0 +1,208 @@
+// Package stream pushes the events of the outbox to the clients of the live
+// change feeds as server-sent events.
+package stream
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/outbox"
+)
+
+type Options struct {
+	// Interval is the wait between two polls of the outbox.
+	Interval time.Duration
+	// BatchSize is the number of events read at once.
+	BatchSize int
+	// Buffer is the number of recent events kept in memory.
+	Buffer int
+	// Settle bounds the transaction of a write, a missing id older than
+	// Settle belongs to a transaction rolled back.
+	Settle time.Duration
+}
+
+// Broker polls the outbox once for all the streams of the instance. It moves
+// its head over the events in id order, but a postgres transaction may commit
+// an event after an event with a higher id: the Broker waits at a missing id
+// until the transaction holding it must have ended, so a stream resuming after
+// an id never misses an event.
+type Broker struct {
+	storage outbox.Storage
+	options Options
+	log     common.Log
+	now     func() time.Time
+
+	mu   sync.RWMutex
+	head int64
+	// recent holds the events after from up to head.
+	recent  []outbox.Event
+	from    int64
+	changed chan struct{}
+
+	// the missing ids below gapBelow are skipped after gapUntil
+	gapBelow int64
+	gapUntil time.Time
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func NewBroker(storage outbox.Storage, options Options, log common.Log) *Broker {
+	return &Broker{
+		storage: storage,
+		options: options,
+		log:     log,
+		now:     time.Now,
+		changed: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Start puts the head after the events which cannot be followed by a lower id
+// anymore: an id taken before an event added twice Settle ago belongs to a
+// transaction started before, which has ended.
+func (b *Broker) Start(ctx context.Context) error {
+	head, err := b.storage.LastBefore(ctx, b.now().Add(-2*b.options.Settle))
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.head, b.from = head, head
+	return nil
+}
+
+// Run polls the outbox every interval until ctx is done.
+func (b *Broker) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.options.Interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := b.Poll(ctx)
+			if err != nil && ctx.Err() == nil {
+				b.log.Warnf("Stream poll fail : %s", err)
+			}
+			if err != nil || n < b.options.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll moves the head over the events following it and returns their number,
+// it is not safe for concurrent use.
+func (b *Broker) Poll(ctx context.Context) (int, error) {
+	b.mu.RLock()
+	head := b.head
+	b.mu.RUnlock()
+
+	events, err := b.storage.Since(ctx, head, b.options.BatchSize)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	now := b.now()
+	next := head + 1
+	accepted := events[:0]
+	for _, e := range events {
+		if e.Id != next && !b.skipGap(now, e.Id, events[len(events)-1].Id) {
+			break
+		}
+		accepted = append(accepted, e)
+		next = e.Id + 1
+	}
+	if len(accepted) == 0 {
+		return 0, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.head = accepted[len(accepted)-1].Id
+	b.recent = append(b.recent, accepted...)
+	if over := len(b.recent) - b.options.Buffer; over > 0 {
+		b.from = b.recent[over-1].Id
+		b.recent = append([]outbox.Event{}, b.recent[over:]...)
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+	return len(accepted), nil
+}
+
+// skipGap reports whether the ids missing before id may be skipped. They
+// were taken before last was seen, their transaction has ended Settle after.
+func (b *Broker) skipGap(now time.Time, id int64, last int64) bool {
+	if b.gapUntil.IsZero() || id > b.gapBelow {
+		b.gapBelow, b.gapUntil = last, now.Add(b.options.Settle)
+		return false
+	}
+	return !now.Before(b.gapUntil)
+}
+
+// Head is the id of the last event the Broker moved over.
+func (b *Broker) Head() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.head
+}
+
+// Next returns up to limit events after the id after, the id the events
+// reach and a channel closed once the head moves. The id reached is the head
+// when no event is left before it. An id after the head, which no event has,
+// is taken as the head so that the stream does not skip the events up to it.
+// The recent events are served from memory, the older ones from the outbox.
+func (b *Broker) Next(ctx context.Context, after int64, limit int) ([]outbox.Event, int64, <-chan struct{}, error) {
+	b.mu.RLock()
+	head, changed := b.head, b.changed
+	if after >= head {
+		b.mu.RUnlock()
+		return nil, head, changed, nil
+	}
+	// exhausted tells that events holds every event up to the head
+	var events []outbox.Event
+	var exhausted bool
+	if after >= b.from {
+		i := sort.Search(len(b.recent), func(i int) bool { return b.recent[i].Id > after })
+		events, exhausted = append(events, b.recent[i:]...), true
+		b.mu.RUnlock()
+	} else {
+		b.mu.RUnlock()
+		var err error
+		if events, err = b.storage.Since(ctx, after, limit); err != nil {
+			return nil, after, nil, err
+		}
+		exhausted = len(events) < limit
+	}
+
+	for i, e := range events {
+		if e.Id > head {
+			events, exhausted = events[:i], true
+			break
+		}
+	}
+	if len(events) > limit {
+		events, exhausted = events[:limit], false
+	}
+	if exhausted {
+		return events, head, changed, nil
+	}
+	return events, events[len(events)-1].Id, changed, nil
+}
+
+// Close ends the streams, for the server to shut down without waiting for
+// them.
+func (b *Broker) Close() {
+	b.closeOnce.Do(func() { close(b.closed) })
+}
+
+// Closed is closed by Close.
+func (b *Broker) Closed() <-chan struct{} {
+	return b.closed
+}
@@ -0,0 +1,242 @@
+//go:build unit
+
+package stream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/outbox"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// StorageStub is an outbox whose committed events are added by the tests.
+type StorageStub struct {
+	mu         sync.Mutex
+	events     []outbox.Event
+	sinceCalls int
+	before     time.Time
+}
+
+func (s *StorageStub) add(ids ...int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.events = append(s.events, outbox.Event{Id: id, Aggregate: "expense", AggregateId: id, Type: "ExpenseCreated", Payload: []byte(`{}`)})
+	}
+}
+
+func (s *StorageStub) Relay(ctx context.Context, limit int, maxAttempts int, publish func(context.Context, outbox.Event) error) (int, error) {
+	return 0, nil
+}
+
+func (s *StorageStub) Since(ctx context.Context, after int64, limit int) ([]outbox.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sinceCalls++
+	result := []outbox.Event{}
+	for _, e := range s.events {
+		if e.Id > after && len(result) < limit {
+			result = append(result, e)
+		}
+	}
+	// the ids are taken in order but committed in any order
+	for i := 1; i < len(result); i++ {
+		for j := i; j > 0 && result[j].Id < result[j-1].Id; j-- {
+			result[j], result[j-1] = result[j-1], result[j]
+		}
+	}
+	return result, nil
+}
+
+func (s *StorageStub) LastBefore(ctx context.Context, at time.Time) (int64, error) {
+	s.before = at
+	return 2, nil
+}
+
+func newBroker(storage *StorageStub, now *time.Time) *Broker {
+	b := NewBroker(storage, Options{Interval: time.Millisecond, BatchSize: 10, Buffer: 3, Settle: 5 * time.Second}, logrus.New())
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func ids(events []outbox.Event) []int64 {
+	result := []int64{}
+	for _, e := range events {
+		result = append(result, e.Id)
+	}
+	return result
+}
+
+func TestBrokerStart(t *testing.T) {
+	t.Run("should start after the events older than twice the settle time", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		broker := newBroker(storage, &now)
+
+		assert.NoError(t, broker.Start(context.Background()))
+
+		assert.Equal(t, now.Add(-10*time.Second), storage.before)
+		assert.Equal(t, int64(2), broker.Head())
+	})
+}
+
+func TestBrokerPoll(t *testing.T) {
+	t.Run("should move the head over the following events", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		storage.add(1, 2, 3)
+		broker := newBroker(storage, &now)
+
+		n, err := broker.Poll(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+		assert.Equal(t, int64(3), broker.Head())
+	})
+
+	t.Run("should wait at a missing id until the settle time passed", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		storage.add(1, 3)
+		broker := newBroker(storage, &now)
+
+		n, _ := broker.Poll(context.Background())
+		assert.Equal(t, 1, n)
+		assert.Equal(t, int64(1), broker.Head())
+
+		// the transaction holding 2 commits
+		now = now.Add(time.Second)
+		storage.add(2)
+		n, _ = broker.Poll(context.Background())
+		assert.Equal(t, 2, n)
+		assert.Equal(t, int64(3), broker.Head())
+	})
+
+	t.Run("should skip a missing id once the settle time passed", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		storage.add(1, 3, 5)
+		broker := newBroker(storage, &now)
+
+		broker.Poll(context.Background())
+		now = now.Add(4 * time.Second)
+		n, _ := broker.Poll(context.Background())
+		assert.Equal(t, 0, n)
+
+		now = now.Add(time.Second)
+		n, _ = broker.Poll(context.Background())
+		assert.Equal(t, 2, n)
+		assert.Equal(t, int64(5), broker.Head())
+	})
+
+	t.Run("should wait again at a missing id taken after the last wait", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		storage.add(1, 3)
+		broker := newBroker(storage, &now)
+
+		broker.Poll(context.Background())
+		now = now.Add(5 * time.Second)
+		storage.add(5)
+		n, _ := broker.Poll(context.Background())
+
+		assert.Equal(t, 1, n)
+		assert.Equal(t, int64(3), broker.Head())
+	})
+}
+
+func TestBrokerNext(t *testing.T) {
+	t.Run("should return nothing at the head", func(t *testing.T) {
+		now := time.Now()
+		broker := newBroker(&StorageStub{}, &now)
+
+		events, cursor, changed, err := broker.Next(context.Background(), 0, 10)
+
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+		assert.Equal(t, int64(0), cursor)
+		assert.NotNil(t, changed)
+	})
+
+	t.Run("should clamp an id after the head to the head", func(t *testing.T) {
+		now := time.Now()
+		broker := newBroker(&StorageStub{}, &now)
+
+		events, cursor, _, err := broker.Next(context.Background(), 1<<40, 10)
+
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+		assert.Equal(t, int64(0), cursor)
+	})
+
+	t.Run("should serve the recent events from memory", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		storage.add(1, 2, 3, 4, 5)
+		broker := newBroker(storage, &now)
+		broker.Poll(context.Background())
+		calls := storage.sinceCalls
+
+		events, cursor, _, err := broker.Next(context.Background(), 2, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3, 4, 5}, ids(events))
+		assert.Equal(t, int64(5), cursor)
+		assert.Equal(t, calls, storage.sinceCalls)
+	})
+
+	t.Run("should read the older events from the outbox up to the head", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		storage.add(1, 2, 3, 4, 5)
+		broker := newBroker(storage, &now)
+		broker.Poll(context.Background())
+		storage.add(6)
+
+		first, cursor, _, err := broker.Next(context.Background(), 0, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, ids(first))
+		assert.Equal(t, int64(2), cursor)
+
+		rest, cursor, _, err := broker.Next(context.Background(), cursor, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3, 4, 5}, ids(rest))
+		assert.Equal(t, int64(5), cursor)
+	})
+
+	t.Run("should reach the head over the skipped ids", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		storage.add(1, 4)
+		broker := newBroker(storage, &now)
+		broker.Poll(context.Background())
+		now = now.Add(5 * time.Second)
+		broker.Poll(context.Background())
+
+		events, cursor, _, err := broker.Next(context.Background(), 1, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{4}, ids(events))
+		assert.Equal(t, int64(4), cursor)
+	})
+
+	t.Run("should close the changed channel when the head moves", func(t *testing.T) {
+		now := time.Now()
+		storage := &StorageStub{}
+		broker := newBroker(storage, &now)
+		_, _, changed, _ := broker.Next(context.Background(), 0, 10)
+
+		storage.add(1)
+		broker.Poll(context.Background())
+
+		select {
+		case <-changed:
+		default:
+			t.Fatal("changed is not closed")
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/outbox"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// LastEventIdHeader is sent by the EventSource of the browsers when they
+	// reconnect, with the id of the last event received.
+	LastEventIdHeader = "Last-Event-ID"
+	// batchSize is the number of events written before a flush.
+	batchSize = 100
+	// retry is the wait of the clients before they reconnect.
+	retry = 3 * time.Second
+)
+
+type Handler struct {
+	log       common.Log
+	broker    *Broker
+	heartbeat time.Duration
+}
+
+func NewHandler(b *Broker, heartbeat time.Duration, l common.Log) *Handler {
+	return &Handler{broker: b, heartbeat: heartbeat, log: l}
+}
+
+// StreamExpenses pushes the expense events as server-sent events, named after
+// their type with the outbox event as data and its id as event id. The stream
+// starts after the Last-Event-ID header, or the last_event_id query parameter
+// of the clients which cannot set headers, and with the next event without
+// them. The expenses have no owner, every caller is told about all of them.
+func (h Handler) StreamExpenses(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.StreamExpenses")
+	defer span.End()
+
+	cursor := h.broker.Head()
+	lastId := c.Request().Header.Get(LastEventIdHeader)
+	if lastId == "" {
+		lastId = c.QueryParam("last_event_id")
+	}
+	if lastId != "" {
+		var err error
+		if cursor, err = strconv.ParseInt(lastId, 10, 64); err != nil || cursor < 0 {
+			return c.NoContent(http.StatusBadRequest)
+		}
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// keeps nginx from buffering the events
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-h.broker.Closed():
+			return nil
+		default:
+		}
+
+		events, next, changed, err := h.broker.Next(ctx, cursor, batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				tracing.RecordError(ctx, err)
+				h.log.Errorf("Handler StreamExpenses Error : %s", err)
+			}
+			// the client reconnects with the id of its last event
+			return nil
+		}
+		cursor = next
+		if len(events) > 0 {
+			if err := writeEvents(res, events); err != nil {
+				return nil
+			}
+			res.Flush()
+			heartbeat.Reset(h.heartbeat)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-h.broker.Closed():
+			return nil
+		case <-changed:
+		case <-heartbeat.C:
+			if _, err := io.WriteString(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// writeEvents writes the expense events of events, the others are skipped.
+func writeEvents(w io.Writer, events []outbox.Event) error {
+	for _, e := range events {
+		if e.Aggregate != expenses.Aggregate {
+			continue
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
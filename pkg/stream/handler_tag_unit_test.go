@@ -0,0 +1,147 @@
+//go:build unit
+
+package stream
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// newServer serves the stream of a running broker over storage.
+func newServer(t *testing.T, storage *StorageStub, heartbeat time.Duration) (*httptest.Server, *Broker) {
+	broker := NewBroker(storage, Options{Interval: time.Millisecond, BatchSize: 10, Buffer: 10, Settle: time.Second}, logrus.New())
+	ctx, cancel := context.WithCancel(context.Background())
+	go broker.Run(ctx)
+
+	e := echo.New()
+	e.GET("/expenses/stream", NewHandler(broker, heartbeat, logrus.New()).StreamExpenses)
+	server := httptest.NewServer(e)
+	t.Cleanup(func() {
+		broker.Close()
+		server.Close()
+		cancel()
+	})
+	return server, broker
+}
+
+func get(t *testing.T, url string, lastEventId string) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	if lastEventId != "" {
+		req.Header.Set(LastEventIdHeader, lastEventId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// readIds reads the stream up to its n next event ids.
+func readIds(t *testing.T, r *bufio.Reader, n int) []string {
+	ids := []string{}
+	for len(ids) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		}
+	}
+	return ids
+}
+
+func TestStreamExpensesHandler(t *testing.T) {
+	t.Run("should push the events after the Last-Event-ID", func(t *testing.T) {
+		storage := &StorageStub{}
+		storage.add(1, 2, 3)
+		server, _ := newServer(t, storage, time.Minute)
+
+		resp := get(t, server.URL+"/expenses/stream", "1")
+		r := bufio.NewReader(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+		assert.Equal(t, []string{"2", "3"}, readIds(t, r, 2))
+
+		storage.add(4)
+		assert.Equal(t, []string{"4"}, readIds(t, r, 1))
+	})
+
+	t.Run("should name the event after its type with the event as data", func(t *testing.T) {
+		storage := &StorageStub{}
+		storage.add(1)
+		server, _ := newServer(t, storage, time.Minute)
+
+		resp := get(t, server.URL+"/expenses/stream?last_event_id=0", "")
+		r := bufio.NewReader(resp.Body)
+
+		lines := []string{}
+		for len(lines) < 6 {
+			line, err := r.ReadString('\n')
+			assert.NoError(t, err)
+			lines = append(lines, line)
+		}
+		assert.Equal(t, "retry: 3000\n", lines[0])
+		assert.Equal(t, "id: 1\n", lines[2])
+		assert.Equal(t, "event: ExpenseCreated\n", lines[3])
+		assert.True(t, strings.HasPrefix(lines[4], `data: {"id":1,"aggregate":"expense","aggregate_id":1,"type":"ExpenseCreated","payload":{}`), lines[4])
+		assert.Equal(t, "\n", lines[5])
+	})
+
+	t.Run("should send heartbeats while idle", func(t *testing.T) {
+		server, _ := newServer(t, &StorageStub{}, 10*time.Millisecond)
+
+		resp := get(t, server.URL+"/expenses/stream", "")
+		r := bufio.NewReader(resp.Body)
+
+		for {
+			line, err := r.ReadString('\n')
+			assert.NoError(t, err)
+			if line == ": heartbeat\n" {
+				break
+			}
+		}
+	})
+
+	t.Run("should end the stream when the broker is closed", func(t *testing.T) {
+		server, broker := newServer(t, &StorageStub{}, time.Minute)
+		resp := get(t, server.URL+"/expenses/stream", "")
+		r := bufio.NewReader(resp.Body)
+		_, err := r.ReadString('\n')
+		assert.NoError(t, err)
+
+		broker.Close()
+
+		done := make(chan error)
+		go func() {
+			_, err := r.ReadString(0)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			assert.Error(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("stream not ended")
+		}
+	})
+
+	t.Run("should return http status code = 400 for an invalid Last-Event-ID", func(t *testing.T) {
+		server, _ := newServer(t, &StorageStub{}, time.Minute)
+
+		resp := get(t, server.URL+"/expenses/stream", "abc")
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
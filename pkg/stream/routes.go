@@ -0,0 +1,34 @@
+package stream
+
+import (
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/apiversion"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/labstack/echo/v4"
+)
+
+// operations are the routes of Routes, the same in every version of the api.
+var operations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/expenses/stream", Id: "StreamExpenses", Summary: "Server-sent events of the expense changes", Tag: "expenses",
+		Params: []openapi.Param{
+			{Name: LastEventIdHeader, In: "header", Description: "id of the last event received", Schema: int64(0)},
+			{Name: "last_event_id", In: "query", Description: "id of the last event received, for the clients which cannot set headers", Schema: int64(0)},
+		}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the events, until the client disconnects", ContentType: "text/event-stream"},
+			openapi.BadRequest,
+		}},
+}
+
+// Operations describe the routes of Routes in the OpenAPI document, in every
+// version of versions.
+func Operations(versions apiversion.Versions) []openapi.Operation {
+	return versions.Same(1, operations)
+}
+
+func Routes(echo *echo.Echo, ins *config.Instance, broker *Broker) {
+	streamHandler := NewHandler(broker, ins.Config.Get().StreamHeartbeat(), ins.Log)
+
+	apiversion.Served(ins.Config.Get()).Add(echo, 1, http.MethodGet, "/expenses/stream", streamHandler.StreamExpenses)
+}
@@ -0,0 +1,221 @@
+package tags
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/lib/pq"
+)
+
+// Every change to a tag also rewrites the expenses.tags copy of the expenses
+// linked to it, in the same transaction.
+const (
+	listQuery          = "select t.id, t.name, count(et.expense_id) from tags t left join expense_tags et on et.tag_id = t.id group by t.id, t.name, t.key order by t.key"
+	searchByIdQuery    = "select t.id, t.name, count(et.expense_id) from tags t left join expense_tags et on et.tag_id = t.id where t.id = $1 group by t.id, t.name"
+	lockQuery          = "select name from tags where id = $1 for update"
+	renameQuery        = "UPDATE tags SET name = $1, key = $2 WHERE id = $3"
+	renameExpenseQuery = "UPDATE expenses SET tags = array_replace(tags, $1, $2) WHERE id IN (select expense_id from expense_tags where tag_id = $3)"
+	mergeExpenseQuery  = "UPDATE expenses SET tags = CASE WHEN tags @> ARRAY[$2::text] THEN array_remove(tags, $1) ELSE array_replace(tags, $1, $2) END WHERE id IN (select expense_id from expense_tags where tag_id = $3)"
+	mergeLinkQuery     = "INSERT INTO expense_tags (expense_id, tag_id) select expense_id, $1 from expense_tags where tag_id = $2 ON CONFLICT DO NOTHING"
+	removeExpenseQuery = "UPDATE expenses SET tags = array_remove(tags, $1) WHERE id IN (select expense_id from expense_tags where tag_id = $2)"
+	deleteQuery        = "DELETE FROM tags WHERE id = $1"
+)
+
+// uniqueViolation is the postgres error code of a duplicate key.
+const uniqueViolation = "23505"
+
+type DataMgmt struct {
+	dataMgmt *sql.DB
+	timeout  time.Duration
+}
+
+func New(d *sql.DB, timeout time.Duration) *DataMgmt {
+	return &DataMgmt{dataMgmt: d, timeout: timeout}
+}
+
+func (mgmt DataMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+// queryError prefers the context error, maps a missing row to
+// common.ErrNotFound and a duplicate key to common.ErrConflict.
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		err = common.ErrConflict
+	}
+	tracing.RecordError(ctx, err)
+	return err
+}
+
+// inTx runs fn in a transaction committed only when fn succeeds.
+func (mgmt DataMgmt) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := mgmt.dataMgmt.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func searchById(ctx context.Context, tx *sql.Tx, id int64) (*TagResponse, error) {
+	result := &TagResponse{}
+	err := tx.QueryRowContext(ctx, searchByIdQuery, id).Scan(&result.Id, &result.Name, &result.Count)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func lock(ctx context.Context, tx *sql.Tx, id int64) (string, error) {
+	var name string
+	err := tx.QueryRowContext(ctx, lockQuery, id).Scan(&name)
+	return name, err
+}
+
+func (mgmt DataMgmt) List(ctx context.Context) ([]TagResponse, error) {
+	defer metrics.ObserveQuery("ListTags", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "TagMgmt.List", listQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, listQuery)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []TagResponse{}
+	for rows.Next() {
+		tag := TagResponse{}
+		if err := rows.Scan(&tag.Id, &tag.Name, &tag.Count); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		result = append(result, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Rename changes the display name of the tag, the new name must not be the
+// key of another tag.
+func (mgmt DataMgmt) Rename(ctx context.Context, id int64, name string) (*TagResponse, error) {
+	defer metrics.ObserveQuery("RenameTag", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "TagMgmt.Rename", renameQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result *TagResponse
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		old, err := lock(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, renameQuery, name, Key(name), id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, renameExpenseQuery, old, name, id); err != nil {
+			return err
+		}
+		result, err = searchById(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Merge moves every expense of the tag id to the tag into and deletes id.
+func (mgmt DataMgmt) Merge(ctx context.Context, id int64, into int64) (*TagResponse, error) {
+	defer metrics.ObserveQuery("MergeTags", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "TagMgmt.Merge", mergeExpenseQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	var result *TagResponse
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		// lock in id order so concurrent merges of the same pair cannot deadlock
+		first, second := id, into
+		if first > second {
+			first, second = second, first
+		}
+		names := map[int64]string{}
+		for _, lockId := range []int64{first, second} {
+			name, err := lock(ctx, tx, lockId)
+			if err != nil {
+				return err
+			}
+			names[lockId] = name
+		}
+
+		if _, err := tx.ExecContext(ctx, mergeExpenseQuery, names[id], names[into], id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, mergeLinkQuery, into, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, deleteQuery, id); err != nil {
+			return err
+		}
+		var err error
+		result, err = searchById(ctx, tx, into)
+		return err
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Delete removes the tag from every expense and deletes it.
+func (mgmt DataMgmt) Delete(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("DeleteTag", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "TagMgmt.Delete", deleteQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	err := mgmt.inTx(ctx, func(tx *sql.Tx) error {
+		name, err := lock(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, removeExpenseQuery, name, id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, deleteQuery, id)
+		return err
+	})
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+//go:build unit
+
+package tags
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	t.Run("should list tags with their usage count", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		rows := sqlmock.NewRows([]string{"id", "name", "count"}).AddRow(1, "food", 2).AddRow(2, "travel", 0)
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).WillReturnRows(rows)
+
+		result, err := New(db, time.Second).List(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []TagResponse{{Id: 1, Name: "food", Count: 2}, {Id: 2, Name: "travel", Count: 0}}, result)
+	})
+}
+
+func TestRename(t *testing.T) {
+	t.Run("should rename the tag and its expenses in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("food"))
+		mock.ExpectExec(regexp.QuoteMeta(renameQuery)).WithArgs("Groceries", "groceries", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(renameExpenseQuery)).WithArgs("food", "Groceries", 1).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(searchByIdQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "count"}).AddRow(1, "Groceries", 2))
+		mock.ExpectCommit()
+
+		result, err := New(db, time.Second).Rename(context.Background(), 1, "Groceries")
+
+		assert.NoError(t, err)
+		assert.Equal(t, &TagResponse{Id: 1, Name: "Groceries", Count: 2}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return common.ErrConflict and roll back when the name is taken", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("food"))
+		mock.ExpectExec(regexp.QuoteMeta(renameQuery)).WithArgs("travel", "travel", 1).WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		result, err := New(db, time.Second).Rename(context.Background(), 1, "travel")
+
+		assert.ErrorIs(t, err, common.ErrConflict)
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return common.ErrNotFound for an unknown tag", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(404).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectRollback()
+
+		result, err := New(db, time.Second).Rename(context.Background(), 404, "food")
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+		assert.Nil(t, result)
+	})
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("should move the expenses to the target and delete the merged tag", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("food"))
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("meal"))
+		mock.ExpectExec(regexp.QuoteMeta(mergeExpenseQuery)).WithArgs("meal", "food", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(mergeLinkQuery)).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(searchByIdQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "count"}).AddRow(1, "food", 3))
+		mock.ExpectCommit()
+
+		result, err := New(db, time.Second).Merge(context.Background(), 2, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &TagResponse{Id: 1, Name: "food", Count: 3}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("should remove the tag from its expenses and delete it", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("food"))
+		mock.ExpectExec(regexp.QuoteMeta(removeExpenseQuery)).WithArgs("food", 1).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = New(db, time.Second).Delete(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,115 @@
+package tags
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/labstack/echo/v4"
+)
+
+type Services interface {
+	ListTags(ctx context.Context) ([]TagResponse, error)
+	RenameTag(ctx context.Context, id int64, req RenameRequest) (*TagResponse, error)
+	MergeTags(ctx context.Context, id int64, req MergeRequest) (*TagResponse, error)
+	DeleteTag(ctx context.Context, id int64) error
+}
+
+type Handler struct {
+	log     common.Log
+	service Services
+}
+
+func NewHandler(s Services, l common.Log) *Handler {
+	return &Handler{service: s, log: l}
+}
+
+func (h Handler) ListTags(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.ListTags")
+	defer span.End()
+
+	resp, err := h.service.ListTags(ctx)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler ListTags Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) RenameTag(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.RenameTag")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	req := RenameRequest{}
+	if err := c.Bind(&req); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.RenameTag(ctx, id, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler RenameTag Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) MergeTags(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.MergeTags")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	req := MergeRequest{}
+	if err := c.Bind(&req); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.MergeTags(ctx, id, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler MergeTags Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) DeleteTag(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.DeleteTag")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if err := h.service.DeleteTag(ctx, id); err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler DeleteTag Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
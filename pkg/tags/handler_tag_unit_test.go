@@ -0,0 +1,135 @@
+//go:build unit
+
+package tags
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ServiceStub struct {
+	merge MergeRequest
+	code  int
+}
+
+func (s *ServiceStub) error() error {
+	if s.code != 0 {
+		return &common.Error{Code: s.code}
+	}
+	return nil
+}
+
+func (s *ServiceStub) ListTags(ctx context.Context) ([]TagResponse, error) {
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return []TagResponse{{Id: 1, Name: "food", Count: 2}}, nil
+}
+
+func (s *ServiceStub) RenameTag(ctx context.Context, id int64, req RenameRequest) (*TagResponse, error) {
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &TagResponse{Id: id, Name: req.Name}, nil
+}
+
+func (s *ServiceStub) MergeTags(ctx context.Context, id int64, req MergeRequest) (*TagResponse, error) {
+	s.merge = req
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &TagResponse{Id: req.Into, Name: "food"}, nil
+}
+
+func (s *ServiceStub) DeleteTag(ctx context.Context, id int64) error {
+	return s.error()
+}
+
+func newContext(method string, target string, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestListTagsHandler(t *testing.T) {
+	t.Run("should return http status code = 200 and the tags", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "/tags", "")
+		handler := NewHandler(&ServiceStub{}, logrus.New())
+
+		err := handler.ListTags(c)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			resp := []TagResponse{}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.Equal(t, []TagResponse{{Id: 1, Name: "food", Count: 2}}, resp)
+		}
+	})
+}
+
+func TestRenameTagHandler(t *testing.T) {
+	t.Run("should return http status code = 409 when the service reports a conflict", func(t *testing.T) {
+		c, rec := newContext(http.MethodPut, "/tags/1", `{"name":"travel"}`)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		handler := NewHandler(&ServiceStub{code: http.StatusConflict}, logrus.New())
+
+		err := handler.RenameTag(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("should return http status code = 400 when :id is not a number", func(t *testing.T) {
+		c, rec := newContext(http.MethodPut, "/tags/food", `{"name":"travel"}`)
+		c.SetParamNames("id")
+		c.SetParamValues("food")
+		handler := NewHandler(&ServiceStub{}, logrus.New())
+
+		err := handler.RenameTag(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestMergeTagsHandler(t *testing.T) {
+	t.Run("should pass the target tag to the service", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, "/tags/2/merge", `{"into":1}`)
+		c.SetParamNames("id")
+		c.SetParamValues("2")
+		service := &ServiceStub{}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.MergeTags(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, MergeRequest{Into: 1}, service.merge)
+	})
+}
+
+func TestDeleteTagHandler(t *testing.T) {
+	t.Run("should return http status code = 204 when the tag is deleted", func(t *testing.T) {
+		c, rec := newContext(http.MethodDelete, "/tags/1", "")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		handler := NewHandler(&ServiceStub{}, logrus.New())
+
+		err := handler.DeleteTag(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+}
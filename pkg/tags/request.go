@@ -0,0 +1,10 @@
+package tags
+
+type RenameRequest struct {
+	Name string `json:"name"`
+}
+
+// MergeRequest names the tag that absorbs the merged one.
+type MergeRequest struct {
+	Into int64 `json:"into"`
+}
@@ -0,0 +1,7 @@
+package tags
+
+type TagResponse struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,42 @@
+package tags
+
+import (
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/labstack/echo/v4"
+)
+
+// Operations describe the routes of Routes in the OpenAPI document.
+var Operations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/tags", Id: "ListTags", Summary: "List the tags with the number of their expenses", Tag: "tags",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the tags", Body: []TagResponse{}},
+		}},
+	{Method: http.MethodPut, Path: "/tags/:id", Id: "RenameTag", Summary: "Rename a tag on every expense", Tag: "tags",
+		Body: RenameRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the renamed tag", Body: TagResponse{}},
+			openapi.BadRequest, openapi.NotFound, openapi.Conflict,
+		}},
+	{Method: http.MethodPost, Path: "/tags/:id/merge", Id: "MergeTags", Summary: "Merge a tag into another", Tag: "tags",
+		Body: MergeRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the tag the other was merged into", Body: TagResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodDelete, Path: "/tags/:id", Id: "DeleteTag", Summary: "Remove a tag from every expense", Tag: "tags",
+		Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "deleted"},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+}
+
+func Routes(echo *echo.Echo, ins *config.Instance, storage Storage) {
+	tagService := NewService(storage, ins.Log)
+	tagHandler := NewHandler(tagService, ins.Log)
+
+	echo.GET("/tags", tagHandler.ListTags)
+	echo.PUT("/tags/:id", tagHandler.RenameTag)
+	echo.POST("/tags/:id/merge", tagHandler.MergeTags)
+	echo.DELETE("/tags/:id", tagHandler.DeleteTag)
+}
@@ -0,0 +1,87 @@
+package tags
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+)
+
+type Storage interface {
+	List(ctx context.Context) ([]TagResponse, error)
+	Rename(ctx context.Context, id int64, name string) (*TagResponse, error)
+	Merge(ctx context.Context, id int64, into int64) (*TagResponse, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type Service struct {
+	log     common.Log
+	storage Storage
+}
+
+func NewService(s Storage, l common.Log) *Service {
+	return &Service{storage: s, log: l}
+}
+
+func (s Service) ListTags(ctx context.Context) ([]TagResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.ListTags")
+	defer span.End()
+
+	resp, err := s.storage.List(ctx)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("List Tags Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "List Tags Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+// RenameTag answers 409 when the new name is already the name of another tag,
+// those are merged instead.
+func (s Service) RenameTag(ctx context.Context, id int64, req RenameRequest) (*TagResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.RenameTag")
+	defer span.End()
+
+	name := Clean(req.Name)
+	if name == "" {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "Rename Tag Error : empty name"}
+	}
+
+	resp, err := s.storage.Rename(ctx, id, name)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Rename Tag Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Rename Tag Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) MergeTags(ctx context.Context, id int64, req MergeRequest) (*TagResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.MergeTags")
+	defer span.End()
+
+	if req.Into == id {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "Merge Tags Error : a tag cannot be merged into itself"}
+	}
+
+	resp, err := s.storage.Merge(ctx, id, req.Into)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Merge Tags Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Merge Tags Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) DeleteTag(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteTag")
+	defer span.End()
+
+	if err := s.storage.Delete(ctx, id); err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Delete Tag Error : %s", err)
+		return &common.Error{Code: common.StatusFromError(err), Desc: "Delete Tag Error", OriginalError: err}
+	}
+	return nil
+}
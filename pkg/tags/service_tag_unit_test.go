@@ -0,0 +1,105 @@
+//go:build unit
+
+package tags
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type StorageStub struct {
+	renamedTo      string
+	mergeWasCalled bool
+	err            error
+}
+
+func (s *StorageStub) List(ctx context.Context) ([]TagResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []TagResponse{{Id: 1, Name: "food", Count: 2}}, nil
+}
+
+func (s *StorageStub) Rename(ctx context.Context, id int64, name string) (*TagResponse, error) {
+	s.renamedTo = name
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &TagResponse{Id: id, Name: name}, nil
+}
+
+func (s *StorageStub) Merge(ctx context.Context, id int64, into int64) (*TagResponse, error) {
+	s.mergeWasCalled = true
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &TagResponse{Id: into, Name: "food"}, nil
+}
+
+func (s *StorageStub) Delete(ctx context.Context, id int64) error {
+	return s.err
+}
+
+func TestRenameTag(t *testing.T) {
+	t.Run("should pass the cleaned name to storage.Rename()", func(t *testing.T) {
+		storage := &StorageStub{}
+		service := NewService(storage, logrus.New())
+
+		resp, err := service.RenameTag(context.Background(), 1, RenameRequest{Name: "  street   food "})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "street food", storage.renamedTo)
+		assert.Equal(t, "street food", resp.Name)
+	})
+
+	t.Run("should return error code 400 for an empty name", func(t *testing.T) {
+		service := NewService(&StorageStub{}, logrus.New())
+
+		_, err := service.RenameTag(context.Background(), 1, RenameRequest{Name: " "})
+
+		assert.Equal(t, http.StatusBadRequest, err.(*common.Error).Code)
+	})
+
+	t.Run("should return error code 409 when storage.Rename() reports a conflict", func(t *testing.T) {
+		service := NewService(&StorageStub{err: common.ErrConflict}, logrus.New())
+
+		_, err := service.RenameTag(context.Background(), 1, RenameRequest{Name: "travel"})
+
+		assert.Equal(t, http.StatusConflict, err.(*common.Error).Code)
+	})
+}
+
+func TestMergeTags(t *testing.T) {
+	t.Run("should return error code 400 without calling storage.Merge() for the same tag", func(t *testing.T) {
+		storage := &StorageStub{}
+		service := NewService(storage, logrus.New())
+
+		_, err := service.MergeTags(context.Background(), 1, MergeRequest{Into: 1})
+
+		assert.Equal(t, http.StatusBadRequest, err.(*common.Error).Code)
+		assert.False(t, storage.mergeWasCalled)
+	})
+
+	t.Run("should return error code 404 when storage.Merge() does not find a tag", func(t *testing.T) {
+		service := NewService(&StorageStub{err: common.ErrNotFound}, logrus.New())
+
+		_, err := service.MergeTags(context.Background(), 1, MergeRequest{Into: 2})
+
+		assert.Equal(t, http.StatusNotFound, err.(*common.Error).Code)
+	})
+}
+
+func TestDeleteTag(t *testing.T) {
+	t.Run("should return error code 404 when storage.Delete() does not find the tag", func(t *testing.T) {
+		service := NewService(&StorageStub{err: common.ErrNotFound}, logrus.New())
+
+		err := service.DeleteTag(context.Background(), 1)
+
+		assert.Equal(t, http.StatusNotFound, err.(*common.Error).Code)
+	})
+}
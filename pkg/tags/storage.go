@@ -0,0 +1,16 @@
+package tags
+
+import (
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+)
+
+// NewStorage creates the Storage of the configured backend. Only postgres keeps
+// tags in their own table, the other backends return common.ErrUnsupported.
+func NewStorage(ins *config.Instance) (Storage, error) {
+	cf := ins.Config.Get()
+	if cf.StorageBackend() != config.BackendPostgres {
+		return nil, common.ErrUnsupported
+	}
+	return New(ins.DB, cf.DbTimeout()), nil
+}
@@ -0,0 +1,48 @@
+// Package tags manages the tags shared by expenses. Tags are identified by
+// their key so spelling variants such as "Food", "food" and "food " are the
+// same tag.
+package tags
+
+import "strings"
+
+// Clean trims name and collapses its inner whitespace to single spaces.
+func Clean(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Key is the identity of a tag name, the lower-cased cleaned name.
+func Key(name string) string {
+	return strings.ToLower(Clean(name))
+}
+
+// Normalize cleans names, drops the empty ones and keeps the first spelling of
+// names sharing a key. A nil names stays nil.
+func Normalize(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	result := []string{}
+	for _, name := range names {
+		name = Clean(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// Keys returns the key of every name.
+func Keys(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = Key(name)
+	}
+	return keys
+}
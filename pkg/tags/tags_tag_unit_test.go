@@ -0,0 +1,31 @@
+//go:build unit
+
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Run("should clean names and keep the first spelling of a key", func(t *testing.T) {
+		got := Normalize([]string{" Food ", "street   food", "food", "", "  ", "FOOD", "Beverage"})
+
+		assert.Equal(t, []string{"Food", "street food", "Beverage"}, got)
+	})
+
+	t.Run("should keep nil as nil and empty as empty", func(t *testing.T) {
+		assert.Nil(t, Normalize(nil))
+		assert.Equal(t, []string{}, Normalize([]string{}))
+	})
+}
+
+func TestKey(t *testing.T) {
+	t.Run("should give the same key to spelling variants", func(t *testing.T) {
+		assert.Equal(t, "food", Key("Food"))
+		assert.Equal(t, "food", Key("food "))
+		assert.Equal(t, "street food", Key(" Street\tFood "))
+		assert.Equal(t, []string{"food", "travel"}, Keys([]string{"FOOD", "Travel"}))
+	})
+}
@@ -0,0 +1,100 @@
+//go:build unit
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setup() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+}
+
+func TestSanitize(t *testing.T) {
+	t.Run("should replace literals and keep bind parameters", func(t *testing.T) {
+		query := "select id,  title from expenses\n where id = $1 and title = 'it''s' and amount > 10.5"
+
+		got := Sanitize(query)
+
+		assert.Equal(t, "select id, title from expenses where id = $1 and title = ? and amount > ?", got)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("should continue trace from traceparent header and nest child spans", func(t *testing.T) {
+		recorder := setup()
+		e := echo.New()
+		e.Use(Middleware())
+		e.GET("/expenses/:id", func(c echo.Context) error {
+			_, span := Start(c.Request().Context(), "Handler.SearchExpensesById")
+			span.End()
+			return c.NoContent(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		spans := recorder.Ended()
+		if assert.Len(t, spans, 2) {
+			child, server := spans[0], spans[1]
+			assert.Equal(t, "GET /expenses/:id", server.Name())
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+			assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+			assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+		}
+		assert.Contains(t, rec.Header().Get("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+	})
+}
+
+func TestRecordError(t *testing.T) {
+	t.Run("should mark span in context as error", func(t *testing.T) {
+		recorder := setup()
+
+		ctx, span := StartQuery(context.Background(), SystemPostgres, "DataMgmt.Insert", "INSERT INTO expenses (title) values ($1)")
+		RecordError(ctx, context.DeadlineExceeded)
+		span.End()
+
+		spans := recorder.Ended()
+		if assert.Len(t, spans, 1) {
+			assert.Equal(t, codes.Error, spans[0].Status().Code)
+		}
+	})
+}
+
+func TestInit(t *testing.T) {
+	t.Run("should export spans to writer when exporter is stdout", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		shutdown, err := Init(context.Background(), Config{Exporter: ExporterStdout, Writer: buf})
+		assert.NoError(t, err)
+
+		_, span := Start(context.Background(), "Service.AddExpenses")
+		span.End()
+		err = shutdown(context.Background())
+
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "Service.AddExpenses")
+	})
+
+	t.Run("should return error when exporter is unknown", func(t *testing.T) {
+		_, err := Init(context.Background(), Config{Exporter: "jaeger"})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,295 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/lib/pq"
+)
+
+const (
+	insertQuery     = "INSERT INTO webhooks (url, events, secret, active) values ($1, $2, $3, $4) RETURNING id, url, events, active, created_at"
+	searchByIdQuery = "select id, url, events, active, created_at from webhooks where id = $1"
+	listQuery       = "select id, url, events, active, created_at from webhooks order by id"
+	// updateQuery keeps the secret when $3 is empty.
+	updateQuery = "UPDATE webhooks SET url = $1, events = $2, secret = coalesce(nullif($3, ''), secret), active = $4 WHERE id = $5 RETURNING id, url, events, active, created_at"
+	deleteQuery = "DELETE FROM webhooks WHERE id = $1"
+)
+
+// A delivery is added for every active webhook subscribed to the event. The
+// dispatcher claims the due deliveries by moving their next attempt after the
+// lease, so another instance does not send them meanwhile.
+const (
+	deliveryColumns = "id, webhook_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at"
+	deliveriesQuery = "select " + deliveryColumns + " from webhook_deliveries where webhook_id = $1 and ($2 = '' or status = $2) order by id desc limit $3"
+	replayQuery     = "UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL WHERE id = $1 AND webhook_id = $2 RETURNING " + deliveryColumns
+	claimQuery      = `WITH due AS (select d.id from webhook_deliveries d join webhooks w on w.id = d.webhook_id
+where d.status = 'pending' and d.next_attempt_at <= now() and w.active
+order by d.next_attempt_at, d.id limit $1 for update of d skip locked),
+claimed AS (UPDATE webhook_deliveries d SET next_attempt_at = now() + make_interval(secs => $2) FROM due WHERE d.id = due.id RETURNING d.id, d.webhook_id, d.event, d.payload, d.attempts)
+select c.id, c.webhook_id, w.url, w.secret, c.event, c.payload, c.attempts from claimed c join webhooks w on w.id = c.webhook_id order by c.id`
+	recordQuery = "UPDATE webhook_deliveries SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_status_code = $4, last_error = $5, delivered_at = CASE WHEN $2 = 'delivered' THEN now() END WHERE id = $1"
+)
+
+type DataMgmt struct {
+	dataMgmt *sql.DB
+	timeout  time.Duration
+}
+
+func New(d *sql.DB, timeout time.Duration) *DataMgmt {
+	return &DataMgmt{dataMgmt: d, timeout: timeout}
+}
+
+func (mgmt DataMgmt) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mgmt.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mgmt.timeout)
+}
+
+// queryError prefers the context error and maps a missing row to
+// common.ErrNotFound.
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.ErrNotFound
+	}
+	tracing.RecordError(ctx, err)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*WebhookResponse, error) {
+	result := &WebhookResponse{}
+	if err := row.Scan(&result.Id, &result.Url, pq.Array(&result.Events), &result.Active, &result.CreatedAt); err != nil {
+		return nil, err
+	}
+	if result.Events == nil {
+		result.Events = []string{}
+	}
+	return result, nil
+}
+
+func scanDelivery(row scanner) (*DeliveryResponse, error) {
+	result := &DeliveryResponse{}
+	var payload []byte
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var statusCode sql.NullInt64
+	var lastError sql.NullString
+	err := row.Scan(&result.Id, &result.WebhookId, &result.Event, &payload, &result.Status, &result.Attempts, &nextAttemptAt, &statusCode, &lastError, &result.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	result.Payload = payload
+	result.LastStatusCode, result.LastError = int(statusCode.Int64), lastError.String
+	if nextAttemptAt.Valid && result.Status == StatusPending {
+		result.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		result.DeliveredAt = &deliveredAt.Time
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) Insert(ctx context.Context, req WebhookRequest) (*WebhookResponse, error) {
+	defer metrics.ObserveQuery("InsertWebhook", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.Insert", insertQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := scanWebhook(mgmt.dataMgmt.QueryRowContext(ctx, insertQuery, req.Url, pq.Array(req.Events), req.Secret, active(req)))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// active is the state a request gives a webhook, active unless told otherwise.
+func active(req WebhookRequest) bool {
+	return req.Active == nil || *req.Active
+}
+
+func (mgmt DataMgmt) SearchById(ctx context.Context, id int64) (*WebhookResponse, error) {
+	defer metrics.ObserveQuery("SearchWebhookById", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.SearchById", searchByIdQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := scanWebhook(mgmt.dataMgmt.QueryRowContext(ctx, searchByIdQuery, id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) List(ctx context.Context) ([]WebhookResponse, error) {
+	defer metrics.ObserveQuery("ListWebhooks", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.List", listQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, listQuery)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []WebhookResponse{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		result = append(result, *webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+func (mgmt DataMgmt) Update(ctx context.Context, id int64, req WebhookRequest) (*WebhookResponse, error) {
+	defer metrics.ObserveQuery("UpdateWebhook", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.Update", updateQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := scanWebhook(mgmt.dataMgmt.QueryRowContext(ctx, updateQuery, req.Url, pq.Array(req.Events), req.Secret, active(req), id))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Delete removes the webhook and its delivery log.
+func (mgmt DataMgmt) Delete(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("DeleteWebhook", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.Delete", deleteQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	res, err := mgmt.dataMgmt.ExecContext(ctx, deleteQuery, id)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return queryError(ctx, err)
+	} else if n == 0 {
+		return queryError(ctx, common.ErrNotFound)
+	}
+	return nil
+}
+
+// Deliveries returns the delivery log of the webhook, the latest first.
+func (mgmt DataMgmt) Deliveries(ctx context.Context, webhookId int64, filter DeliveryFilter) ([]DeliveryResponse, error) {
+	defer metrics.ObserveQuery("WebhookDeliveries", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.Deliveries", deliveriesQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, deliveriesQuery, webhookId, filter.Status, filter.Limit)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []DeliveryResponse{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		result = append(result, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Replay makes the delivery of the webhook pending again with all its
+// attempts, whatever its state.
+func (mgmt DataMgmt) Replay(ctx context.Context, webhookId int64, deliveryId int64) (*DeliveryResponse, error) {
+	defer metrics.ObserveQuery("ReplayWebhookDelivery", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.Replay", replayQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	result, err := scanDelivery(mgmt.dataMgmt.QueryRowContext(ctx, replayQuery, deliveryId, webhookId))
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Claim returns up to limit due deliveries, which are not due again before
+// lease.
+func (mgmt DataMgmt) Claim(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	defer metrics.ObserveQuery("ClaimWebhookDeliveries", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.Claim", claimQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := mgmt.dataMgmt.QueryContext(ctx, claimQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	result := []Delivery{}
+	for rows.Next() {
+		d := Delivery{}
+		if err := rows.Scan(&d.Id, &d.WebhookId, &d.Url, &d.Secret, &d.Event, &d.Payload, &d.Attempts); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// Record saves the outcome of an attempt of the delivery id.
+func (mgmt DataMgmt) Record(ctx context.Context, id int64, attempt Attempt) error {
+	defer metrics.ObserveQuery("RecordWebhookAttempt", time.Now())
+	ctx, span := tracing.StartQuery(ctx, tracing.SystemPostgres, "WebhookMgmt.Record", recordQuery)
+	defer span.End()
+
+	ctx, cancel := mgmt.withTimeout(ctx)
+	defer cancel()
+
+	nextAttemptAt := sql.NullTime{Time: attempt.NextAttemptAt, Valid: !attempt.NextAttemptAt.IsZero()}
+	statusCode := sql.NullInt64{Int64: int64(attempt.StatusCode), Valid: attempt.StatusCode != 0}
+	lastError := sql.NullString{String: attempt.Error, Valid: attempt.Error != ""}
+	if _, err := mgmt.dataMgmt.ExecContext(ctx, recordQuery, id, attempt.Status, nextAttemptAt, statusCode, lastError); err != nil {
+		return queryError(ctx, err)
+	}
+	return nil
+}
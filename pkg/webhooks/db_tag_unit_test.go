@@ -0,0 +1,125 @@
+//go:build unit
+
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsert(t *testing.T) {
+	t.Run("should insert an active webhook", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		createdAt := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(insertQuery)).WithArgs(publicUrl, pq.Array([]string{"expense.created"}), "s3cret", true).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "url", "events", "active", "created_at"}).AddRow(1, publicUrl, "{expense.created}", true, createdAt))
+
+		result, err := New(db, time.Second).Insert(context.Background(), WebhookRequest{Url: publicUrl, Events: []string{"expense.created"}, Secret: "s3cret"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &WebhookResponse{Id: 1, Url: publicUrl, Events: []string{"expense.created"}, Active: true, CreatedAt: createdAt}, result)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("should return common.ErrNotFound for an unknown webhook", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		inactive := false
+		mock.ExpectQuery(regexp.QuoteMeta(updateQuery)).WithArgs(publicUrl, pq.Array([]string{}), "", false, 404).WillReturnError(sql.ErrNoRows)
+
+		_, err = New(db, time.Second).Update(context.Background(), 404, WebhookRequest{Url: publicUrl, Events: []string{}, Active: &inactive})
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("should return common.ErrNotFound for an unknown webhook", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).WithArgs(404).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = New(db, time.Second).Delete(context.Background(), 404)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+	})
+}
+
+func TestDeliveries(t *testing.T) {
+	t.Run("should return the log with the next attempt of the pending deliveries only", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		at := time.Now()
+		columns := []string{"id", "webhook_id", "event", "payload", "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at"}
+		mock.ExpectQuery(regexp.QuoteMeta(deliveriesQuery)).WithArgs(1, "", 50).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(2, 1, "expense.updated", []byte(`{}`), StatusPending, 1, at, 503, "webhook answered 503", at, nil).
+				AddRow(1, 1, "expense.created", []byte(`{}`), StatusDelivered, 1, at, 200, nil, at, at))
+
+		result, err := New(db, time.Second).Deliveries(context.Background(), 1, DeliveryFilter{Limit: 50})
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.Equal(t, &at, result[0].NextAttemptAt)
+		assert.Equal(t, 503, result[0].LastStatusCode)
+		assert.Nil(t, result[1].NextAttemptAt)
+		assert.Equal(t, &at, result[1].DeliveredAt)
+	})
+}
+
+func TestReplay(t *testing.T) {
+	t.Run("should return common.ErrNotFound for a delivery of another webhook", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery(regexp.QuoteMeta(replayQuery)).WithArgs(7, 2).WillReturnError(sql.ErrNoRows)
+
+		_, err = New(db, time.Second).Replay(context.Background(), 2, 7)
+
+		assert.ErrorIs(t, err, common.ErrNotFound)
+	})
+}
+
+func TestClaim(t *testing.T) {
+	t.Run("should claim the due deliveries for the lease", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectQuery(regexp.QuoteMeta(claimQuery)).WithArgs(10, float64(30)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "url", "secret", "event", "payload", "attempts"}).
+				AddRow(7, 1, publicUrl, "s3cret", "expense.created", []byte(`{}`), 2))
+
+		result, err := New(db, time.Second).Claim(context.Background(), 10, 30*time.Second)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Delivery{{Id: 7, WebhookId: 1, Url: publicUrl, Secret: "s3cret", Event: "expense.created", Payload: []byte(`{}`), Attempts: 2}}, result)
+	})
+}
+
+func TestRecord(t *testing.T) {
+	t.Run("should save a delivered attempt without a next attempt", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		mock.ExpectExec(regexp.QuoteMeta(recordQuery)).WithArgs(7, StatusDelivered, nil, 200, nil).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = New(db, time.Second).Record(context.Background(), 7, Attempt{Status: StatusDelivered, StatusCode: 200})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,157 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+)
+
+// maxBackoff bounds the wait between two attempts of a delivery.
+const maxBackoff = 24 * time.Hour
+
+// Delivery is a due delivery with the target of its webhook.
+type Delivery struct {
+	Id        int64
+	WebhookId int64
+	Url       string
+	Secret    string
+	Event     string
+	Payload   []byte
+	Attempts  int
+}
+
+// Attempt is the outcome of sending a Delivery. A pending Attempt is retried
+// at NextAttemptAt.
+type Attempt struct {
+	Status        string
+	NextAttemptAt time.Time
+	StatusCode    int
+	Error         string
+}
+
+type DispatcherOptions struct {
+	// Interval is the wait between two claims of the due deliveries.
+	Interval time.Duration
+	// Timeout bounds a single delivery.
+	Timeout time.Duration
+	// Backoff is the wait before the first retry, it doubles with every
+	// attempt.
+	Backoff time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is dead.
+	MaxAttempts int
+	// BatchSize is the number of deliveries claimed at once.
+	BatchSize int
+}
+
+// Dispatcher sends the due deliveries of the webhooks.
+type Dispatcher struct {
+	storage Storage
+	client  *http.Client
+	options DispatcherOptions
+	log     common.Log
+	now     func() time.Time
+}
+
+// NewDispatcher creates a Dispatcher sending with client, which should come
+// from Guard.Client.
+func NewDispatcher(storage Storage, client *http.Client, options DispatcherOptions, log common.Log) *Dispatcher {
+	return &Dispatcher{storage: storage, client: client, options: options, log: log, now: time.Now}
+}
+
+// Run sends the due deliveries every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			d.log.Warnf("Webhook dispatch fail : %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends the due deliveries claimed at once and returns how many
+// were sent. A delivery is claimed for all its sending and recording time, so
+// an instance stopped meanwhile only delays it.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	lease := time.Duration(d.options.BatchSize+1) * (d.options.Timeout + time.Second)
+	deliveries, err := d.storage.Claim(ctx, d.options.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range deliveries {
+		attempt := d.send(ctx, delivery)
+		if err := d.storage.Record(ctx, delivery.Id, attempt); err != nil {
+			return 0, err
+		}
+		if attempt.Status == StatusDead {
+			d.log.Warnf("Webhook delivery %d to webhook %d is dead : %s", delivery.Id, delivery.WebhookId, attempt.Error)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// send posts the payload of delivery signed with the secret of its webhook.
+// Every answer but 2xx is a failure.
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery) Attempt {
+	ctx, cancel := context.WithTimeout(ctx, d.options.Timeout)
+	defer cancel()
+
+	now := d.now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return d.failed(delivery, now, 0, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "expenses-webhooks/1")
+	req.Header.Set("X-Webhook-Id", strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return d.failed(delivery, now, 0, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return d.failed(delivery, now, resp.StatusCode, fmt.Errorf("webhook answered %s", resp.Status))
+	}
+	return Attempt{Status: StatusDelivered, StatusCode: resp.StatusCode}
+}
+
+// failed schedules the retry of delivery, which is dead once its attempts
+// run out.
+func (d *Dispatcher) failed(delivery Delivery, now time.Time, statusCode int, err error) Attempt {
+	attempt := Attempt{Status: StatusDead, StatusCode: statusCode, Error: err.Error()}
+	attempts := delivery.Attempts + 1
+	if attempts < d.options.MaxAttempts {
+		attempt.Status = StatusPending
+		attempt.NextAttemptAt = now.Add(backoff(d.options.Backoff, attempts))
+	}
+	return attempt
+}
+
+// backoff is the wait after the failed attempt number attempts, doubled for
+// every attempt after the first one and at most maxBackoff.
+func backoff(first time.Duration, attempts int) time.Duration {
+	wait := first
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
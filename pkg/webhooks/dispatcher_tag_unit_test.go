@@ -0,0 +1,116 @@
+//go:build unit
+
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// QueueStub hands out its deliveries once and keeps the recorded attempts.
+type QueueStub struct {
+	StorageStub
+	due      []Delivery
+	lease    time.Duration
+	attempts map[int64]Attempt
+}
+
+func (s *QueueStub) Claim(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	due := s.due
+	s.due, s.lease = nil, lease
+	return due, nil
+}
+
+func (s *QueueStub) Record(ctx context.Context, id int64, attempt Attempt) error {
+	s.attempts[id] = attempt
+	return nil
+}
+
+func newDispatcher(storage Storage, now time.Time) *Dispatcher {
+	d := NewDispatcher(storage, NewGuard(true).Client(time.Second), DispatcherOptions{
+		Interval:    time.Millisecond,
+		Timeout:     time.Second,
+		Backoff:     30 * time.Second,
+		MaxAttempts: 3,
+		BatchSize:   10,
+	}, logrus.New())
+	d.now = func() time.Time { return now }
+	return d
+}
+
+func TestDispatchDue(t *testing.T) {
+	now := time.Now()
+
+	t.Run("should send a signed delivery and record it delivered", func(t *testing.T) {
+		var header http.Header
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		storage := &QueueStub{attempts: map[int64]Attempt{}, due: []Delivery{
+			{Id: 7, WebhookId: 1, Url: server.URL, Secret: "s3cret", Event: "expense.created", Payload: []byte(`{"event":"expense.created"}`)},
+		}}
+
+		n, err := newDispatcher(storage, now).DispatchDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, 11*(2*time.Second), storage.lease)
+		assert.Equal(t, Attempt{Status: StatusDelivered, StatusCode: http.StatusNoContent}, storage.attempts[7])
+		assert.Equal(t, `{"event":"expense.created"}`, string(body))
+		assert.Equal(t, "7", header.Get("X-Webhook-Id"))
+		assert.Equal(t, "expense.created", header.Get("X-Webhook-Event"))
+		assert.True(t, Verify("s3cret", header.Get(SignatureHeader), body, now, time.Minute))
+	})
+
+	t.Run("should retry a failed delivery with an exponential backoff until it is dead", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		storage := &QueueStub{attempts: map[int64]Attempt{}, due: []Delivery{
+			{Id: 1, Url: server.URL, Payload: []byte(`{}`)},
+			{Id: 2, Url: server.URL, Payload: []byte(`{}`), Attempts: 1},
+			{Id: 3, Url: server.URL, Payload: []byte(`{}`), Attempts: 2},
+		}}
+
+		_, err := newDispatcher(storage, now).DispatchDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusPending, storage.attempts[1].Status)
+		assert.Equal(t, now.Add(30*time.Second), storage.attempts[1].NextAttemptAt)
+		assert.Equal(t, http.StatusServiceUnavailable, storage.attempts[1].StatusCode)
+		assert.Equal(t, now.Add(time.Minute), storage.attempts[2].NextAttemptAt)
+		assert.Equal(t, StatusDead, storage.attempts[3].Status)
+		assert.True(t, storage.attempts[3].NextAttemptAt.IsZero())
+		assert.Equal(t, "webhook answered 503 Service Unavailable", storage.attempts[3].Error)
+	})
+
+	t.Run("should record an unreachable target as a failure", func(t *testing.T) {
+		storage := &QueueStub{attempts: map[int64]Attempt{}, due: []Delivery{{Id: 1, Url: "http://127.0.0.1:1/hook", Payload: []byte(`{}`)}}}
+
+		_, err := newDispatcher(storage, now).DispatchDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusPending, storage.attempts[1].Status)
+		assert.NotEmpty(t, storage.attempts[1].Error)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	t.Run("should double the wait and cap it", func(t *testing.T) {
+		assert.Equal(t, time.Second, backoff(time.Second, 1))
+		assert.Equal(t, 8*time.Second, backoff(time.Second, 4))
+		assert.Equal(t, maxBackoff, backoff(time.Second, 100))
+	})
+}
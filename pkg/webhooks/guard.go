@@ -0,0 +1,144 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+)
+
+// ErrForbiddenTarget is returned for a webhook url reaching a loopback,
+// private, link-local or otherwise internal address.
+var ErrForbiddenTarget = fmt.Errorf("%w: webhook target address is not allowed", common.ErrInvalid)
+
+// sharedAddressSpace is the carrier-grade NAT range, internal like the private
+// ranges net.IP.IsPrivate knows.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// The IPv6 ranges reaching an IPv4 address embedded in them: the NAT64
+// well-known prefix (RFC 6052) and the IPv4-compatible addresses in their last
+// 32 bits, 6to4 (RFC 3056) in the 32 bits after its prefix. The local NAT64
+// prefix (RFC 8215) translates to addresses of the network, it is internal.
+var (
+	nat64          = &net.IPNet{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)}
+	nat64Local     = &net.IPNet{IP: net.ParseIP("64:ff9b:1::"), Mask: net.CIDRMask(48, 128)}
+	ipv4Compatible = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(96, 128)}
+	sixToFour      = &net.IPNet{IP: net.ParseIP("2002::"), Mask: net.CIDRMask(16, 128)}
+)
+
+// Guard keeps the webhooks of the integrators from reaching the internal
+// services (SSRF). Check rejects the urls when they are registered and the
+// Client checks the address of every connection again, as a name may resolve
+// differently later.
+type Guard struct {
+	allowPrivate bool
+	resolver     *net.Resolver
+}
+
+// NewGuard creates a Guard, allowPrivate disables the checks for local
+// development.
+func NewGuard(allowPrivate bool) *Guard {
+	return &Guard{allowPrivate: allowPrivate, resolver: net.DefaultResolver}
+}
+
+// Check returns common.ErrInvalid unless raw is an http or https url without
+// credentials whose host resolves to public addresses only.
+func (g *Guard) Check(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: webhook url must be an absolute http or https url", common.ErrInvalid)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: webhook url must not hold credentials", common.ErrInvalid)
+	}
+	if g.allowPrivate {
+		return nil
+	}
+	ips, err := g.resolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: webhook host does not resolve", common.ErrInvalid)
+	}
+	for _, ip := range ips {
+		if forbidden(ip.IP) {
+			return ErrForbiddenTarget
+		}
+	}
+	return nil
+}
+
+// Client returns an http.Client connecting to allowed addresses only. It does
+// not follow redirects nor use a proxy, either would reach another target
+// than the checked one.
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: g.control}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConnsPerHost:   2,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// control runs once the address of a connection is resolved.
+func (g *Guard) control(network string, address string, _ syscall.RawConn) error {
+	if g.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errors.New("webhook dial without an ip address")
+	}
+	if forbidden(ip) {
+		return ErrForbiddenTarget
+	}
+	return nil
+}
+
+func forbidden(ip net.IP) bool {
+	if v4 := embeddedIPv4(ip); v4 != nil {
+		return forbidden(v4)
+	}
+	if nat64Local.Contains(ip) {
+		return true
+	}
+	// the IPv4-mapped addresses are checked as IPv4 by To4 and the net.IP
+	// methods
+	if v4 := ip.To4(); v4 != nil && v4[0] == 0 {
+		// 0.0.0.0/8 reaches the local host on some systems
+		return true
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// embeddedIPv4 returns the IPv4 address reached through the IPv6 address ip,
+// nil when ip is not of a range embedding one.
+func embeddedIPv4(ip net.IP) net.IP {
+	if len(ip) != net.IPv6len || ip.To4() != nil {
+		return nil
+	}
+	switch {
+	case nat64.Contains(ip), ipv4Compatible.Contains(ip):
+		return ip[12:16]
+	case sixToFour.Contains(ip):
+		return ip[2:6]
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+//go:build unit
+
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardCheck(t *testing.T) {
+	t.Run("should reject the internal addresses", func(t *testing.T) {
+		for _, url := range []string{"http://127.0.0.1/", "http://0.0.0.0/", "http://192.168.1.1/", "http://172.16.0.1/", "http://100.64.0.1/", "http://169.254.169.254/", "http://[fe80::1]/", "http://[fd00::1]/"} {
+			assert.ErrorIs(t, NewGuard(false).Check(context.Background(), url), ErrForbiddenTarget, url)
+		}
+	})
+
+	t.Run("should reject the internal IPv4 addresses in IPv6 forms", func(t *testing.T) {
+		for _, url := range []string{
+			"http://[::ffff:127.0.0.1]/", "http://[::ffff:a9fe:a9fe]/", "http://[64:ff9b::7f00:1]/", "http://[64:ff9b::169.254.169.254]/",
+			"http://[64:ff9b:1::808:808]/", "http://[::10.0.0.1]/", "http://[2002:c0a8:101::1]/",
+		} {
+			assert.ErrorIs(t, NewGuard(false).Check(context.Background(), url), ErrForbiddenTarget, url)
+		}
+	})
+
+	t.Run("should accept a public IPv4 address through NAT64", func(t *testing.T) {
+		assert.NoError(t, NewGuard(false).Check(context.Background(), "http://[64:ff9b::808:808]/"))
+	})
+
+	t.Run("should accept a public address", func(t *testing.T) {
+		assert.NoError(t, NewGuard(false).Check(context.Background(), publicUrl))
+	})
+
+	t.Run("should reject a relative url even when private targets are allowed", func(t *testing.T) {
+		assert.ErrorIs(t, NewGuard(true).Check(context.Background(), "/hook"), common.ErrInvalid)
+	})
+}
+
+func TestGuardClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer server.Close()
+
+	t.Run("should refuse to connect to a loopback address", func(t *testing.T) {
+		_, err := NewGuard(false).Client(time.Second).Get(server.URL)
+
+		assert.ErrorIs(t, err, ErrForbiddenTarget)
+	})
+
+	t.Run("should not follow redirects", func(t *testing.T) {
+		resp, err := NewGuard(true).Client(time.Second).Get(server.URL)
+
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+	})
+}
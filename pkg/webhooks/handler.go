@@ -0,0 +1,189 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/labstack/echo/v4"
+)
+
+type Services interface {
+	AddWebhook(ctx context.Context, req WebhookRequest) (*WebhookResponse, error)
+	SearchWebhookById(ctx context.Context, id int64) (*WebhookResponse, error)
+	ListWebhooks(ctx context.Context) ([]WebhookResponse, error)
+	UpdateWebhook(ctx context.Context, id int64, req WebhookRequest) (*WebhookResponse, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, webhookId int64, filter DeliveryFilter) ([]DeliveryResponse, error)
+	ReplayDelivery(ctx context.Context, webhookId int64, deliveryId int64) (*DeliveryResponse, error)
+}
+
+type Handler struct {
+	log     common.Log
+	service Services
+}
+
+func NewHandler(s Services, l common.Log) *Handler {
+	return &Handler{service: s, log: l}
+}
+
+func (h Handler) AddWebhook(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.AddWebhook")
+	defer span.End()
+
+	req := WebhookRequest{}
+	if err := c.Bind(&req); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.AddWebhook(ctx, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler AddWebhook Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (h Handler) SearchWebhookById(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.SearchWebhookById")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.SearchWebhookById(ctx, id)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler SearchWebhookById Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) ListWebhooks(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.ListWebhooks")
+	defer span.End()
+
+	resp, err := h.service.ListWebhooks(ctx)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler ListWebhooks Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) UpdateWebhook(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.UpdateWebhook")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	req := WebhookRequest{}
+	if err := c.Bind(&req); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.UpdateWebhook(ctx, id, req)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler UpdateWebhook Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) DeleteWebhook(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.DeleteWebhook")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	err = h.service.DeleteWebhook(ctx, id)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler DeleteWebhook Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListDeliveries serves the delivery log of a webhook, filtered by the status
+// and limit query parameters.
+func (h Handler) ListDeliveries(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.ListDeliveries")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	filter := DeliveryFilter{Status: c.QueryParam("status")}
+	if limit := c.QueryParam("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return c.NoContent(http.StatusBadRequest)
+		}
+	}
+
+	resp, err := h.service.ListDeliveries(ctx, id, filter)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler ListDeliveries Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h Handler) ReplayDelivery(c echo.Context) error {
+	ctx, span := tracing.Start(c.Request().Context(), "Handler.ReplayDelivery")
+	defer span.End()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	deliveryId, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.service.ReplayDelivery(ctx, id, deliveryId)
+	if err != nil {
+		if cmErr, ok := err.(*common.Error); ok {
+			return c.NoContent(cmErr.Code)
+		}
+		h.log.Errorf("Handler ReplayDelivery Error : %s", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusAccepted, resp)
+}
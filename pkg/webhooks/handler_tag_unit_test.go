@@ -0,0 +1,180 @@
+//go:build unit
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ServiceStub struct {
+	req    WebhookRequest
+	filter DeliveryFilter
+	code   int
+}
+
+func (s *ServiceStub) error() error {
+	if s.code != 0 {
+		return &common.Error{Code: s.code}
+	}
+	return nil
+}
+
+func (s *ServiceStub) AddWebhook(ctx context.Context, req WebhookRequest) (*WebhookResponse, error) {
+	s.req = req
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &WebhookResponse{Id: 1, Url: req.Url, Events: req.Events, Active: true, Secret: "whsec_1"}, nil
+}
+
+func (s *ServiceStub) SearchWebhookById(ctx context.Context, id int64) (*WebhookResponse, error) {
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &WebhookResponse{Id: id, Url: publicUrl, Events: []string{}, Active: true}, nil
+}
+
+func (s *ServiceStub) ListWebhooks(ctx context.Context) ([]WebhookResponse, error) {
+	return []WebhookResponse{}, s.error()
+}
+
+func (s *ServiceStub) UpdateWebhook(ctx context.Context, id int64, req WebhookRequest) (*WebhookResponse, error) {
+	s.req = req
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &WebhookResponse{Id: id, Url: req.Url, Events: req.Events, Active: true}, nil
+}
+
+func (s *ServiceStub) DeleteWebhook(ctx context.Context, id int64) error {
+	return s.error()
+}
+
+func (s *ServiceStub) ListDeliveries(ctx context.Context, webhookId int64, filter DeliveryFilter) ([]DeliveryResponse, error) {
+	s.filter = filter
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return []DeliveryResponse{{Id: 7, WebhookId: webhookId, Status: StatusDead}}, nil
+}
+
+func (s *ServiceStub) ReplayDelivery(ctx context.Context, webhookId int64, deliveryId int64) (*DeliveryResponse, error) {
+	if err := s.error(); err != nil {
+		return nil, err
+	}
+	return &DeliveryResponse{Id: deliveryId, WebhookId: webhookId, Status: StatusPending}, nil
+}
+
+func newContext(method string, target string, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestAddWebhookHandler(t *testing.T) {
+	t.Run("should return http status code = 201 and the webhook with its secret", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, "/webhooks", `{"url":"`+publicUrl+`","events":["expense.created"]}`)
+		service := &ServiceStub{}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.AddWebhook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, WebhookRequest{Url: publicUrl, Events: []string{"expense.created"}}, service.req)
+		resp := WebhookResponse{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "whsec_1", resp.Secret)
+	})
+
+	t.Run("should return http status code = 400 when the service rejects the url", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, "/webhooks", `{"url":"http://127.0.0.1/hook"}`)
+		handler := NewHandler(&ServiceStub{code: http.StatusBadRequest}, logrus.New())
+
+		err := handler.AddWebhook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestDeleteWebhookHandler(t *testing.T) {
+	t.Run("should return http status code = 204", func(t *testing.T) {
+		c, rec := newContext(http.MethodDelete, "/webhooks/1", "")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		handler := NewHandler(&ServiceStub{}, logrus.New())
+
+		err := handler.DeleteWebhook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+}
+
+func TestListDeliveriesHandler(t *testing.T) {
+	t.Run("should pass the status and limit to the service", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "/webhooks/1/deliveries?status=dead&limit=10", "")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		service := &ServiceStub{}
+		handler := NewHandler(service, logrus.New())
+
+		err := handler.ListDeliveries(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, DeliveryFilter{Status: StatusDead, Limit: 10}, service.filter)
+	})
+
+	t.Run("should return http status code = 400 for a limit that is not a number", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "/webhooks/1/deliveries?limit=ten", "")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		handler := NewHandler(&ServiceStub{}, logrus.New())
+
+		err := handler.ListDeliveries(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestReplayDeliveryHandler(t *testing.T) {
+	t.Run("should return http status code = 202 and the pending delivery", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, "/webhooks/1/deliveries/7/replay", "")
+		c.SetParamNames("id", "deliveryId")
+		c.SetParamValues("1", "7")
+		handler := NewHandler(&ServiceStub{}, logrus.New())
+
+		err := handler.ReplayDelivery(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.JSONEq(t, `{"id":7,"webhook_id":1,"event":"","payload":null,"status":"pending","attempts":0,"created_at":"0001-01-01T00:00:00Z"}`, rec.Body.String())
+	})
+
+	t.Run("should return http status code = 404 for an unknown delivery", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, "/webhooks/1/deliveries/7/replay", "")
+		c.SetParamNames("id", "deliveryId")
+		c.SetParamValues("1", "7")
+		handler := NewHandler(&ServiceStub{code: http.StatusNotFound}, logrus.New())
+
+		err := handler.ReplayDelivery(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
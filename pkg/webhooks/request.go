@@ -0,0 +1,19 @@
+package webhooks
+
+// WebhookRequest creates or replaces a webhook. Events are the expense events
+// delivered, all of them when empty. A webhook created without a secret gets a
+// generated one, a replaced webhook keeps its secret when Secret is empty. A
+// nil Active keeps the webhook active.
+type WebhookRequest struct {
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// DeliveryFilter selects the deliveries of the log, every status when Status
+// is empty.
+type DeliveryFilter struct {
+	Status string
+	Limit  int
+}
@@ -0,0 +1,65 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/labstack/echo/v4"
+)
+
+// Operations describe the routes of Routes in the OpenAPI document.
+var Operations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/webhooks", Id: "AddWebhook", Summary: "Create a webhook", Tag: "webhooks",
+		Body: WebhookRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusCreated, Description: "the created webhook with its secret", Body: WebhookResponse{}},
+			openapi.BadRequest,
+		}},
+	{Method: http.MethodGet, Path: "/webhooks", Id: "ListWebhooks", Summary: "List the webhooks", Tag: "webhooks",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the webhooks", Body: []WebhookResponse{}},
+		}},
+	{Method: http.MethodGet, Path: "/webhooks/:id", Id: "SearchWebhookById", Summary: "Read a webhook", Tag: "webhooks",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the webhook", Body: WebhookResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodPut, Path: "/webhooks/:id", Id: "UpdateWebhook", Summary: "Replace a webhook", Tag: "webhooks",
+		Body: WebhookRequest{}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the updated webhook", Body: WebhookResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodDelete, Path: "/webhooks/:id", Id: "DeleteWebhook", Summary: "Delete a webhook", Tag: "webhooks",
+		Responses: []openapi.Response{
+			{Status: http.StatusNoContent, Description: "deleted"},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodGet, Path: "/webhooks/:id/deliveries", Id: "ListDeliveries", Summary: "Delivery log of a webhook", Tag: "webhooks",
+		Params: []openapi.Param{
+			{Name: "status", In: "query", Description: "status of the deliveries, every status when empty", Schema: &openapi.Schema{Type: "string", Enum: []string{StatusPending, StatusDelivered, StatusDead}}},
+			{Name: "limit", In: "query", Description: "number of deliveries", Schema: 0},
+		}, Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "the deliveries, newest first", Body: []DeliveryResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+	{Method: http.MethodPost, Path: "/webhooks/:id/deliveries/:deliveryId/replay", Id: "ReplayDelivery", Summary: "Send a delivery again", Tag: "webhooks",
+		Responses: []openapi.Response{
+			{Status: http.StatusAccepted, Description: "the delivery, queued", Body: DeliveryResponse{}},
+			openapi.BadRequest, openapi.NotFound,
+		}},
+}
+
+// Routes serves the webhooks of storage, their deliveries are queued by the
+// outbox trigger of postgres.
+func Routes(echo *echo.Echo, ins *config.Instance, storage Storage) {
+	webhookService := NewService(storage, NewGuard(ins.Config.Get().WebhookAllowPrivate()), ins.Log)
+	webhookHandler := NewHandler(webhookService, ins.Log)
+
+	echo.POST("/webhooks", webhookHandler.AddWebhook)
+	echo.GET("/webhooks", webhookHandler.ListWebhooks)
+	echo.GET("/webhooks/:id", webhookHandler.SearchWebhookById)
+	echo.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+	echo.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+	echo.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+	echo.POST("/webhooks/:id/deliveries/:deliveryId/replay", webhookHandler.ReplayDelivery)
+}
@@ -0,0 +1,222 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+)
+
+const (
+	defaultDeliveries = 50
+	maxDeliveries     = 500
+)
+
+// events are the expense events a webhook may subscribe to.
+var events = map[string]bool{
+	expenses.EventCreated: true,
+	expenses.EventUpdated: true,
+	expenses.EventDeleted: true,
+}
+
+type Storage interface {
+	Insert(ctx context.Context, req WebhookRequest) (*WebhookResponse, error)
+	SearchById(ctx context.Context, id int64) (*WebhookResponse, error)
+	List(ctx context.Context) ([]WebhookResponse, error)
+	Update(ctx context.Context, id int64, req WebhookRequest) (*WebhookResponse, error)
+	Delete(ctx context.Context, id int64) error
+	Deliveries(ctx context.Context, webhookId int64, filter DeliveryFilter) ([]DeliveryResponse, error)
+	// Replay makes a delivery of the webhook pending again, common.ErrNotFound
+	// is returned when the webhook has no such delivery.
+	Replay(ctx context.Context, webhookId int64, deliveryId int64) (*DeliveryResponse, error)
+	// Claim returns up to limit due deliveries, not due again before lease.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	Record(ctx context.Context, id int64, attempt Attempt) error
+}
+
+// Payload is the body of a delivery, the deliveries are added by the outbox
+// trigger of postgres in the transaction of the expense write. Expense is the
+// expense before the write for ExpenseDeleted.
+type Payload struct {
+	Event      string                     `json:"event"`
+	OccurredAt time.Time                  `json:"occurred_at"`
+	Expense    *expenses.ExpensesResponse `json:"expense"`
+}
+
+type Service struct {
+	log     common.Log
+	storage Storage
+	guard   *Guard
+}
+
+func NewService(s Storage, g *Guard, l common.Log) *Service {
+	return &Service{storage: s, guard: g, log: l}
+}
+
+// validate checks the url and the events of req and lists every event when
+// none is given.
+func (s Service) validate(ctx context.Context, req *WebhookRequest) error {
+	req.Url = strings.TrimSpace(req.Url)
+	if err := s.guard.Check(ctx, req.Url); err != nil {
+		return err
+	}
+	if req.Events == nil {
+		req.Events = []string{}
+	}
+	for _, e := range req.Events {
+		if !events[e] {
+			return &common.Error{Code: http.StatusBadRequest, Desc: "unknown event " + e}
+		}
+	}
+	return nil
+}
+
+// newSecret is 32 random bytes, hex encoded.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func (s Service) AddWebhook(ctx context.Context, req WebhookRequest) (*WebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.AddWebhook")
+	defer span.End()
+
+	if err := s.validate(ctx, &req); err != nil {
+		return nil, invalid("Insert Webhook Error", err)
+	}
+	if req.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, &common.Error{Code: http.StatusInternalServerError, Desc: "Insert Webhook Error", OriginalError: err}
+		}
+		req.Secret = secret
+	}
+
+	resp, err := s.storage.Insert(ctx, req)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Insert Webhook Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Insert Webhook Error", OriginalError: err}
+	}
+	resp.Secret = req.Secret
+	return resp, nil
+}
+
+// invalid is the 400 error of a request validate rejected.
+func invalid(desc string, err error) error {
+	var cmErr *common.Error
+	if errors.As(err, &cmErr) {
+		return cmErr
+	}
+	return &common.Error{Code: common.StatusFromError(err), Desc: desc + " : " + err.Error(), OriginalError: err}
+}
+
+func (s Service) SearchWebhookById(ctx context.Context, id int64) (*WebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.SearchWebhookById")
+	defer span.End()
+
+	resp, err := s.storage.SearchById(ctx, id)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Search Webhook By Id Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Search Webhook By Id Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) ListWebhooks(ctx context.Context) ([]WebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.ListWebhooks")
+	defer span.End()
+
+	resp, err := s.storage.List(ctx)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("List Webhooks Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "List Webhooks Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) UpdateWebhook(ctx context.Context, id int64, req WebhookRequest) (*WebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateWebhook")
+	defer span.End()
+
+	if err := s.validate(ctx, &req); err != nil {
+		return nil, invalid("Update Webhook Error", err)
+	}
+	resp, err := s.storage.Update(ctx, id, req)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Update Webhook Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Update Webhook Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+func (s Service) DeleteWebhook(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteWebhook")
+	defer span.End()
+
+	if err := s.storage.Delete(ctx, id); err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Delete Webhook Error : %s", err)
+		return &common.Error{Code: common.StatusFromError(err), Desc: "Delete Webhook Error", OriginalError: err}
+	}
+	return nil
+}
+
+// ListDeliveries returns the delivery log of the webhook, the latest first.
+func (s Service) ListDeliveries(ctx context.Context, webhookId int64, filter DeliveryFilter) ([]DeliveryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.ListDeliveries")
+	defer span.End()
+
+	switch filter.Status {
+	case "", StatusPending, StatusDelivered, StatusDead:
+	default:
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "List Deliveries Error : unknown status " + filter.Status}
+	}
+	if filter.Limit < 0 || filter.Limit > maxDeliveries {
+		return nil, &common.Error{Code: http.StatusBadRequest, Desc: "List Deliveries Error : limit out of range"}
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultDeliveries
+	}
+
+	if _, err := s.storage.SearchById(ctx, webhookId); err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("List Deliveries Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "List Deliveries Error", OriginalError: err}
+	}
+	resp, err := s.storage.Deliveries(ctx, webhookId, filter)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("List Deliveries Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "List Deliveries Error", OriginalError: err}
+	}
+	return resp, nil
+}
+
+// ReplayDelivery sends a delivery again with all its attempts, a dead one
+// included.
+func (s Service) ReplayDelivery(ctx context.Context, webhookId int64, deliveryId int64) (*DeliveryResponse, error) {
+	ctx, span := tracing.Start(ctx, "Service.ReplayDelivery")
+	defer span.End()
+
+	resp, err := s.storage.Replay(ctx, webhookId, deliveryId)
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		s.log.Errorf("Replay Delivery Error : %s", err)
+		return nil, &common.Error{Code: common.StatusFromError(err), Desc: "Replay Delivery Error", OriginalError: err}
+	}
+	return resp, nil
+}
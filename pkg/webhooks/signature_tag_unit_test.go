@@ -0,0 +1,29 @@
+//go:build unit
+
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"expense.created"}`)
+
+	t.Run("should sign the time and the body with HMAC-SHA256", func(t *testing.T) {
+		assert.Equal(t, "t=1700000000,v1=a3d6e1acfb22791f35a759b834727ab9aa3eb197ff7fd49080cb8230533ce160", Sign("s3cret", at, body))
+	})
+
+	t.Run("should verify its own signature within the tolerance", func(t *testing.T) {
+		header := Sign("s3cret", at, body)
+
+		assert.True(t, Verify("s3cret", header, body, at.Add(time.Minute), 5*time.Minute))
+		assert.False(t, Verify("other", header, body, at, 5*time.Minute))
+		assert.False(t, Verify("s3cret", header, []byte(`{}`), at, 5*time.Minute))
+		assert.False(t, Verify("s3cret", header, body, at.Add(time.Hour), 5*time.Minute))
+		assert.False(t, Verify("s3cret", "v1=abc", body, at, 5*time.Minute))
+	})
+}
@@ -3,38 +3,109 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/EknarongAphiphutthikul/assessment/pkg/apiversion"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/attachments"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/cache"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/categories"
 	"github.com/EknarongAphiphutthikul/assessment/pkg/common"
 	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
 	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/graphapi"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/grpcapi"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/health"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/metrics"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/migration"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/outbox"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/ratelimit"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/search"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/stream"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tags"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tracing"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/webhooks"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
+// apiVersion is the version of the REST api in its OpenAPI document.
+const apiVersion = "1.0.0"
+
 func main() {
 	log := initialLog()
 
-	cf := config.NewConfig()
+	store, err := config.NewStore(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Load Config fail : %s", err)
+	}
+	cf := store.Get()
+	if cf.PrintConfig() {
+		fmt.Print(cf.Dump())
+		return
+	}
+	log.SetLevel(cf.LogLevel())
 	log.Info("Load Config success.")
 
-	db := initialPostgres(cf, log)
-	defer db.Close()
-	log.Info("Database store initial success.")
+	shutdownTracing := initialTracing(cf, log)
+	defer shutdownTracing(context.Background())
+	log.Info("Tracing initial success.")
+
+	var db *sql.DB
+	var migrator *migration.Migrator
+	if cf.StorageBackend() != config.BackendMemory {
+		db = initialDatabase(cf, log)
+		defer db.Close()
+		log.Info("Database store initial success.")
+
+		migrator = initialMigration(db, cf.StorageBackend(), log)
+		log.Info("Database migration success.")
+		if err := metrics.RegisterDBStats(db); err != nil {
+			log.Warnf("Register database metrics fail : %s", err)
+		}
+	}
 
 	ins := &config.Instance{
 		DB:     db,
 		Log:    log,
-		Config: &cf,
+		Config: store,
 	}
+	limiter := ratelimit.New(cf.RateLimit())
+	store.OnReload(func(cf config.Config) {
+		log.SetLevel(cf.LogLevel())
+		limiter.Update(cf.RateLimit())
+	})
+	go watchConfig(store, log)
+
+	storages := initialStorages(ins, log)
+	log.Infof("Storage initial success. BACKEND=%s", cf.StorageBackend())
+	var stopCache func()
+	storages.expenses, stopCache = cacheExpenses(ins, storages.expenses, log)
+	defer stopCache()
+
+	stopRelay := startRelay(ins, log)
+	defer stopRelay()
+	stopDispatcher := startDispatcher(ins, storages.webhooks, log)
+	defer stopDispatcher()
+	broker, stopBroker := startBroker(ins, log)
+	defer stopBroker()
 
-	gracefulShutdown(startServer(ins), log)
+	hc := initialHealth(cf, db, migrator)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv, service := startServer(ctx, ins, hc, limiter, storages, broker)
+	gracefulShutdown(hc, cancel, broker, startGrpcServer(ins, service), log, srv, startAdminServer(ins))
 }
 
 func initialLog() *logrus.Logger {
@@ -48,15 +119,38 @@ func initialLog() *logrus.Logger {
 	}
 }
 
-func initialPostgres(config config.Config, log common.Log) *sql.DB {
-	createTable := `
-		CREATE TABLE IF NOT EXISTS expenses (id SERIAL PRIMARY KEY, title TEXT,	amount FLOAT,	note TEXT,	tags TEXT[]	);
-	`
-	db, err := common.NewDb(common.DbConfig{
-		DriverName:    "postgres",
-		Url:           config.DbUrl(),
-		SqlInitialize: createTable,
+func initialTracing(config config.Config, log common.Log) func(context.Context) error {
+	shutdown, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter: config.TraceExporter(),
+		Endpoint: config.TraceEndpoint(),
+		Insecure: config.TraceInsecure(),
 	})
+	if err != nil {
+		log.Fatalf("Tracing initial fail : %s", err)
+		panic(err)
+	}
+	return shutdown
+}
+
+// initialDatabase opens the database of the storage backend, which is also
+// the name of its driver.
+func initialDatabase(cf config.Config, log common.Log) *sql.DB {
+	dbConfig := common.DbConfig{
+		DriverName:      cf.StorageBackend(),
+		Url:             cf.DbUrl(),
+		MaxOpenConns:    cf.DbMaxOpenConns(),
+		MaxIdleConns:    cf.DbMaxIdleConns(),
+		ConnMaxLifetime: cf.DbConnMaxLifetime(),
+		ConnectTimeout:  cf.DbConnectTimeout(),
+		Log:             log,
+	}
+	if cf.StorageBackend() == config.BackendSqlite {
+		// SQLite allows a single writer, serializing on one connection avoids
+		// "database is locked" errors.
+		dbConfig.MaxOpenConns = 1
+		dbConfig.MaxIdleConns = 1
+	}
+	db, err := common.NewDb(dbConfig)
 	if err != nil {
 		log.Fatalf("Database store initial fail : %s", err)
 		panic(err)
@@ -65,43 +159,409 @@ func initialPostgres(config config.Config, log common.Log) *sql.DB {
 	return db
 }
 
-func startServer(ins *config.Instance) *http.Server {
+func initialMigration(db *sql.DB, dialect string, log common.Log) *migration.Migrator {
+	migrator, err := migration.New(db, dialect)
+	if err == nil {
+		err = migrator.Up(context.Background())
+	}
+	if err != nil {
+		log.Fatalf("Database migration fail : %s", err)
+		panic(err)
+	}
+	return migrator
+}
+
+// storages are the storages of the configured backend, a feature the backend
+// does not support has a nil storage and no routes.
+type storages struct {
+	expenses   expenses.Storage
+	tags       tags.Storage
+	categories categories.Storage
+	search     search.Storage
+	webhooks   webhooks.Storage
+
+	attachments attachments.Storage
+	blobs       attachments.BlobStore
+}
+
+func initialStorages(ins *config.Instance, log common.Log) storages {
+	expenseStorage, err := expenses.NewStorage(ins)
+	if err != nil {
+		log.Fatalf("Storage initial fail : %s", err)
+		panic(err)
+	}
+
+	tagStorage, err := tags.NewStorage(ins)
+	if errors.Is(err, common.ErrUnsupported) {
+		log.Infof("Tag management disabled : %s", err)
+	} else if err != nil {
+		log.Fatalf("Tag storage initial fail : %s", err)
+		panic(err)
+	}
+
+	categoryStorage, err := categories.NewStorage(ins)
+	if errors.Is(err, common.ErrUnsupported) {
+		log.Infof("Categories disabled : %s", err)
+	} else if err != nil {
+		log.Fatalf("Category storage initial fail : %s", err)
+		panic(err)
+	}
+
+	searchStorage, err := search.NewStorage(ins)
+	if errors.Is(err, common.ErrUnsupported) {
+		log.Infof("Expense search disabled : %s", err)
+	} else if err != nil {
+		log.Fatalf("Search storage initial fail : %s", err)
+		panic(err)
+	}
+
+	webhookStorage, err := webhooks.NewStorage(ins)
+	if errors.Is(err, common.ErrUnsupported) {
+		log.Infof("Webhooks disabled : %s", err)
+	} else if err != nil {
+		log.Fatalf("Webhook storage initial fail : %s", err)
+		panic(err)
+	}
+
+	st := storages{expenses: expenseStorage, tags: tagStorage, categories: categoryStorage, search: searchStorage, webhooks: webhookStorage}
+	st.attachments, err = attachments.NewStorage(ins)
+	if errors.Is(err, common.ErrUnsupported) {
+		log.Infof("Attachments disabled : %s", err)
+		return st
+	} else if err != nil {
+		log.Fatalf("Attachment storage initial fail : %s", err)
+		panic(err)
+	}
+	st.blobs, err = attachments.NewBlobStore(ins.Config.Get())
+	if err != nil {
+		log.Fatalf("Attachment blob store initial fail : %s", err)
+		panic(err)
+	}
+
+	return st
+}
+
+// cacheExpenses puts a cache in front of the expense reads by id of storage,
+// emptied of the expenses the instances write until the returned function is
+// called.
+func cacheExpenses(ins *config.Instance, storage expenses.Storage, log common.Log) (expenses.Storage, func()) {
+	cf := ins.Config.Get()
+	if !cf.CacheEnabled() {
+		log.Info("Expense cache disabled : turned off")
+		return storage, func() {}
+	}
+	if cf.StorageBackend() != config.BackendPostgres {
+		log.Infof("Expense cache disabled : %s", common.ErrUnsupported)
+		return storage, func() {}
+	}
+
+	cached := expenses.NewCached(storage, cf.CacheSize(), cf.CacheTtl())
+	listener := cache.Listen(cf.DbUrl(), expenses.Channel, cached, log)
+	log.Infof("Expense cache started. SIZE=%d", cf.CacheSize())
+
+	return cached, func() {
+		if err := listener.Close(); err != nil {
+			log.Errorf("Expense cache listener close fail : %s", err)
+		}
+	}
+}
+
+// startRelay publishes the events of the outbox to the configured sink until
+// the returned function is called.
+func startRelay(ins *config.Instance, log common.Log) func() {
+	cf := ins.Config.Get()
+	storage, err := outbox.NewStorage(ins)
+	if errors.Is(err, common.ErrUnsupported) {
+		log.Infof("Outbox relay disabled : %s", err)
+		return func() {}
+	} else if err != nil {
+		log.Fatalf("Outbox storage initial fail : %s", err)
+		panic(err)
+	}
+	sink, err := outbox.NewSink(cf)
+	if err != nil {
+		log.Fatalf("Outbox sink initial fail : %s", err)
+		panic(err)
+	}
+	if sink == nil {
+		log.Info("Outbox relay disabled : no sink")
+		return func() {}
+	}
+
+	relay := outbox.NewRelay(storage, sink, outbox.Options{
+		Interval:       cf.OutboxInterval(),
+		BatchSize:      cf.OutboxBatchSize(),
+		PublishTimeout: cf.OutboxPublishTimeout(),
+		MaxAttempts:    cf.OutboxMaxAttempts(),
+	}, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+	log.Infof("Outbox relay started. SINK=%s", cf.OutboxSink())
+
+	return func() {
+		cancel()
+		<-done
+		if err := sink.Close(); err != nil {
+			log.Errorf("Outbox sink close fail : %s", err)
+		}
+	}
+}
+
+// webhookBatchSize is the number of webhook deliveries claimed at once.
+const webhookBatchSize = 20
+
+// startDispatcher sends the due webhook deliveries of storage until the
+// returned function is called.
+func startDispatcher(ins *config.Instance, storage webhooks.Storage, log common.Log) func() {
+	if storage == nil {
+		return func() {}
+	}
+	cf := ins.Config.Get()
+	guard := webhooks.NewGuard(cf.WebhookAllowPrivate())
+	dispatcher := webhooks.NewDispatcher(storage, guard.Client(cf.WebhookTimeout()), webhooks.DispatcherOptions{
+		Interval:    cf.WebhookInterval(),
+		Timeout:     cf.WebhookTimeout(),
+		Backoff:     cf.WebhookBackoff(),
+		MaxAttempts: cf.WebhookMaxAttempts(),
+		BatchSize:   webhookBatchSize,
+	}, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dispatcher.Run(ctx)
+	}()
+	log.Info("Webhook dispatcher started.")
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+const (
+	// streamBatchSize is the number of events the expense stream reads at once.
+	streamBatchSize = 100
+	// streamSettle bounds the transactions of the writes when the database
+	// has no query timeout.
+	streamSettle = time.Minute
+)
+
+// startBroker polls the outbox for the expense streams until the returned
+// function is called. The Broker is nil when the backend keeps no outbox.
+func startBroker(ins *config.Instance, log common.Log) (*stream.Broker, func()) {
+	cf := ins.Config.Get()
+	storage, err := outbox.NewStorage(ins)
+	if errors.Is(err, common.ErrUnsupported) {
+		log.Infof("Expense stream disabled : %s", err)
+		return nil, func() {}
+	} else if err != nil {
+		log.Fatalf("Stream storage initial fail : %s", err)
+		panic(err)
+	}
+
+	// a write holds its transaction for the query timeout at most
+	settle := streamSettle
+	if cf.DbTimeout() > 0 {
+		settle = cf.DbTimeout() + time.Second
+	}
+	broker := stream.NewBroker(storage, stream.Options{
+		Interval:  cf.StreamInterval(),
+		BatchSize: streamBatchSize,
+		Buffer:    cf.StreamBuffer(),
+		Settle:    settle,
+	}, log)
+	if err := broker.Start(context.Background()); err != nil {
+		log.Fatalf("Stream initial fail : %s", err)
+		panic(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		broker.Run(ctx)
+	}()
+	log.Info("Expense stream started.")
+
+	return broker, func() {
+		cancel()
+		<-done
+	}
+}
+
+func initialHealth(config config.Config, db *sql.DB, migrator *migration.Migrator) *health.Health {
+	hc := health.New(config.HealthTimeout(), config.ShutdownDrainDelay())
+	if db == nil {
+		return hc
+	}
+	hc.AddCheck("database", db.PingContext)
+	hc.AddCheck("migrations", func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d pending migrations", pending)
+		}
+		return nil
+	})
+	return hc
+}
+
+// startServer serves requests with contexts derived from ctx, so cancelling it
+// aborts every in-flight request including its SQL. The expense Service is
+// returned for the gRPC api.
+func startServer(ctx context.Context, ins *config.Instance, hc *health.Health, limiter *ratelimit.Limiter, storages storages, broker *stream.Broker) (*http.Server, expenses.Services) {
 	e := echo.New()
 	e.Logger.SetLevel(log.INFO)
 
-	initMiddleware(e, ins)
-	initRoutes(e, ins)
+	initMiddleware(e, ins, limiter)
+	service, doc := initRoutes(e, ins, hc, storages, broker)
+	initValidation(e, ins, doc)
 
 	srv := &http.Server{
-		Addr:    ":" + ins.Config.Port(),
+		Addr:    ":" + ins.Config.Get().Port(),
 		Handler: e,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
 	}
 
-	ins.Log.Infof("App started. PORT=%s", ins.Config.Port())
+	ins.Log.Infof("App started. PORT=%s", ins.Config.Get().Port())
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			ins.Log.Fatalf("shutting down the server %s", err)
 		}
 	}()
 
+	return srv, service
+}
+
+// startGrpcServer serves the expenses of service over gRPC on its own port.
+func startGrpcServer(ins *config.Instance, service expenses.Services) *grpc.Server {
+	lis, err := net.Listen("tcp", ":"+ins.Config.Get().GrpcPort())
+	if err != nil {
+		ins.Log.Fatalf("gRPC listen fail : %s", err)
+		panic(err)
+	}
+	srv := grpcapi.New(ins, service)
+
+	ins.Log.Infof("gRPC started. PORT=%s", ins.Config.Get().GrpcPort())
+	go func() {
+		if err := srv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			ins.Log.Fatalf("shutting down the gRPC server %s", err)
+		}
+	}()
+
 	return srv
 }
 
-func gracefulShutdown(srv *http.Server, log common.Log) {
+// startAdminServer serves operational endpoints on a port that is not exposed
+// with the public API.
+func startAdminServer(ins *config.Instance) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	srv := &http.Server{
+		Addr:    ":" + ins.Config.Get().AdminPort(),
+		Handler: mux,
+	}
+
+	ins.Log.Infof("Admin started. PORT=%s", ins.Config.Get().AdminPort())
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			ins.Log.Fatalf("shutting down the admin server %s", err)
+		}
+	}()
+
+	return srv
+}
+
+// watchConfig reloads the configuration on SIGHUP or when the config file is
+// modified. SIGTERM and SIGINT are left to gracefulShutdown.
+func watchConfig(store *config.Store, log common.Log) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	modified := time.Now()
+	for {
+		select {
+		case <-hup:
+		case <-ticker.C:
+			changed, at := store.Changed(modified)
+			if !changed {
+				continue
+			}
+			modified = at
+		}
+
+		warnings, err := store.Reload()
+		for _, w := range warnings {
+			log.Warnf("Reload Config : %s", w)
+		}
+		if err != nil {
+			log.Errorf("Reload Config fail, keep current config : %s", err)
+			continue
+		}
+		log.Info("Reload Config success.")
+	}
+}
+
+func gracefulShutdown(hc *health.Health, cancelRequests context.CancelFunc, broker *stream.Broker, grpcServer *grpc.Server, log common.Log, servers ...*http.Server) {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 	<-signals
 
+	log.Info("App is draining...")
+	hc.Drain()
+
+	if broker != nil {
+		// the streams never end by themselves, their clients reconnect to
+		// another instance with the id of their last event
+		log.Info("App is closing the streams...")
+		broker.Close()
+	}
+
 	log.Info("App is shutting down...")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Error shutting down: %s", err)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		grpcServer.GracefulStop()
+	}()
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Errorf("Error shutting down: %s", err)
+			cancelRequests()
+			srv.Close()
+		}
+	}
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Errorf("Error shutting down gRPC: %s", ctx.Err())
+		grpcServer.Stop()
+		<-stopped
 	}
 	log.Info("Bye")
 }
 
-func initMiddleware(e *echo.Echo, ins *config.Instance) {
+func initMiddleware(e *echo.Echo, ins *config.Instance, limiter *ratelimit.Limiter) {
+	e.IPExtractor = ipExtractor(ins.Config.Get().TrustedProxies())
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.SetRequest(c.Request().WithContext(common.WithRequestId(c.Request().Context(), id)))
+		},
+	}))
+	e.Use(tracing.Middleware())
+	e.Use(metrics.Middleware())
 	/*
 		e.Use(middleware.BodyDumpWithConfig(middleware.BodyDumpConfig{
 			Handler: func(c echo.Context, req []byte, resp []byte) {
@@ -110,47 +570,142 @@ func initMiddleware(e *echo.Echo, ins *config.Instance) {
 		}))
 	*/
 	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogURI:      true,
-		LogStatus:   true,
-		LogRemoteIP: true,
-		LogMethod:   true,
-		LogHeaders:  []string{"Content-Type", "Authorization"},
-		LogLatency:  true,
-		LogError:    true,
+		LogURI:       true,
+		LogRequestID: true,
+		LogStatus:    true,
+		LogRemoteIP:  true,
+		LogMethod:    true,
+		LogHeaders:   []string{"Content-Type", "Authorization"},
+		LogLatency:   true,
+		LogError:     true,
 		LogValuesFunc: func(c echo.Context, values middleware.RequestLoggerValues) error {
 			if values.Error == nil {
 				ins.Log.WithFields(logrus.Fields{
-					"URI":     values.URI,
-					"status":  values.Status,
-					"method":  values.Method,
-					"headers": values.Headers,
-					"latency": values.Latency,
+					"URI":        values.URI,
+					"request_id": values.RequestID,
+					"status":     values.Status,
+					"method":     values.Method,
+					"headers":    values.Headers,
+					"latency":    values.Latency,
 				}).Info("request")
 			} else {
 				ins.Log.WithFields(logrus.Fields{
-					"URI":     values.URI,
-					"status":  values.Status,
-					"method":  values.Method,
-					"headers": values.Headers,
-					"latency": values.Latency,
-					"error":   values.Error,
+					"URI":        values.URI,
+					"request_id": values.RequestID,
+					"status":     values.Status,
+					"method":     values.Method,
+					"headers":    values.Headers,
+					"latency":    values.Latency,
+					"error":      values.Error,
 				}).Error("request error")
 			}
 			return nil
 		},
 	}))
+	e.Use(ratelimit.Middleware(limiter, func(c echo.Context) bool {
+		return health.IsProbe(c.Path())
+	}))
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			value := c.Request().Header.Values("Authorization")
-			if value != nil && value[0] == ins.Config.AuthKey() {
+			if health.IsProbe(c.Path()) || openapi.IsDocs(c.Path()) {
 				return next(c)
 			}
+			value := c.Request().Header.Values("Authorization")
+			if value != nil {
+				if name, ok := ins.Config.Get().Authenticate(value[0]); ok {
+					// the name of the key is the actor of the expense history
+					c.SetRequest(c.Request().WithContext(common.WithActor(c.Request().Context(), name)))
+					return next(c)
+				}
+			}
 			return echo.NewHTTPError(http.StatusUnauthorized, "Please provide valid credentials")
 		}
 	})
 	e.Use(middleware.Recover())
 }
 
-func initRoutes(echo *echo.Echo, ins *config.Instance) {
-	expenses.Routes(echo, ins)
+// ipExtractor tells the client ip of the rate limit and the logs. Without
+// trusted proxies it is the peer address, a client could otherwise pick a new
+// ip per request in X-Forwarded-For. Behind them it is the first address of
+// X-Forwarded-For that is not one of them.
+func ipExtractor(proxies []*net.IPNet) echo.IPExtractor {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range proxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// initRoutes serves the routes of the supported features and their OpenAPI
+// document, which is returned.
+func initRoutes(echo *echo.Echo, ins *config.Instance, hc *health.Health, storages storages, broker *stream.Broker) (expenses.Services, *openapi.Document) {
+	health.Routes(echo, hc)
+	versions := apiversion.Served(ins.Config.Get())
+	operations := [][]openapi.Operation{health.Operations, openapi.Operations}
+	if storages.webhooks != nil {
+		webhooks.Routes(echo, ins, storages.webhooks)
+		operations = append(operations, webhooks.Operations)
+	}
+	service := expenses.Routes(echo, ins, storages.expenses)
+	operations = append(operations, expenses.Operations(versions))
+	if broker != nil {
+		stream.Routes(echo, ins, broker)
+		operations = append(operations, stream.Operations(versions))
+	}
+	var tagService graphapi.Tags
+	if storages.tags != nil {
+		tags.Routes(echo, ins, storages.tags)
+		tagService = tags.NewService(storages.tags, ins.Log)
+		operations = append(operations, tags.Operations)
+	}
+	var categoryService graphapi.Categories
+	if storages.categories != nil {
+		categories.Routes(echo, ins, storages.categories)
+		categoryService = categories.NewService(storages.categories, ins.Log)
+		operations = append(operations, categories.Operations)
+	}
+	graphapi.Routes(echo, ins, service, tagService, categoryService)
+	operations = append(operations, graphapi.Operations)
+	if storages.search != nil {
+		search.Routes(echo, ins, storages.search)
+		operations = append(operations, search.Operations(versions))
+	}
+	if storages.attachments != nil {
+		attachments.Routes(echo, ins, storages.attachments, storages.blobs)
+		operations = append(operations, attachments.Operations(versions))
+	}
+	doc := openapi.New(openapi.Info{
+		Title:       "Expenses",
+		Version:     apiVersion,
+		Description: "Expense tracking api, every operation but the probes and the documentation needs an api key in the Authorization header.",
+	}, operations...)
+	openapi.Routes(echo, doc)
+	return service, doc
+}
+
+// initValidation checks the requests, and the responses, against doc when
+// configured. The middleware of echo also applies to the routes added before
+// it, after the authentication so that a request without api key is told
+// 401.
+func initValidation(e *echo.Echo, ins *config.Instance, doc *openapi.Document) {
+	cf := ins.Config.Get()
+	if !cf.ValidateRequests() && !cf.ValidateResponses() {
+		return
+	}
+	validator, err := openapi.NewValidator(doc, ins.Log)
+	if err != nil {
+		ins.Log.Fatalf("OpenAPI validation initial fail : %s", err)
+		panic(err)
+	}
+	if cf.ValidateRequests() {
+		e.Use(validator.Requests())
+		ins.Log.Info("Request validation enabled.")
+	}
+	if cf.ValidateResponses() {
+		e.Use(validator.Responses())
+		ins.Log.Info("Response validation enabled.")
+	}
 }
@@ -0,0 +1,259 @@
+//go:build unit
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/EknarongAphiphutthikul/assessment/pkg/apiversion"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/attachments"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/categories"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/config"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/expenses"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/health"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/openapi"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/ratelimit"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/search"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/stream"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/tags"
+	"github.com/EknarongAphiphutthikul/assessment/pkg/webhooks"
+	"github.com/labstack/echo/v4"
+)
+
+// allStorages serves every feature, the storages are never called.
+func allStorages(t *testing.T) storages {
+	blobs, err := attachments.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storages{
+		expenses:    expenses.NewMemory(),
+		tags:        tags.New(nil, 0),
+		categories:  categories.New(nil, 0),
+		search:      search.New(nil, 0),
+		webhooks:    webhooks.New(nil, 0),
+		attachments: attachments.New(nil, 0),
+		blobs:       blobs,
+	}
+}
+
+func setupServer(t *testing.T, st storages, args ...string) *echo.Echo {
+	store, err := config.NewStore(append([]string{"-port", "2565", "-database-url", "postgres://localhost:5432/postgres"}, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ins := &config.Instance{Log: initialLog(), Config: store}
+
+	e := echo.New()
+	initMiddleware(e, ins, ratelimit.New(store.Get().RateLimit()))
+	_, doc := initRoutes(e, ins, health.New(0, 0), st, stream.NewBroker(nil, stream.Options{}, ins.Log))
+	initValidation(e, ins, doc)
+	return e
+}
+
+func document(t *testing.T, e *echo.Echo) *openapi.Document {
+	req := httptest.NewRequest(http.MethodGet, openapi.DocumentPath, nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status=%d; want %d", openapi.DocumentPath, rec.Code, http.StatusOK)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+func TestOpenAPI(t *testing.T) {
+	t.Run("should describe every route and only the routes", func(t *testing.T) {
+		e := setupServer(t, allStorages(t))
+		routes := []string{}
+		for _, r := range e.Routes() {
+			if openapi.IsDocs(r.Path) && r.Path != openapi.DocumentPath {
+				continue
+			}
+			path, _ := openapi.PathOf(r.Path)
+			routes = append(routes, r.Method+" "+path)
+		}
+		sort.Strings(routes)
+
+		got := document(t, e).Operations()
+
+		if strings.Join(got, "\n") != strings.Join(routes, "\n") {
+			t.Errorf("document operations:\n%s\nwant the routes:\n%s", strings.Join(got, "\n"), strings.Join(routes, "\n"))
+		}
+	})
+
+	t.Run("should route the paths of every version to their own route", func(t *testing.T) {
+		e := setupServer(t, allStorages(t))
+
+		for _, path := range []string{"/expenses/search", "/expenses/stream", "/expenses/:id/attachments", "/expenses/:id/attachments/:attachmentId"} {
+			for _, prefix := range []string{"", "/v1", "/v2"} {
+				c := e.NewContext(nil, nil)
+				e.Router().Find(http.MethodGet, strings.NewReplacer(":id", "1", ":attachmentId", "2").Replace(prefix+path), c)
+
+				if c.Path() != prefix+path {
+					t.Errorf("GET %s routed to %s", prefix+path, c.Path())
+				}
+			}
+		}
+	})
+
+	t.Run("should serve the document and the swagger ui without api key", func(t *testing.T) {
+		e := setupServer(t, allStorages(t))
+
+		for path, want := range map[string]int{
+			openapi.DocumentPath:               http.StatusOK,
+			openapi.DocsPath + "/":             http.StatusOK,
+			openapi.DocsPath + "/index.css":    http.StatusOK,
+			"/expenses":                        http.StatusUnauthorized,
+			openapi.DocsPath + "/missing.file": http.StatusNotFound,
+		} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != want {
+				t.Errorf("GET %s status=%d; want %d", path, rec.Code, want)
+			}
+		}
+	})
+}
+
+func TestValidation(t *testing.T) {
+	e := setupServer(t, storages{expenses: expenses.NewMemory()}, "-openapi-validate-requests", "true", "-openapi-validate-responses", "true")
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderAuthorization, "November 10, 2009")
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should serve the requests and responses matching the document", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/expenses", `{"title":"strawberry smoothie","amount":79,"note":"night market","tags":["food"]}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("POST status=%d; want %d\n%s", rec.Code, http.StatusCreated, rec.Body)
+		}
+
+		for _, path := range []string{"/expenses/1", "/expenses", "/expenses/1/history", "/v1/expenses/1", "/v2/expenses/1", "/v2/expenses", "/v2/expenses/1/history"} {
+			if rec := serve(http.MethodGet, path, ""); rec.Code != http.StatusOK {
+				t.Errorf("GET %s status=%d; want %d\n%s", path, rec.Code, http.StatusOK, rec.Body)
+			}
+		}
+	})
+
+	t.Run("should serve the unversioned paths in the version of the API-Version header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/expenses/1", strings.NewReader(`{"title":"apple smoothie","amount":89,"category_id":null}`))
+		req.Header.Set(echo.HeaderAuthorization, "November 10, 2009")
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(apiversion.Header, "2")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"1"`) {
+			t.Errorf("status=%d; want %d with a string id\n%s", rec.Code, http.StatusOK, rec.Body)
+		}
+	})
+
+	t.Run("should reject the requests not matching the document with a problem", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			method string
+			path   string
+			body   string
+			want   openapi.ProblemError
+		}{
+			"amount of the wrong type": {http.MethodPost, "/expenses", `{"title":"tea","amount":"79"}`, openapi.ProblemError{In: "body", Pointer: "/amount"}},
+			"unknown field":            {http.MethodPost, "/expenses", `{"title":"tea","amount":79,"id":"1"}`, openapi.ProblemError{In: "body"}},
+			"id that is not a number":  {http.MethodGet, "/expenses/one", "", openapi.ProblemError{In: "path", Name: "id"}},
+			"as_of that is not a time": {http.MethodGet, "/expenses/1?as_of=yesterday", "", openapi.ProblemError{In: "query", Name: "as_of"}},
+		} {
+			rec := serve(tc.method, tc.path, tc.body)
+
+			var got openapi.Problem
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			if rec.Code != http.StatusBadRequest || rec.Header().Get(echo.HeaderContentType) != openapi.MIMEApplicationProblemJSON {
+				t.Errorf("%s: status=%d content type=%s; want a %d problem", name, rec.Code, rec.Header().Get(echo.HeaderContentType), http.StatusBadRequest)
+				continue
+			}
+			if len(got.Errors) == 0 || got.Errors[0].In != tc.want.In || got.Errors[0].Name != tc.want.Name || got.Errors[0].Pointer != tc.want.Pointer {
+				t.Errorf("%s: errors=%+v; want %+v", name, got.Errors, tc.want)
+			}
+		}
+	})
+
+	t.Run("should serve the other media types of the expenses", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader("title,amount\nmango sticky rice,60\n"))
+		req.Header.Set(echo.HeaderAuthorization, "November 10, 2009")
+		req.Header.Set(echo.HeaderContentType, "text/csv")
+		req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationXML)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), "<title>mango sticky rice</title>") {
+			t.Errorf("status=%d; want %d\n%s", rec.Code, http.StatusCreated, rec.Body)
+		}
+	})
+
+	t.Run("should tell 401 before the validation", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/expenses/one", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status=%d; want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func TestRateLimitClientIp(t *testing.T) {
+	serve := func(e *echo.Echo, remoteAddr string, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(echo.HeaderAuthorization, "November 10, 2009")
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		req.Header.Set(echo.HeaderXRealIP, forwardedFor)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("should not reset the bucket of a client spoofing X-Forwarded-For", func(t *testing.T) {
+		e := setupServer(t, storages{expenses: expenses.NewMemory()}, "-rate-limit-rps", "0.001", "-rate-limit-burst", "1")
+
+		codes := []int{serve(e, "203.0.113.7:4321", "10.0.0.1"), serve(e, "203.0.113.7:4321", "10.0.0.2")}
+
+		if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+			t.Errorf("status=%v; want %d then %d", codes, http.StatusOK, http.StatusTooManyRequests)
+		}
+	})
+
+	t.Run("should take the client ip of X-Forwarded-For from a trusted proxy", func(t *testing.T) {
+		e := setupServer(t, storages{expenses: expenses.NewMemory()}, "-rate-limit-rps", "0.001", "-rate-limit-burst", "1", "-rate-limit-trusted-proxies", "203.0.113.0/24")
+
+		codes := []int{
+			serve(e, "203.0.113.7:4321", "198.51.100.1"),
+			serve(e, "203.0.113.8:4321", "198.51.100.2"),
+			serve(e, "203.0.113.7:4321", "198.51.100.1"),
+			serve(e, "198.51.100.9:4321", "198.51.100.3"),
+			serve(e, "198.51.100.9:4321", "198.51.100.4"),
+		}
+
+		want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK, http.StatusTooManyRequests}
+		for i := range want {
+			if codes[i] != want[i] {
+				t.Errorf("status=%v; want %v", codes, want)
+				break
+			}
+		}
+	})
+}